@@ -0,0 +1,124 @@
+package auth
+
+type Role string
+
+type Permission string
+
+const (
+	RoleAdmin   Role = "admin"
+	RoleManager Role = "manager"
+	RoleCashier Role = "cashier"
+	RoleViewer  Role = "viewer"
+)
+
+// DefaultRole is given to self-registered accounts. Only admins can grant
+// anything more.
+const DefaultRole = RoleViewer
+
+const (
+	ProductsRead   Permission = "products:read"
+	ProductsWrite  Permission = "products:write"
+	ProductsDelete Permission = "products:delete"
+
+	CategoriesRead   Permission = "categories:read"
+	CategoriesWrite  Permission = "categories:write"
+	CategoriesDelete Permission = "categories:delete"
+
+	CustomersRead   Permission = "customers:read"
+	CustomersWrite  Permission = "customers:write"
+	CustomersDelete Permission = "customers:delete"
+
+	SalesRead   Permission = "sales:read"
+	SalesCreate Permission = "sales:create"
+	SalesDelete Permission = "sales:delete"
+	// SalesAmend allows changing the lines of a sale after it was created.
+	SalesAmend Permission = "sales:amend"
+	// SalesReturn allows taking items back and refunding them.
+	SalesReturn Permission = "sales:return"
+	// SalesOverridePrice allows selling at a price other than the catalog
+	// price.
+	SalesOverridePrice Permission = "sales:override_price"
+	// SalesDiscount allows taking a manual discount off a line or a sale.
+	SalesDiscount Permission = "sales:discount"
+
+	// PromotionsManage allows setting up promotions and coupons. Anyone who
+	// can read sales can read them.
+	PromotionsManage Permission = "promotions:manage"
+	// TaxesManage allows setting up tax rates. Anyone who can read sales can
+	// read them.
+	TaxesManage Permission = "taxes:manage"
+	// ReportsRead allows reading the reports built over many sales.
+	ReportsRead Permission = "reports:read"
+
+	UsersManage Permission = "users:manage"
+)
+
+// Each role inherits the permissions of the roles below it; the lists hold
+// only what a role adds.
+var viewerPermissions = []Permission{
+	ProductsRead,
+	CategoriesRead,
+	SalesRead,
+}
+
+// Customers hold personal data, so viewers cannot read them; cashiers
+// look them up and register them at the till.
+var cashierPermissions = []Permission{
+	CustomersRead,
+	CustomersWrite,
+	SalesCreate,
+}
+
+var managerPermissions = []Permission{
+	ProductsWrite,
+	ProductsDelete,
+	CategoriesWrite,
+	CategoriesDelete,
+	CustomersDelete,
+	SalesDelete,
+	SalesAmend,
+	SalesReturn,
+	SalesOverridePrice,
+	SalesDiscount,
+	PromotionsManage,
+	TaxesManage,
+	ReportsRead,
+}
+
+var adminPermissions = []Permission{
+	UsersManage,
+}
+
+var rolePermissions = map[Role]map[Permission]bool{
+	RoleViewer:  permissionSet(viewerPermissions),
+	RoleCashier: permissionSet(viewerPermissions, cashierPermissions),
+	RoleManager: permissionSet(viewerPermissions, cashierPermissions, managerPermissions),
+	RoleAdmin:   permissionSet(viewerPermissions, cashierPermissions, managerPermissions, adminPermissions),
+}
+
+func permissionSet(lists ...[]Permission) map[Permission]bool {
+	set := map[Permission]bool{}
+	for _, permissions := range lists {
+		for _, permission := range permissions {
+			set[permission] = true
+		}
+	}
+	return set
+}
+
+// Valid reports whether r is one of the known roles.
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can reports whether the role grants permission. Unknown roles grant
+// nothing.
+func (r Role) Can(permission Permission) bool {
+	return rolePermissions[r][permission]
+}
+
+// Roles lists every known role, from most to least privileged.
+func Roles() []Role {
+	return []Role{RoleAdmin, RoleManager, RoleCashier, RoleViewer}
+}
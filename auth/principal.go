@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// Principal is the authenticated user behind a request, as described by the
+// token claims.
+type Principal struct {
+	UserID   uint
+	Username string
+	Role     Role
+
+	// TokenID is the jti of the access token and SessionID the refresh
+	// token family it was issued with; logout revokes both.
+	TokenID   string
+	SessionID string
+	ExpiresAt time.Time
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// UserID returns the ID of the authenticated user, or 0 when ctx carries no
+// principal, as for work done at startup.
+func UserID(ctx context.Context) uint {
+	principal, _ := PrincipalFrom(ctx)
+	return principal.UserID
+}
+
+// PrincipalFrom returns the principal stored by the authentication
+// middleware, if any.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
@@ -1,13 +1,22 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	config "productmanagerapi/config"
+	"productmanagerapi/controllers"
+	"productmanagerapi/database"
+	"productmanagerapi/migrations"
 	"productmanagerapi/models"
+	"productmanagerapi/payments"
+	"productmanagerapi/repository"
 	routes "productmanagerapi/routes"
+	"productmanagerapi/services"
 	"productmanagerapi/utils"
-	"strings"
+	"strconv"
 
 	_ "github.com/swaggo/http-swagger"
 )
@@ -16,27 +25,134 @@ func main() {
 	router := http.NewServeMux()
 
 	fmt.Println("Starting Product Manager API...")
+
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Println("Error loading configuration:", err)
+		os.Exit(1)
+	}
+	config.App = cfg
+
 	fmt.Println("Connecting to the database...")
 
-	config.Db.AutoMigrate(&models.User{}, &models.Category{}, &models.Product{}, &models.Sale{}, &models.SaleProduct{})
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		fmt.Println("Error connecting to the database:", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	fmt.Println("Database connected successfully")
+
+	migrator, err := migrations.New(db.DB, db.Driver())
+	if err != nil {
+		fmt.Println("Error loading migrations:", err)
+		os.Exit(1)
+	}
 
-	if config.Err != nil {
-		fmt.Println("Error connecting to the database:", config.Err)
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrate(migrator, args[1:]); err != nil {
+			fmt.Println("Migration failed:", err)
+			os.Exit(1)
+		}
 		return
 	}
-	fmt.Println("Database connected successfully")
 
-	for path, handler := range routes.Routes {
+	if cfg.Database.AutoMigrate {
+		if err := runMigrate(migrator, []string{"up"}); err != nil {
+			fmt.Println("Migration failed:", err)
+			os.Exit(1)
+		}
+	}
+
+	if err := migrator.EnsureUpToDate(context.Background()); err != nil {
+		fmt.Println("Refusing to start:", err)
+		os.Exit(1)
+	}
+
+	provider, err := payments.New(cfg.Payments.Provider)
+	if err != nil {
+		fmt.Println("Invalid payment settings:", err)
+		os.Exit(1)
+	}
+
+	appServices := services.New(repository.NewGormStore(db.DB), provider)
 
-		if strings.HasPrefix(path, "/auth") || strings.HasPrefix(path, "/swagger") || strings.HasPrefix(path, "/docs") {
-			router.HandleFunc(path, handler)
-			continue
+	if cfg.Auth.AdminUsername != "" {
+		created, err := appServices.Auth.EnsureAdmin(context.Background(), models.User{
+			Username: cfg.Auth.AdminUsername,
+			Password: cfg.Auth.AdminPassword,
+			Email:    cfg.Auth.AdminEmail,
+		})
+		if err != nil {
+			fmt.Println("Creating admin account failed:", err)
+			os.Exit(1)
+		}
+		if created {
+			fmt.Println("Created admin account", cfg.Auth.AdminUsername)
 		}
+	}
+
+	utils.IsTokenRevoked = appServices.Auth.IsRevoked
+
+	handlers := controllers.New(appServices, db)
+
+	routes.Register(router, handlers, cfg.API.LegacySunsetTime())
+
+	fmt.Println("Server is running on", cfg.Server.Addr())
+	if err := http.ListenAndServe(cfg.Server.Addr(), utils.CORSMiddleware(router)); err != nil {
+		fmt.Println("Server stopped:", err)
+		os.Exit(1)
+	}
+}
 
-		router.HandleFunc(path, utils.AuthMiddleware(handler))
+// runMigrate implements the "migrate up", "migrate down [steps]" and
+// "migrate status" commands.
+func runMigrate(migrator *migrations.Migrator, args []string) error {
+	ctx := context.Background()
 
+	if len(args) == 0 {
+		return errors.New("usage: migrate up | down [steps] | status")
 	}
 
-	fmt.Println("Server is running on http://localhost:2002")
-	http.ListenAndServe(":2002", utils.CORSMiddleware(router))
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("Database schema is up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			parsed, err := strconv.Atoi(args[1])
+			if err != nil || parsed <= 0 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = parsed
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		for _, migration := range rolledBack {
+			fmt.Printf("Rolled back %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q (expected up, down or status)", args[0])
+	}
 }
@@ -0,0 +1,497 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"productmanagerapi/payments"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultSecretKey is the secret the API used to ship with. The server refuses
+// to start while it is still configured.
+const DefaultSecretKey = "BAKEMONO_SECRET"
+
+// Supported database drivers.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// minSecretKeyLength matches the 256-bit key size recommended for HS256.
+const minSecretKeyLength = 32
+
+type Config struct {
+	Server   ServerConfig   `yaml:"server" toml:"server"`
+	Database DatabaseConfig `yaml:"database" toml:"database"`
+	Auth     AuthConfig     `yaml:"auth" toml:"auth"`
+	CORS     CORSConfig     `yaml:"cors" toml:"cors"`
+	API      APIConfig      `yaml:"api" toml:"api"`
+	Payments PaymentsConfig `yaml:"payments" toml:"payments"`
+	Store    StoreConfig    `yaml:"store" toml:"store"`
+}
+
+type ServerConfig struct {
+	Host string `yaml:"host" toml:"host"`
+	Port int    `yaml:"port" toml:"port"`
+}
+
+type DatabaseConfig struct {
+	// Driver selects the storage backend: "postgres" or "sqlite".
+	Driver string `yaml:"driver" toml:"driver"`
+
+	// DSN, when set, takes precedence over the individual connection fields.
+	// For SQLite it is the database file path, or ":memory:" for a private
+	// in-memory database.
+	DSN      string `yaml:"dsn" toml:"dsn"`
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	Name     string `yaml:"name" toml:"name"`
+	SSLMode  string `yaml:"sslmode" toml:"sslmode"`
+
+	// Connection pool settings.
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
+
+	// ConnectRetries is the number of extra attempts made when the first
+	// connection fails. The delay between attempts starts at
+	// ConnectRetryDelay and doubles each time.
+	ConnectRetries    int           `yaml:"connect_retries" toml:"connect_retries"`
+	ConnectRetryDelay time.Duration `yaml:"connect_retry_delay" toml:"connect_retry_delay"`
+
+	// AutoMigrate applies pending migrations at startup instead of refusing
+	// to start. Meant for local development and in-memory databases.
+	AutoMigrate bool `yaml:"auto_migrate" toml:"auto_migrate"`
+}
+
+type AuthConfig struct {
+	SecretKey string `yaml:"secret_key" toml:"secret_key"`
+	// Issuer is put in the iss claim of access tokens and required back.
+	Issuer string `yaml:"issuer" toml:"issuer"`
+	// AccessTokenTTL is how long a signed access token is accepted.
+	// RefreshTokenTTL is how long the opaque refresh token that renews it
+	// stays usable.
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl" toml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" toml:"refresh_token_ttl"`
+	// Admin is created at startup with the admin role when no user with
+	// that username exists yet. Leave the username empty to skip it.
+	AdminUsername string `yaml:"admin_username" toml:"admin_username"`
+	AdminPassword string `yaml:"admin_password" toml:"admin_password"`
+	AdminEmail    string `yaml:"admin_email" toml:"admin_email"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins" toml:"allowed_origins"`
+}
+
+type APIConfig struct {
+	// LegacySunset is the date (YYYY-MM-DD) unversioned routes will be
+	// removed, sent to clients in the Sunset header. Empty omits the header.
+	LegacySunset string `yaml:"legacy_sunset" toml:"legacy_sunset"`
+	// IdempotencyWindow is how long the response to a request sent with an
+	// Idempotency-Key header is kept and replayed to its retries.
+	IdempotencyWindow time.Duration `yaml:"idempotency_window" toml:"idempotency_window"`
+}
+
+type PaymentsConfig struct {
+	// Provider settles card and mobile money tenders. "none", the default,
+	// refuses them, so only cash and store credit are taken. "fake" approves
+	// everything without moving money and is meant for local testing, so it
+	// is only accepted together with AllowFake.
+	Provider  string `yaml:"provider" toml:"provider"`
+	AllowFake bool   `yaml:"allow_fake" toml:"allow_fake"`
+}
+
+// StoreConfig describes the store at the top and bottom of receipts and
+// invoices. Empty fields are left out.
+type StoreConfig struct {
+	Name string `yaml:"name" toml:"name"`
+	// Address may span several lines.
+	Address string `yaml:"address" toml:"address"`
+	Phone   string `yaml:"phone" toml:"phone"`
+	Email   string `yaml:"email" toml:"email"`
+	Website string `yaml:"website" toml:"website"`
+	// TaxID is the store's tax registration number, such as a VAT number.
+	TaxID string `yaml:"tax_id" toml:"tax_id"`
+	// Currency is printed next to totals, such as USD.
+	Currency string `yaml:"currency" toml:"currency"`
+	// InvoicePrefix is put in front of invoice numbers, which are printed
+	// with at least six digits: INV-000042.
+	InvoicePrefix string `yaml:"invoice_prefix" toml:"invoice_prefix"`
+	// Footer ends every receipt, such as a returns policy.
+	Footer string `yaml:"footer" toml:"footer"`
+}
+
+// App holds the configuration the server was started with.
+var App = Default()
+
+// Default returns the configuration used before any file, environment
+// variable or flag is applied. The secret key is intentionally left empty.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port: 2002,
+		},
+		Database: DatabaseConfig{
+			Driver:  DriverPostgres,
+			Host:    "localhost",
+			Port:    5432,
+			SSLMode: "disable",
+
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+
+			ConnectRetries:    5,
+			ConnectRetryDelay: time.Second,
+		},
+		Auth: AuthConfig{
+			Issuer:          "productmanagerapi",
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 7 * 24 * time.Hour,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"http://localhost:3000"},
+		},
+		API: APIConfig{
+			IdempotencyWindow: 24 * time.Hour,
+		},
+		Payments: PaymentsConfig{
+			Provider: payments.NoneProviderName,
+		},
+		Store: StoreConfig{
+			InvoicePrefix: "INV-",
+		},
+	}
+}
+
+// Load builds the configuration from, in increasing order of precedence:
+// defaults, the config file (-config flag or CONFIG_FILE), environment
+// variables and command-line flags. The result is validated before it is
+// returned together with the positional arguments left after the flags.
+func Load(args []string) (Config, []string, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("productmanagerapi", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML configuration file")
+	host := fs.String("host", "", "address the HTTP server binds to")
+	port := fs.Int("port", 0, "port the HTTP server listens on")
+	driver := fs.String("db-driver", "", "database driver (postgres or sqlite)")
+	dsn := fs.String("db-dsn", "", "database connection string")
+	secretKey := fs.String("secret-key", "", "key used to sign JWT tokens")
+	corsOrigins := fs.String("cors-origins", "", "comma-separated list of allowed CORS origins")
+	autoMigrate := fs.Bool("migrate", false, "apply pending database migrations at startup")
+
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	if *configFile != "" {
+		if err := loadFile(*configFile, &cfg); err != nil {
+			return Config{}, nil, err
+		}
+	}
+
+	if err := loadEnv(&cfg); err != nil {
+		return Config{}, nil, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "host":
+			cfg.Server.Host = *host
+		case "port":
+			cfg.Server.Port = *port
+		case "db-driver":
+			cfg.Database.Driver = *driver
+		case "db-dsn":
+			cfg.Database.DSN = *dsn
+		case "secret-key":
+			cfg.Auth.SecretKey = *secretKey
+		case "cors-origins":
+			cfg.CORS.AllowedOrigins = splitList(*corsOrigins)
+		case "migrate":
+			cfg.Database.AutoMigrate = *autoMigrate
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, nil, err
+	}
+
+	return cfg, fs.Args(), nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, cfg)
+	case ".toml":
+		err = toml.Unmarshal(content, cfg)
+	default:
+		return fmt.Errorf("unsupported config file extension %q (expected .yaml, .yml or .toml)", filepath.Ext(path))
+	}
+
+	if err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func loadEnv(cfg *Config) error {
+	setString := func(key string, target *string) {
+		if value, ok := os.LookupEnv(key); ok {
+			*target = value
+		}
+	}
+	setInt := func(key string, target *int) error {
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			return nil
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s must be an integer: %w", key, err)
+		}
+		*target = parsed
+		return nil
+	}
+	setBool := func(key string, target *bool) error {
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			return nil
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false: %w", key, err)
+		}
+		*target = parsed
+		return nil
+	}
+	setDuration := func(key string, target *time.Duration) error {
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			return nil
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a duration such as 30s or 5m: %w", key, err)
+		}
+		*target = parsed
+		return nil
+	}
+
+	setString("SERVER_HOST", &cfg.Server.Host)
+	if err := setInt("SERVER_PORT", &cfg.Server.Port); err != nil {
+		return err
+	}
+
+	setString("DB_DRIVER", &cfg.Database.Driver)
+	setString("DATABASE_URL", &cfg.Database.DSN)
+	setString("DB_HOST", &cfg.Database.Host)
+	if err := setInt("DB_PORT", &cfg.Database.Port); err != nil {
+		return err
+	}
+	setString("DB_USER", &cfg.Database.User)
+	setString("DB_PASSWORD", &cfg.Database.Password)
+	setString("DB_NAME", &cfg.Database.Name)
+	setString("DB_SSLMODE", &cfg.Database.SSLMode)
+	if err := setInt("DB_MAX_OPEN_CONNS", &cfg.Database.MaxOpenConns); err != nil {
+		return err
+	}
+	if err := setInt("DB_MAX_IDLE_CONNS", &cfg.Database.MaxIdleConns); err != nil {
+		return err
+	}
+	if err := setDuration("DB_CONN_MAX_LIFETIME", &cfg.Database.ConnMaxLifetime); err != nil {
+		return err
+	}
+	if err := setDuration("DB_CONN_MAX_IDLE_TIME", &cfg.Database.ConnMaxIdleTime); err != nil {
+		return err
+	}
+	if err := setInt("DB_CONNECT_RETRIES", &cfg.Database.ConnectRetries); err != nil {
+		return err
+	}
+	if err := setDuration("DB_CONNECT_RETRY_DELAY", &cfg.Database.ConnectRetryDelay); err != nil {
+		return err
+	}
+	if err := setBool("DB_AUTO_MIGRATE", &cfg.Database.AutoMigrate); err != nil {
+		return err
+	}
+
+	setString("SECRET_KEY", &cfg.Auth.SecretKey)
+	setString("TOKEN_ISSUER", &cfg.Auth.Issuer)
+	if err := setDuration("ACCESS_TOKEN_TTL", &cfg.Auth.AccessTokenTTL); err != nil {
+		return err
+	}
+	if err := setDuration("REFRESH_TOKEN_TTL", &cfg.Auth.RefreshTokenTTL); err != nil {
+		return err
+	}
+	setString("ADMIN_USERNAME", &cfg.Auth.AdminUsername)
+	setString("ADMIN_PASSWORD", &cfg.Auth.AdminPassword)
+	setString("ADMIN_EMAIL", &cfg.Auth.AdminEmail)
+
+	setString("API_LEGACY_SUNSET", &cfg.API.LegacySunset)
+	if err := setDuration("IDEMPOTENCY_WINDOW", &cfg.API.IdempotencyWindow); err != nil {
+		return err
+	}
+	setString("PAYMENT_PROVIDER", &cfg.Payments.Provider)
+	if err := setBool("PAYMENT_ALLOW_FAKE", &cfg.Payments.AllowFake); err != nil {
+		return err
+	}
+
+	setString("STORE_NAME", &cfg.Store.Name)
+	setString("STORE_ADDRESS", &cfg.Store.Address)
+	setString("STORE_PHONE", &cfg.Store.Phone)
+	setString("STORE_EMAIL", &cfg.Store.Email)
+	setString("STORE_WEBSITE", &cfg.Store.Website)
+	setString("STORE_TAX_ID", &cfg.Store.TaxID)
+	setString("STORE_CURRENCY", &cfg.Store.Currency)
+	setString("INVOICE_PREFIX", &cfg.Store.InvoicePrefix)
+	setString("RECEIPT_FOOTER", &cfg.Store.Footer)
+
+	if value, ok := os.LookupEnv("CORS_ALLOWED_ORIGINS"); ok {
+		cfg.CORS.AllowedOrigins = splitList(value)
+	}
+
+	return nil
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Validate reports every missing or invalid setting at once so a deployment
+// can be fixed in a single pass.
+func (c Config) Validate() error {
+	var problems []string
+
+	if c.Server.Port <= 0 || c.Server.Port > 65535 {
+		problems = append(problems, "server port must be between 1 and 65535")
+	}
+
+	switch c.Database.Driver {
+	case DriverSQLite:
+		if strings.TrimSpace(c.Database.DSN) == "" {
+			problems = append(problems, "database dsn is required for sqlite: a file path or :memory: (DATABASE_URL)")
+		}
+	case DriverPostgres:
+		if strings.TrimSpace(c.Database.DSN) != "" {
+			break
+		}
+		if strings.TrimSpace(c.Database.Host) == "" {
+			problems = append(problems, "database host is required (DB_HOST)")
+		}
+		if strings.TrimSpace(c.Database.User) == "" {
+			problems = append(problems, "database user is required (DB_USER)")
+		}
+		if strings.TrimSpace(c.Database.Name) == "" {
+			problems = append(problems, "database name is required (DB_NAME)")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("unsupported database driver %q (expected %s or %s)", c.Database.Driver, DriverPostgres, DriverSQLite))
+	}
+
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		problems = append(problems, "database pool sizes cannot be negative")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		problems = append(problems, "database max idle connections cannot exceed max open connections")
+	}
+	if c.Database.ConnectRetries < 0 {
+		problems = append(problems, "database connect retries cannot be negative")
+	}
+
+	switch {
+	case strings.TrimSpace(c.Auth.SecretKey) == "":
+		problems = append(problems, "secret key is required (SECRET_KEY)")
+	case c.Auth.SecretKey == DefaultSecretKey:
+		problems = append(problems, "secret key must be changed from the default value")
+	case len(c.Auth.SecretKey) < minSecretKeyLength:
+		problems = append(problems, fmt.Sprintf("secret key must be at least %d characters long", minSecretKeyLength))
+	}
+
+	if strings.TrimSpace(c.Auth.Issuer) == "" {
+		problems = append(problems, "token issuer is required (TOKEN_ISSUER)")
+	}
+	if c.Auth.AccessTokenTTL <= 0 {
+		problems = append(problems, "access token TTL must be positive")
+	}
+	if c.Auth.RefreshTokenTTL < c.Auth.AccessTokenTTL {
+		problems = append(problems, "refresh token TTL cannot be shorter than the access token TTL")
+	}
+
+	if c.Auth.AdminUsername != "" && c.Auth.AdminPassword == "" {
+		problems = append(problems, "admin password is required when an admin username is set (ADMIN_PASSWORD)")
+	}
+
+	if c.API.LegacySunset != "" {
+		if _, err := time.Parse(time.DateOnly, c.API.LegacySunset); err != nil {
+			problems = append(problems, "api legacy sunset must be a date formatted as YYYY-MM-DD")
+		}
+	}
+
+	if c.API.IdempotencyWindow <= 0 {
+		problems = append(problems, "idempotency window must be positive")
+	}
+
+	switch strings.TrimSpace(c.Payments.Provider) {
+	case "":
+		problems = append(problems, "payment provider is required (PAYMENT_PROVIDER); use "+payments.NoneProviderName+" to take only cash and store credit")
+	case payments.FakeProviderName:
+		if !c.Payments.AllowFake {
+			problems = append(problems, "the fake payment provider approves every charge and is only for development and tests; set PAYMENT_ALLOW_FAKE=true to use it")
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// LegacySunsetTime returns the parsed LegacySunset date, or the zero time
+// when none is configured.
+func (a APIConfig) LegacySunsetTime() time.Time {
+	sunset, _ := time.Parse(time.DateOnly, a.LegacySunset)
+	return sunset
+}
+
+// Addr returns the address the HTTP server listens on.
+func (s ServerConfig) Addr() string {
+	return fmt.Sprintf("%s:%d", s.Host, s.Port)
+}
+
+// ConnectionString returns the configured DSN, or builds a Postgres DSN from
+// the individual connection fields.
+func (d DatabaseConfig) ConnectionString() string {
+	if d.Driver == DriverSQLite || strings.TrimSpace(d.DSN) != "" {
+		return d.DSN
+	}
+
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s", d.Host, d.User, d.Password, d.Name, d.Port, d.SSLMode)
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"productmanagerapi/payments"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPaymentProviderDefaultsToNone(t *testing.T) {
+	cfg := Default()
+	cfg.Auth.SecretKey = strings.Repeat("k", 32)
+	cfg.Database.Driver = DriverSQLite
+	cfg.Database.DSN = ":memory:"
+	if cfg.Payments.Provider != payments.NoneProviderName {
+		t.Fatalf("got provider %q, want %q", cfg.Payments.Provider, payments.NoneProviderName)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("the defaults with a secret key and SQLite are valid, got %v", err)
+	}
+
+	cfg.Payments.Provider = payments.FakeProviderName
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "PAYMENT_ALLOW_FAKE") {
+		t.Fatalf("got %v, want the fake provider refused without PAYMENT_ALLOW_FAKE", err)
+	}
+	cfg.Payments.AllowFake = true
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+server:
+  host: file-host
+  port: 3000
+database:
+  driver: sqlite
+  dsn: file.db
+auth:
+  secret_key: ` + strings.Repeat("f", 32) + `
+  issuer: file-issuer
+api:
+  idempotency_window: 1h
+`
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SERVER_PORT", "4000")
+	t.Setenv("TOKEN_ISSUER", "env-issuer")
+	t.Setenv("DATABASE_URL", "env.db")
+
+	cfg, args, err := Load([]string{"-config", file, "-port", "5000", "migrate", "status"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Port != 5000 {
+		t.Fatalf("got port %d, want the flag to beat the environment and the file", cfg.Server.Port)
+	}
+	if cfg.Auth.Issuer != "env-issuer" || cfg.Database.DSN != "env.db" {
+		t.Fatalf("got issuer %q and DSN %q, want the environment to beat the file", cfg.Auth.Issuer, cfg.Database.DSN)
+	}
+	if cfg.Server.Host != "file-host" || cfg.API.IdempotencyWindow != time.Hour {
+		t.Fatalf("got host %q and window %v, want the file to beat the defaults", cfg.Server.Host, cfg.API.IdempotencyWindow)
+	}
+	if cfg.Auth.AccessTokenTTL != Default().Auth.AccessTokenTTL {
+		t.Fatalf("got access token TTL %v, want the default kept", cfg.Auth.AccessTokenTTL)
+	}
+	if len(args) != 2 || args[0] != "migrate" || args[1] != "status" {
+		t.Fatalf("got args %v, want the command left after the flags", args)
+	}
+
+	t.Setenv("SERVER_PORT", "many")
+	if _, _, err := Load([]string{"-config", file}); err == nil || !strings.Contains(err.Error(), "SERVER_PORT") {
+		t.Fatalf("got %v, want the malformed SERVER_PORT named", err)
+	}
+}
+
+func TestLoadRefusesTheOldSecretKey(t *testing.T) {
+	t.Setenv("DB_DRIVER", DriverSQLite)
+	t.Setenv("DATABASE_URL", ":memory:")
+	t.Setenv("SECRET_KEY", DefaultSecretKey)
+	if _, _, err := Load(nil); err == nil {
+		t.Fatal("the secret key the API used to ship with must be refused")
+	}
+}
@@ -2,29 +2,30 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	config "productmanagerapi/config"
+	"productmanagerapi/auth"
 	"productmanagerapi/models"
 	responseFormatter "productmanagerapi/responseFormatter"
 	"productmanagerapi/services"
+	"productmanagerapi/types"
 	"productmanagerapi/utils"
-	requestMethodValidator "productmanagerapi/utils"
 	"strings"
+)
 
-	jwt "github.com/golang-jwt/jwt/v5"
+type AuthController struct {
+	service *services.AuthService
+}
 
-	bcrypt "golang.org/x/crypto/bcrypt"
-)
+func NewAuthController(service *services.AuthService) *AuthController {
+	return &AuthController{service: service}
+}
 
-var Login = func(w http.ResponseWriter, r *http.Request) {
+func (c *AuthController) Login(w http.ResponseWriter, r *http.Request) {
 	fmt.Printf("Received %s request for %s\n", r.Method, r.URL.Path)
 
-	isValidMethod := requestMethodValidator.RequestMethodValidator(w, *r, http.MethodPost)
-	if !isValidMethod {
-		return
-	}
-
 	fmt.Println("Processing user login...")
 
 	var userInfo struct {
@@ -46,10 +47,10 @@ var Login = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokenString, err, user := services.Login(username, password)
+	tokens, user, err := c.service.Login(r.Context(), username, password)
 
 	if err != nil {
-		if err.Error() == "invalid username or password" {
+		if errors.Is(err, services.ErrInvalidCredentials) {
 			utils.ResponseWritter(w, http.StatusUnauthorized, responseFormatter.FormatResponse(http.StatusUnauthorized, "Invalid username or password", nil))
 			fmt.Println("Invalid username or password for user:", username)
 			return
@@ -60,64 +61,41 @@ var Login = func(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "token",
-		Value:    tokenString,
-		HttpOnly: true,
-		Secure:   false, // Set to true in production with HTTPS
-		SameSite: http.SameSiteStrictMode,
-		Path:     "/",
-		MaxAge:   3600, // 1 hour
-	})
+	setTokenCookies(w, tokens)
 
-	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Login successful", map[string]interface{}{
-		"user_id":      user.ID,
-		"username":     user.Username,
-		"email":        user.Email,
-		"role":         user.Role,
-		"access_token": tokenString,
-	}))
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Login successful", tokenResponse(user, tokens)))
 
 	fmt.Println("User login successful")
 }
 
-var Register = func(w http.ResponseWriter, r *http.Request) {
-
-	isValidMethod := requestMethodValidator.RequestMethodValidator(w, *r, http.MethodPost)
-	if !isValidMethod {
-		return
-	}
-
+func (c *AuthController) Register(w http.ResponseWriter, r *http.Request) {
 	// maka a structured server log that includes the request method and URL
 	fmt.Printf("Received %s request for %s\n", r.Method, r.URL.Path)
 	fmt.Println("Processing user registration...")
 	w.Header().Set("Content-Type", "application/json")
 
-	var user models.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+	var registration types.Registration
+	if err := json.NewDecoder(r.Body).Decode(&registration); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(responseFormatter.FormatResponse(http.StatusBadRequest, "Invalid request body", nil))
 		return
 	}
 
-	if user.Username == "" || user.Password == "" || user.Email == "" {
+	if registration.Username == "" || registration.Password == "" || registration.Email == "" {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(responseFormatter.FormatResponse(http.StatusBadRequest, "Username, password, and email are required", nil))
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	user, err := c.service.Register(r.Context(), models.User{
+		Username: registration.Username,
+		Password: registration.Password,
+		Email:    registration.Email,
+	})
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(responseFormatter.FormatResponse(http.StatusInternalServerError, "Error hashing password", nil))
-		fmt.Println("Error hashing password:", err)
-		return
-	}
-	user.Password = string(hashedPassword)
-
-	if err := config.Db.Create(&user).Error; err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(responseFormatter.FormatResponse(http.StatusInternalServerError, "Error creating user", nil))
+		fmt.Println("Error registering user:", err)
 		return
 	}
 
@@ -132,62 +110,142 @@ var HomeController = func(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(responseFormatter.FormatResponse(http.StatusOK, "Welcome to the Product Manager API", nil))
 }
 
-var RefreshToken = func(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("token")
+// RefreshToken rotates the refresh token sent in the body or the
+// refresh_token cookie and returns a new token pair. A cookie is only
+// accepted together with the session's CSRF token header.
+func (c *AuthController) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Refreshing token...")
+
+	var body types.RefreshRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+			utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, "Invalid request body", nil))
+			return
+		}
+	}
+
+	refreshToken := body.RefreshToken
+	if refreshToken == "" {
+		if cookie, err := r.Cookie(refreshCookieName); err == nil && cookie.Value != "" {
+			sessionID, err := c.service.SessionID(r.Context(), cookie.Value)
+			if err == nil && !utils.ValidCSRFToken(sessionID, r.Header.Get(utils.CSRFHeader)) {
+				utils.ResponseWritter(w, http.StatusForbidden, responseFormatter.FormatResponse(http.StatusForbidden, "Invalid or missing CSRF token", nil))
+				fmt.Println("Refresh rejected: invalid CSRF token")
+				return
+			}
+			refreshToken = cookie.Value
+		}
+	}
+
+	tokens, user, err := c.service.Refresh(r.Context(), refreshToken)
 	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			clearTokenCookies(w)
+			utils.ResponseWritter(w, http.StatusUnauthorized, responseFormatter.FormatResponse(http.StatusUnauthorized, err.Error(), nil))
+			fmt.Println("Refresh rejected:", err)
+			return
+		}
+
+		utils.ResponseWritter(w, http.StatusInternalServerError, responseFormatter.FormatResponse(http.StatusInternalServerError, "Error refreshing token", nil))
+		fmt.Println("Error refreshing token:", err)
 		return
 	}
 
-	// Parse and validate existing token
-	token, err := jwt.Parse(cookie.Value, func(token *jwt.Token) (interface{}, error) {
-		return []byte(config.SECRET_KEY), nil
-	})
-	if err != nil || !token.Valid {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
+	setTokenCookies(w, tokens)
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Token refreshed", tokenResponse(user, tokens)))
+}
+
+// Logout revokes the current access token and its session.
+func (c *AuthController) Logout(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFrom(r.Context())
+	if err := c.service.Logout(r.Context(), principal); err != nil {
+		utils.ResponseWritter(w, http.StatusInternalServerError, responseFormatter.FormatResponse(http.StatusInternalServerError, "Error logging out", nil))
+		fmt.Println("Error logging out:", err)
 		return
 	}
 
-	claims := token.Claims.(jwt.MapClaims)
-
-	// Create a new token with same claims and new expiration
-	newToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id":  claims["user_id"],
-		"username": claims["username"],
-		"email":    claims["email"],
-		"role":     claims["role"],
-	})
+	clearTokenCookies(w)
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Logged out successfully", nil))
+	fmt.Println("User logged out successfully")
+}
 
-	tokenString, err := newToken.SignedString([]byte(config.SECRET_KEY))
-	if err != nil {
-		http.Error(w, "Failed to refresh", http.StatusInternalServerError)
+// LogoutAll revokes every session of the current user, on all devices.
+func (c *AuthController) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFrom(r.Context())
+	if err := c.service.LogoutAll(r.Context(), principal); err != nil {
+		utils.ResponseWritter(w, http.StatusInternalServerError, responseFormatter.FormatResponse(http.StatusInternalServerError, "Error logging out", nil))
+		fmt.Println("Error logging out of all sessions:", err)
 		return
 	}
 
+	clearTokenCookies(w)
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Logged out of all sessions", nil))
+	fmt.Println("User logged out of all sessions:", principal.UserID)
+}
+
+const (
+	accessCookieName  = "token"
+	refreshCookieName = "refresh_token"
+	// csrfCookieName is readable by scripts so browser clients can copy it
+	// into the X-CSRF-Token header.
+	csrfCookieName = "csrf_token"
+)
+
+func setTokenCookies(w http.ResponseWriter, tokens types.TokenPair) {
 	http.SetCookie(w, &http.Cookie{
-		Name:     "token",
-		Value:    tokenString,
+		Name:     accessCookieName,
+		Value:    tokens.AccessToken,
 		HttpOnly: true,
-		Secure:   false,
+		Secure:   false, // Set to true in production with HTTPS
+		SameSite: http.SameSiteStrictMode,
 		Path:     "/",
-		MaxAge:   3600,
-	})
-
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Token refreshed",
+		MaxAge:   tokens.ExpiresIn,
 	})
-}
-
-var Logout = func(w http.ResponseWriter, r *http.Request) {
 	http.SetCookie(w, &http.Cookie{
-		Name:     "token",
-		Value:    "",
+		Name:     refreshCookieName,
+		Value:    tokens.RefreshToken,
 		HttpOnly: true,
 		Secure:   false, // Set to true in production with HTTPS
 		SameSite: http.SameSiteStrictMode,
 		Path:     "/",
+		MaxAge:   tokens.RefreshExpiresIn,
 	})
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(responseFormatter.FormatResponse(http.StatusOK, "Logged out successfully", nil))
-	fmt.Println("User logged out successfully")
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    utils.CSRFToken(tokens.SessionID),
+		HttpOnly: false,
+		Secure:   false, // Set to true in production with HTTPS
+		SameSite: http.SameSiteStrictMode,
+		Path:     "/",
+		MaxAge:   tokens.RefreshExpiresIn,
+	})
+}
+
+func clearTokenCookies(w http.ResponseWriter) {
+	for _, name := range []string{accessCookieName, refreshCookieName, csrfCookieName} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			HttpOnly: name != csrfCookieName,
+			Secure:   false, // Set to true in production with HTTPS
+			SameSite: http.SameSiteStrictMode,
+			Path:     "/",
+			MaxAge:   -1,
+		})
+	}
+}
+
+func tokenResponse(user models.User, tokens types.TokenPair) map[string]interface{} {
+	return map[string]interface{}{
+		"user_id":            user.ID,
+		"username":           user.Username,
+		"email":              user.Email,
+		"role":               user.Role,
+		"access_token":       tokens.AccessToken,
+		"refresh_token":      tokens.RefreshToken,
+		"token_type":         tokens.TokenType,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_expires_in": tokens.RefreshExpiresIn,
+		"csrf_token":         utils.CSRFToken(tokens.SessionID),
+	}
 }
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	responseFormatter "productmanagerapi/responseFormatter"
@@ -8,17 +9,24 @@ import (
 	utils "productmanagerapi/utils"
 )
 
-var GetAllCategories = func(w http.ResponseWriter, r *http.Request) {
-	isValidMethod := utils.RequestMethodValidator(w, *r, http.MethodGet)
-	if !isValidMethod {
-		return
-	}
+type CategoryController struct {
+	service *services.CategoryService
+}
+
+func NewCategoryController(service *services.CategoryService) *CategoryController {
+	return &CategoryController{service: service}
+}
 
+func (c *CategoryController) GetAllCategories(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	utils.Log(r, "Fetcing all Categories...")
 
-	listCategories, err := services.GetAllCategories()
+	listCategories, err := c.service.GetAllCategories(r.Context(), r.URL.Query())
 
+	if errors.Is(err, services.ErrInvalidFilter) {
+		utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
 	if err != nil {
 		utils.ResponseWritter(w, http.StatusInternalServerError, responseFormatter.FormatResponse(http.StatusInternalServerError, "Error fetching categories", nil))
 		fmt.Println("Error fetching categories:", err)
@@ -29,16 +37,11 @@ var GetAllCategories = func(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("Categories fetched successfully:", len(listCategories))
 }
 
-var GetCategoryByID = func(w http.ResponseWriter, r *http.Request) {
-	isValidMethod := utils.RequestMethodValidator(w, *r, http.MethodGet)
-	if !isValidMethod {
-		return
-	}
-
+func (c *CategoryController) GetCategoryByID(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	utils.Log(r, "Fetching Category...")
 
-	category, err := services.GetCategoryByID(r.URL.Query().Get("id"))
+	category, err := c.service.GetCategoryByID(r.Context(), resourceID(r))
 	if err != nil {
 		utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, err.Error(), nil))
 		fmt.Println("Error fetching category by ID:", err)
@@ -50,16 +53,11 @@ var GetCategoryByID = func(w http.ResponseWriter, r *http.Request) {
 
 }
 
-var CreateCategory = func(w http.ResponseWriter, r *http.Request) {
-	isValidMethod := utils.RequestMethodValidator(w, *r, http.MethodPost)
-	if !isValidMethod {
-		return
-	}
-
+func (c *CategoryController) CreateCategory(w http.ResponseWriter, r *http.Request) {
 	utils.Log(r, "Creating a new category...")
 	w.Header().Set("Content-Type", "application/json")
 
-	category, err := services.CreateCategory(r.Body)
+	category, err := c.service.CreateCategory(r.Context(), r.Body)
 	if err != nil {
 		utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, err.Error(), nil))
 		fmt.Println("Error creating category:", err)
@@ -70,16 +68,11 @@ var CreateCategory = func(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("Category created successfully:", category.ID, category.Name)
 }
 
-var UpdateCategory = func(w http.ResponseWriter, r *http.Request) {
-	isValidMethod := utils.RequestMethodValidator(w, *r, http.MethodPut)
-	if !isValidMethod {
-		return
-	}
-
+func (c *CategoryController) UpdateCategory(w http.ResponseWriter, r *http.Request) {
 	utils.Log(r, "Updating category...")
 	w.Header().Set("Content-Type", "application/json")
 
-	category, err := services.UpdateCategory(r.URL.Query().Get("id"), r.Body)
+	category, err := c.service.UpdateCategory(r.Context(), resourceID(r), r.Body)
 
 	if err != nil {
 		utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, err.Error(), nil))
@@ -92,16 +85,41 @@ var UpdateCategory = func(w http.ResponseWriter, r *http.Request) {
 
 }
 
-var DeleteCategory = func(w http.ResponseWriter, r *http.Request) {
-	isValidMethod := utils.RequestMethodValidator(w, *r, http.MethodDelete)
-	if !isValidMethod {
+func (c *CategoryController) PatchCategory(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Patching category...")
+	w.Header().Set("Content-Type", "application/json")
+
+	category, err := c.service.PatchCategory(r.Context(), resourceID(r), r.Body)
+	if err != nil {
+		utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, err.Error(), nil))
+		fmt.Println("Error patching category:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Category updated successfully", category))
+	fmt.Println("Category patched successfully:", category.ID, category.Name)
+}
+
+func (c *CategoryController) UpdateLegacyCategory(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Updating category...")
+	w.Header().Set("Content-Type", "application/json")
+
+	category, err := c.service.UpdateLegacyCategory(r.Context(), resourceID(r), r.Body)
+	if err != nil {
+		utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, err.Error(), nil))
+		fmt.Println("Error updating category:", err)
 		return
 	}
 
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Category updated successfully", category))
+	fmt.Println("Category updated successfully:", category.ID, category.Name)
+}
+
+func (c *CategoryController) DeleteCategory(w http.ResponseWriter, r *http.Request) {
 	utils.Log(r, "Deleting category...")
-	categoryID := r.URL.Query().Get("id")
+	categoryID := resourceID(r)
 
-	err := services.DeleteCategory(categoryID)
+	err := c.service.DeleteCategory(r.Context(), categoryID)
 	if err != nil {
 		utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, err.Error(), nil))
 		fmt.Println("Error deleting category:", err)
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"net/http"
+	"productmanagerapi/services"
+)
+
+// Controllers groups the HTTP handlers for every resource.
+type Controllers struct {
+	Auth        *AuthController
+	Categories  *CategoryController
+	Coupons     *CouponController
+	Customers   *CustomerController
+	Health      *HealthController
+	Idempotency *IdempotencyController
+	Payments    *PaymentController
+	Products    *ProductController
+	Promotions  *PromotionController
+	Receipts    *ReceiptController
+	Reports     *ReportController
+	Returns     *ReturnController
+	Sales       *SaleController
+	TaxRates    *TaxRateController
+	Users       *UserController
+}
+
+func New(s *services.Services, db Pinger) *Controllers {
+	return &Controllers{
+		Auth:        NewAuthController(s.Auth),
+		Categories:  NewCategoryController(s.Categories),
+		Coupons:     NewCouponController(s.Coupons),
+		Customers:   NewCustomerController(s.Customers),
+		Health:      NewHealthController(db),
+		Idempotency: NewIdempotencyController(s.Idempotency),
+		Payments:    NewPaymentController(s.Payments),
+		Products:    NewProductController(s.Products),
+		Promotions:  NewPromotionController(s.Promotions),
+		Receipts:    NewReceiptController(s.Receipts),
+		Reports:     NewReportController(s.Reports),
+		Returns:     NewReturnController(s.Returns),
+		Sales:       NewSaleController(s.Sales),
+		TaxRates:    NewTaxRateController(s.TaxRates),
+		Users:       NewUserController(s.Users),
+	}
+}
+
+// resourceID returns the {id} path parameter, falling back to the ?id= query
+// parameter used by the legacy routes.
+func resourceID(r *http.Request) string {
+	if id := r.PathValue("id"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("id")
+}
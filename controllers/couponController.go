@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"productmanagerapi/repository"
+	responseFormatter "productmanagerapi/responseFormatter"
+	"productmanagerapi/services"
+	"productmanagerapi/utils"
+)
+
+type CouponController struct {
+	service *services.CouponService
+}
+
+func NewCouponController(service *services.CouponService) *CouponController {
+	return &CouponController{service: service}
+}
+
+func (c *CouponController) GetAllCoupons(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Fetching all coupons...")
+
+	coupons, err := c.service.GetAllCoupons(r.Context())
+	if err != nil {
+		utils.ResponseWritter(w, http.StatusInternalServerError, responseFormatter.FormatResponse(http.StatusInternalServerError, "Error fetching coupons", nil))
+		fmt.Println("Error fetching coupons:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Coupons fetched successfully", coupons))
+	fmt.Println("Coupons fetched successfully:", len(coupons))
+}
+
+func (c *CouponController) GetCouponByID(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Fetching coupon...")
+
+	coupon, err := c.service.GetCouponByID(r.Context(), resourceID(r))
+	if err != nil {
+		writeCouponError(w, err)
+		fmt.Println("Error fetching coupon:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Coupon fetched successfully", coupon))
+	fmt.Println("Coupon fetched successfully:", coupon.ID)
+}
+
+func (c *CouponController) CreateCoupon(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Creating a new coupon...")
+
+	coupon, err := c.service.CreateCoupon(r.Context(), r.Body)
+	if err != nil {
+		writeCouponError(w, err)
+		fmt.Println("Error creating coupon:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusCreated, responseFormatter.FormatResponse(http.StatusCreated, "Coupon created successfully", coupon))
+	fmt.Println("Coupon created successfully:", coupon.ID)
+}
+
+func (c *CouponController) UpdateCoupon(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Updating coupon...")
+
+	coupon, err := c.service.UpdateCoupon(r.Context(), resourceID(r), r.Body)
+	if err != nil {
+		writeCouponError(w, err)
+		fmt.Println("Error updating coupon:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Coupon updated successfully", coupon))
+	fmt.Println("Coupon updated successfully:", coupon.ID)
+}
+
+func (c *CouponController) DeleteCoupon(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Deleting coupon...")
+	couponID := resourceID(r)
+
+	if err := c.service.DeleteCoupon(r.Context(), couponID); err != nil {
+		writeCouponError(w, err)
+		fmt.Println("Error deleting coupon:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Coupon deleted successfully", nil))
+	fmt.Println("Coupon deleted successfully with ID:", couponID)
+}
+
+func writeCouponError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, repository.ErrNotFound) {
+		status = http.StatusNotFound
+	}
+	utils.ResponseWritter(w, status, responseFormatter.FormatResponse(status, err.Error(), nil))
+}
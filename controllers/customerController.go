@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"productmanagerapi/repository"
+	responseFormatter "productmanagerapi/responseFormatter"
+	"productmanagerapi/services"
+	"productmanagerapi/utils"
+)
+
+type CustomerController struct {
+	service *services.CustomerService
+}
+
+func NewCustomerController(service *services.CustomerService) *CustomerController {
+	return &CustomerController{service: service}
+}
+
+func (c *CustomerController) GetAllCustomers(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Fetching all customers...")
+
+	customers, err := c.service.GetAllCustomers(r.Context())
+	if err != nil {
+		utils.ResponseWritter(w, http.StatusInternalServerError, responseFormatter.FormatResponse(http.StatusInternalServerError, "Error fetching customers", nil))
+		fmt.Println("Error fetching customers:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Customers fetched successfully", customers))
+	fmt.Println("Customers fetched successfully:", len(customers))
+}
+
+func (c *CustomerController) GetCustomerByID(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Fetching customer...")
+
+	customer, err := c.service.GetCustomerByID(r.Context(), resourceID(r))
+	if err != nil {
+		writeCustomerError(w, err)
+		fmt.Println("Error fetching customer:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Customer fetched successfully", customer))
+	fmt.Println("Customer fetched successfully:", customer.ID)
+}
+
+func (c *CustomerController) CreateCustomer(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Creating a new customer...")
+
+	customer, err := c.service.CreateCustomer(r.Context(), r.Body)
+	if err != nil {
+		writeCustomerError(w, err)
+		fmt.Println("Error creating customer:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusCreated, responseFormatter.FormatResponse(http.StatusCreated, "Customer created successfully", customer))
+	fmt.Println("Customer created successfully:", customer.ID)
+}
+
+func (c *CustomerController) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Updating customer...")
+
+	customer, err := c.service.UpdateCustomer(r.Context(), resourceID(r), r.Body)
+	if err != nil {
+		writeCustomerError(w, err)
+		fmt.Println("Error updating customer:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Customer updated successfully", customer))
+	fmt.Println("Customer updated successfully:", customer.ID)
+}
+
+func (c *CustomerController) DeleteCustomer(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Deleting customer...")
+	customerID := resourceID(r)
+
+	if err := c.service.DeleteCustomer(r.Context(), customerID); err != nil {
+		writeCustomerError(w, err)
+		fmt.Println("Error deleting customer:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Customer deleted successfully", nil))
+	fmt.Println("Customer deleted successfully with ID:", customerID)
+}
+
+func (c *CustomerController) AdjustStoreCredit(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Adjusting store credit...")
+
+	customer, err := c.service.AdjustStoreCredit(r.Context(), resourceID(r), r.Body)
+	if err != nil {
+		writeCustomerError(w, err)
+		fmt.Println("Error adjusting store credit:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Store credit adjusted successfully", customer))
+	fmt.Println("Store credit adjusted successfully:", customer.ID, customer.StoreCredit)
+}
+
+func (c *CustomerController) GetPurchaseHistory(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Fetching customer purchase history...")
+
+	history, err := c.service.GetPurchaseHistory(r.Context(), resourceID(r))
+	if err != nil {
+		writeCustomerError(w, err)
+		fmt.Println("Error fetching purchase history:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Purchase history fetched successfully", history))
+	fmt.Println("Purchase history fetched successfully:", history.Customer.ID, len(history.Sales))
+}
+
+func writeCustomerError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, repository.ErrNotFound) {
+		status = http.StatusNotFound
+	}
+	utils.ResponseWritter(w, status, responseFormatter.FormatResponse(status, err.Error(), nil))
+}
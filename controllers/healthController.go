@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	responseFormatter "productmanagerapi/responseFormatter"
+	"productmanagerapi/utils"
+	"time"
+)
+
+// readinessTimeout bounds how long the readiness probe waits on the database.
+const readinessTimeout = 2 * time.Second
+
+// Pinger is implemented by anything the readiness probe can check.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type HealthController struct {
+	db Pinger
+}
+
+func NewHealthController(db Pinger) *HealthController {
+	return &HealthController{db: db}
+}
+
+// Live reports that the process is up and serving requests.
+func (c *HealthController) Live(w http.ResponseWriter, r *http.Request) {
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "ok", nil))
+}
+
+// Ready reports whether the API can serve traffic, i.e. the database answers.
+func (c *HealthController) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	if err := c.db.Ping(ctx); err != nil {
+		utils.ResponseWritter(w, http.StatusServiceUnavailable, responseFormatter.FormatResponse(http.StatusServiceUnavailable, "Database unavailable", nil))
+		fmt.Println("Readiness check failed:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "ready", nil))
+}
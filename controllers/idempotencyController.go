@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	responseFormatter "productmanagerapi/responseFormatter"
+	"productmanagerapi/services"
+	"productmanagerapi/utils"
+	"strings"
+)
+
+// IdempotencyKeyHeader lets a client retry a request safely: the first
+// response to a key is stored and replayed to later requests with the same
+// key, which do not run again.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on replayed responses.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+type IdempotencyController struct {
+	service *services.IdempotencyService
+}
+
+func NewIdempotencyController(service *services.IdempotencyService) *IdempotencyController {
+	return &IdempotencyController{service: service}
+}
+
+// Wrap makes POST, PUT, PATCH and DELETE requests to next idempotent when
+// they carry an Idempotency-Key header. It must run after authentication,
+// as keys belong to the caller. A key reused with a different method, path
+// or body answers 422, and one whose first request is still running 409.
+// Server errors and handlers that panic are not stored, so the request can
+// be retried with the same key. Responses are replayed with their
+// Content-Type, Location and Link headers.
+func (c *IdempotencyController) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if _, found := r.Header[http.CanonicalHeaderKey(IdempotencyKeyHeader)]; !found || !isIdempotentMethod(r.Method) {
+			next(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, "Error reading request body", nil))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := sha256.New()
+		fmt.Fprintf(fingerprint, "%s %s\n", r.Method, r.URL.RequestURI())
+		fingerprint.Write(body)
+
+		record, replay, err := c.service.Begin(r.Context(), key, hex.EncodeToString(fingerprint.Sum(nil)))
+		if err != nil {
+			status := http.StatusInternalServerError
+			message := "Error checking the idempotency key"
+			switch {
+			case errors.Is(err, services.ErrInvalidIdempotencyKey):
+				status, message = http.StatusBadRequest, err.Error()
+			case errors.Is(err, services.ErrIdempotencyKeyReused):
+				status, message = http.StatusUnprocessableEntity, err.Error()
+			case errors.Is(err, services.ErrIdempotencyKeyInProgress):
+				status, message = http.StatusConflict, err.Error()
+			default:
+				fmt.Println("Error checking idempotency key:", err)
+			}
+			utils.ResponseWritter(w, status, responseFormatter.FormatResponse(status, message, nil))
+			return
+		}
+
+		if replay {
+			w.Header().Set("Content-Type", record.ContentType)
+			if record.Location != "" {
+				w.Header().Set("Location", record.Location)
+			}
+			if record.Link != "" {
+				w.Header().Set("Link", record.Link)
+			}
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			w.WriteHeader(record.Status)
+			w.Write(record.Body)
+			fmt.Printf("Replayed %s %s for idempotency key %q\n", r.Method, r.URL.Path, key)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		release := c.service.Hold(r.Context(), record)
+		defer func() {
+			release()
+			// The response is stored even when the client has left.
+			ctx := context.WithoutCancel(r.Context())
+			if recovered := recover(); recovered != nil {
+				if err := c.service.Abandon(ctx, record); err != nil {
+					fmt.Println("Error freeing idempotency key:", err)
+				}
+				panic(recovered)
+			}
+
+			var err error
+			if recorder.status == 0 || recorder.status >= http.StatusInternalServerError {
+				err = c.service.Abandon(ctx, record)
+			} else {
+				record.Status = recorder.status
+				record.ContentType = w.Header().Get("Content-Type")
+				record.Location = w.Header().Get("Location")
+				record.Link = strings.Join(w.Header().Values("Link"), ", ")
+				record.Body = recorder.body.Bytes()
+				err = c.service.Complete(ctx, record)
+			}
+			if err != nil {
+				fmt.Println("Error storing idempotent response:", err)
+			}
+		}()
+		next(recorder, r)
+	}
+}
+
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// responseRecorder copies what a handler writes, passing it through.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
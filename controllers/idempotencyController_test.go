@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"productmanagerapi/auth"
+	"productmanagerapi/config"
+	"productmanagerapi/repository"
+	"productmanagerapi/services"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	config.App = config.Default()
+	os.Exit(m.Run())
+}
+
+// idempotentRequest sends a POST with an Idempotency-Key to handler, as an
+// authenticated user, and returns the response.
+func idempotentRequest(handler http.HandlerFunc, key string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/sales", strings.NewReader(`{"products":[]}`))
+	r.Header.Set(IdempotencyKeyHeader, key)
+	r = r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{UserID: 1, Username: "admin", Role: auth.RoleAdmin}))
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func TestIdempotencyReplaysHeaders(t *testing.T) {
+	c := NewIdempotencyController(services.NewIdempotencyService(repository.NewMemoryStore()))
+	runs := 0
+	handler := c.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/api/v1/sales/7")
+		w.Header().Add("Link", `</api/v1/sales?offset=0>; rel="first"`)
+		w.Header().Add("Link", `</api/v1/sales?offset=20>; rel="next"`)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":7}`))
+	})
+
+	idempotentRequest(handler, "sale-1")
+	replayed := idempotentRequest(handler, "sale-1")
+	if runs != 1 || replayed.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Fatalf("got %d runs, want the retry replayed without running", runs)
+	}
+	if replayed.Code != http.StatusCreated || replayed.Body.String() != `{"id":7}` {
+		t.Fatalf("got %d %s, want the stored 201", replayed.Code, replayed.Body)
+	}
+	if location := replayed.Header().Get("Location"); location != "/api/v1/sales/7" {
+		t.Fatalf("got Location %q, want it replayed", location)
+	}
+	if link := replayed.Header().Get("Link"); link != `</api/v1/sales?offset=0>; rel="first", </api/v1/sales?offset=20>; rel="next"` {
+		t.Fatalf("got Link %q, want both links replayed", link)
+	}
+}
+
+func TestIdempotencyFreesKeyAfterPanic(t *testing.T) {
+	c := NewIdempotencyController(services.NewIdempotencyService(repository.NewMemoryStore()))
+	panicking := c.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != "boom" {
+				t.Fatalf("got %v, want the panic passed on", recovered)
+			}
+		}()
+		idempotentRequest(panicking, "sale-1")
+	}()
+
+	retried := idempotentRequest(c.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}), "sale-1")
+	if retried.Code != http.StatusCreated || retried.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("got %d, want the retry to run once the panicking request freed its key", retried.Code)
+	}
+}
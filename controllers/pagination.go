@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	responseFormatter "productmanagerapi/responseFormatter"
+	"productmanagerapi/services"
+	"productmanagerapi/utils"
+	"strconv"
+)
+
+// writePage answers with a page of a listing: data holds its records and
+// page, next to data in the response, says where the page stands. Link
+// headers point to the next page when there is one and, for listings paged
+// by offset, to the first, previous and last pages.
+func writePage(w http.ResponseWriter, r *http.Request, message string, data any, page services.Page) {
+	if page.NextCursor != "" {
+		addPageLink(w, r, "next", "cursor", page.NextCursor)
+	}
+	if page.Offset != nil {
+		offset, limit := *page.Offset, int64(page.Limit)
+		last := int64(0)
+		if page.Total > 0 {
+			last = (page.Total - 1) / limit * limit
+		}
+		addPageLink(w, r, "first", "offset", "0")
+		if offset > 0 {
+			addPageLink(w, r, "prev", "offset", strconv.FormatInt(max(int64(offset)-limit, 0), 10))
+		}
+		if int64(offset)+limit < page.Total {
+			addPageLink(w, r, "next", "offset", strconv.FormatInt(int64(offset)+limit, 10))
+		}
+		addPageLink(w, r, "last", "offset", strconv.FormatInt(last, 10))
+	}
+
+	response := responseFormatter.FormatResponse(http.StatusOK, message, data)
+	response["page"] = page
+	utils.ResponseWritter(w, http.StatusOK, response)
+}
+
+// addPageLink adds a Link header to the request's URL with the query
+// parameter name set to value.
+func addPageLink(w http.ResponseWriter, r *http.Request, rel, name, value string) {
+	target := *r.URL
+	query := target.Query()
+	query.Set(name, value)
+	target.RawQuery = query.Encode()
+	w.Header().Add("Link", fmt.Sprintf("<%s>; rel=%q", target.RequestURI(), rel))
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"productmanagerapi/payments"
+	"productmanagerapi/repository"
+	responseFormatter "productmanagerapi/responseFormatter"
+	"productmanagerapi/services"
+	"productmanagerapi/utils"
+)
+
+type PaymentController struct {
+	service *services.PaymentService
+}
+
+func NewPaymentController(service *services.PaymentService) *PaymentController {
+	return &PaymentController{service: service}
+}
+
+func (c *PaymentController) CreatePayment(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Recording a payment...")
+
+	result, err := c.service.CreatePayment(r.Context(), resourceID(r), r.Body)
+	if err != nil {
+		writePaymentError(w, err)
+		fmt.Println("Error recording payment:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusCreated, responseFormatter.FormatResponse(http.StatusCreated, "Payment recorded successfully", result))
+	fmt.Println("Payment recorded successfully:", result.Sale.ID, result.Balance)
+}
+
+func (c *PaymentController) GetSalePayments(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Fetching payments of sale...")
+
+	summary, err := c.service.GetSalePayments(r.Context(), resourceID(r))
+	if err != nil {
+		writePaymentError(w, err)
+		fmt.Println("Error fetching payments:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Payments fetched successfully", summary))
+	fmt.Println("Payments fetched successfully:", len(summary.Payments))
+}
+
+func writePaymentError(w http.ResponseWriter, err error) {
+	var statusErr *services.SaleStatusError
+	switch {
+	case errors.Is(err, payments.ErrDeclined):
+		utils.ResponseWritter(w, http.StatusPaymentRequired, responseFormatter.FormatResponse(http.StatusPaymentRequired, err.Error(), nil))
+	case errors.As(err, &statusErr):
+		utils.ResponseWritter(w, http.StatusConflict, responseFormatter.FormatResponse(http.StatusConflict, err.Error(), map[string]interface{}{
+			"status":  statusErr.Status,
+			"allowed": statusErr.Allowed(),
+		}))
+	case errors.Is(err, repository.ErrNotFound):
+		utils.ResponseWritter(w, http.StatusNotFound, responseFormatter.FormatResponse(http.StatusNotFound, err.Error(), nil))
+	case errors.Is(err, services.ErrInvalidSale), errors.Is(err, payments.ErrUnavailable):
+		utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, err.Error(), nil))
+	default:
+		utils.ResponseWritter(w, http.StatusInternalServerError, responseFormatter.FormatResponse(http.StatusInternalServerError, "Error processing the payment", nil))
+	}
+}
@@ -1,49 +1,49 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	responseFormatter "productmanagerapi/responseFormatter"
 	"productmanagerapi/services"
 	"productmanagerapi/utils"
-	requestMethodValidator "productmanagerapi/utils"
 )
 
-var GetAllProducts = func(w http.ResponseWriter, r *http.Request) {
+type ProductController struct {
+	service *services.ProductService
+}
 
-	isValidMethod := requestMethodValidator.RequestMethodValidator(w, *r, http.MethodGet)
-	if !isValidMethod {
-		return
-	}
+func NewProductController(service *services.ProductService) *ProductController {
+	return &ProductController{service: service}
+}
 
+func (c *ProductController) GetAllProducts(w http.ResponseWriter, r *http.Request) {
 	utils.Log(r, "Fetcing all Products...")
 
 	w.Header().Set("Content-Type", "application/json")
 
-	products, err := services.GetAllProducts()
+	products, page, err := c.service.GetAllProducts(r.Context(), r.URL.Query())
+	if errors.Is(err, services.ErrInvalidFilter) {
+		utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
 	if err != nil {
 		utils.ResponseWritter(w, http.StatusInternalServerError, responseFormatter.FormatResponse(http.StatusInternalServerError, "Error fetching products", nil))
 		fmt.Println("Error fetching products:", err)
 		return
 	}
 
-	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Products fetched successfully", products))
-	fmt.Println("Products fetched successfully:", len(products))
+	writePage(w, r, "Products fetched successfully", products, page)
+	fmt.Println("Products fetched successfully:", page.Total)
 
 }
 
-var GetProductByID = func(w http.ResponseWriter, r *http.Request) {
-
-	isValidMethod := requestMethodValidator.RequestMethodValidator(w, *r, http.MethodGet)
-	if !isValidMethod {
-		return
-	}
-
+func (c *ProductController) GetProductByID(w http.ResponseWriter, r *http.Request) {
 	utils.Log(r, "Fetching Product...")
 
 	w.Header().Set("Content-Type", "application/json")
 
-	prodcuct, err := services.GetProductByID(r.URL.Query().Get("id"))
+	prodcuct, err := c.service.GetProductByID(r.Context(), resourceID(r))
 	if err != nil {
 		utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, err.Error(), nil))
 		fmt.Println("Error fetching product by ID:", err)
@@ -55,18 +55,12 @@ var GetProductByID = func(w http.ResponseWriter, r *http.Request) {
 
 }
 
-var CreateProduct = func(w http.ResponseWriter, r *http.Request) {
-
-	isValidMethod := requestMethodValidator.RequestMethodValidator(w, *r, http.MethodPost)
-	if !isValidMethod {
-		return
-	}
-
+func (c *ProductController) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	utils.Log(r, "Creating a new Product...")
 
 	w.Header().Set("Content-Type", "application/json")
 
-	product, err := services.CreateProduct(r.Body)
+	product, err := c.service.CreateProduct(r.Context(), r.Body)
 	if err != nil {
 		utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, err.Error(), nil))
 		fmt.Println("Error creating product:", err)
@@ -76,17 +70,11 @@ var CreateProduct = func(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("Product created successfully:", product.ID, product.Name)
 }
 
-var UpdateProduct = func(w http.ResponseWriter, r *http.Request) {
-
-	isValidMethod := requestMethodValidator.RequestMethodValidator(w, *r, http.MethodPut)
-	if !isValidMethod {
-		return
-	}
-
+func (c *ProductController) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	utils.Log(r, "Updating Product...")
 	w.Header().Set("Content-Type", "application/json")
 
-	product, err := services.UpdateProduct(r.URL.Query().Get("id"), r.Body)
+	product, err := c.service.UpdateProduct(r.Context(), resourceID(r), r.Body)
 	if err != nil {
 		utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, err.Error(), nil))
 		fmt.Println("Error updating product:", err)
@@ -96,18 +84,26 @@ var UpdateProduct = func(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("Product updated successfully:", product.ID, product.Name)
 }
 
-var DeleteProduct = func(w http.ResponseWriter, r *http.Request) {
+func (c *ProductController) PatchProduct(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Patching Product...")
+	w.Header().Set("Content-Type", "application/json")
 
-	isValidMethod := requestMethodValidator.RequestMethodValidator(w, *r, http.MethodDelete)
-	if !isValidMethod {
+	product, err := c.service.PatchProduct(r.Context(), resourceID(r), r.Body)
+	if err != nil {
+		utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, err.Error(), nil))
+		fmt.Println("Error patching product:", err)
 		return
 	}
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Product updated successfully", product))
+	fmt.Println("Product patched successfully:", product.ID, product.Name)
+}
 
+func (c *ProductController) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	utils.Log(r, "Deleting Product...")
 
 	w.Header().Set("Content-Type", "application/json")
 
-	err := services.DeleteProduct(r.URL.Query().Get("id"))
+	err := c.service.DeleteProduct(r.Context(), resourceID(r))
 	if err != nil {
 		utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, err.Error(), nil))
 		fmt.Println("Error deleting product:", err)
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"productmanagerapi/repository"
+	responseFormatter "productmanagerapi/responseFormatter"
+	"productmanagerapi/services"
+	"productmanagerapi/utils"
+)
+
+type PromotionController struct {
+	service *services.PromotionService
+}
+
+func NewPromotionController(service *services.PromotionService) *PromotionController {
+	return &PromotionController{service: service}
+}
+
+func (c *PromotionController) GetAllPromotions(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Fetching all promotions...")
+
+	promotions, err := c.service.GetAllPromotions(r.Context())
+	if err != nil {
+		utils.ResponseWritter(w, http.StatusInternalServerError, responseFormatter.FormatResponse(http.StatusInternalServerError, "Error fetching promotions", nil))
+		fmt.Println("Error fetching promotions:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Promotions fetched successfully", promotions))
+	fmt.Println("Promotions fetched successfully:", len(promotions))
+}
+
+func (c *PromotionController) GetPromotionByID(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Fetching promotion...")
+
+	promotion, err := c.service.GetPromotionByID(r.Context(), resourceID(r))
+	if err != nil {
+		writePromotionError(w, err)
+		fmt.Println("Error fetching promotion:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Promotion fetched successfully", promotion))
+	fmt.Println("Promotion fetched successfully:", promotion.ID)
+}
+
+func (c *PromotionController) CreatePromotion(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Creating a new promotion...")
+
+	promotion, err := c.service.CreatePromotion(r.Context(), r.Body)
+	if err != nil {
+		writePromotionError(w, err)
+		fmt.Println("Error creating promotion:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusCreated, responseFormatter.FormatResponse(http.StatusCreated, "Promotion created successfully", promotion))
+	fmt.Println("Promotion created successfully:", promotion.ID)
+}
+
+func (c *PromotionController) UpdatePromotion(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Updating promotion...")
+
+	promotion, err := c.service.UpdatePromotion(r.Context(), resourceID(r), r.Body)
+	if err != nil {
+		writePromotionError(w, err)
+		fmt.Println("Error updating promotion:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Promotion updated successfully", promotion))
+	fmt.Println("Promotion updated successfully:", promotion.ID)
+}
+
+func (c *PromotionController) DeletePromotion(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Deleting promotion...")
+	promotionID := resourceID(r)
+
+	if err := c.service.DeletePromotion(r.Context(), promotionID); err != nil {
+		writePromotionError(w, err)
+		fmt.Println("Error deleting promotion:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Promotion deleted successfully", nil))
+	fmt.Println("Promotion deleted successfully with ID:", promotionID)
+}
+
+func writePromotionError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, repository.ErrNotFound) {
+		status = http.StatusNotFound
+	}
+	utils.ResponseWritter(w, status, responseFormatter.FormatResponse(status, err.Error(), nil))
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"productmanagerapi/repository"
+	responseFormatter "productmanagerapi/responseFormatter"
+	"productmanagerapi/services"
+	"productmanagerapi/utils"
+	"strconv"
+)
+
+type ReceiptController struct {
+	service *services.ReceiptService
+}
+
+func NewReceiptController(service *services.ReceiptService) *ReceiptController {
+	return &ReceiptController{service: service}
+}
+
+// GetReceipt answers with the receipt document itself rather than the usual
+// JSON envelope; errors still use the envelope.
+func (c *ReceiptController) GetReceipt(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Rendering receipt...")
+
+	document, err := c.service.GetReceipt(r.Context(), resourceID(r), r.URL.Query())
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, repository.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		utils.ResponseWritter(w, status, responseFormatter.FormatResponse(status, err.Error(), nil))
+		fmt.Println("Error rendering receipt:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", document.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", document.Filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(document.Body)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(document.Body); err != nil {
+		fmt.Println("Error writing receipt:", err)
+		return
+	}
+	fmt.Println("Receipt rendered successfully:", document.Filename)
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	responseFormatter "productmanagerapi/responseFormatter"
+	"productmanagerapi/services"
+	"productmanagerapi/utils"
+	"strings"
+)
+
+type ReportController struct {
+	service *services.ReportService
+}
+
+func NewReportController(service *services.ReportService) *ReportController {
+	return &ReportController{service: service}
+}
+
+func (c *ReportController) TaxSummary(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Building tax report...")
+
+	if !checkReportFormat(w, r) {
+		return
+	}
+	report, err := c.service.TaxSummary(r.Context(), r.URL.Query())
+	if err != nil {
+		writeReportError(w, err)
+		fmt.Println("Error building tax report:", err)
+		return
+	}
+
+	writeReport(w, r, "Tax report built successfully", report)
+	fmt.Println("Tax report built successfully:", report.SaleCount)
+}
+
+func (c *ReportController) RevenueByPeriod(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Building revenue report...")
+
+	if !checkReportFormat(w, r) {
+		return
+	}
+	report, err := c.service.RevenueByPeriod(r.Context(), r.URL.Query())
+	if err != nil {
+		writeReportError(w, err)
+		fmt.Println("Error building revenue report:", err)
+		return
+	}
+
+	writeReport(w, r, "Revenue report built successfully", report)
+	fmt.Println("Revenue report built successfully:", report.SaleCount)
+}
+
+func (c *ReportController) TopProducts(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Building top products report...")
+
+	if !checkReportFormat(w, r) {
+		return
+	}
+	report, err := c.service.TopProducts(r.Context(), r.URL.Query())
+	if err != nil {
+		writeReportError(w, err)
+		fmt.Println("Error building top products report:", err)
+		return
+	}
+
+	writeReport(w, r, "Top products report built successfully", report)
+	fmt.Println("Top products report built successfully:", len(report.Products))
+}
+
+func (c *ReportController) TopCategories(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Building top categories report...")
+
+	if !checkReportFormat(w, r) {
+		return
+	}
+	report, err := c.service.TopCategories(r.Context(), r.URL.Query())
+	if err != nil {
+		writeReportError(w, err)
+		fmt.Println("Error building top categories report:", err)
+		return
+	}
+
+	writeReport(w, r, "Top categories report built successfully", report)
+	fmt.Println("Top categories report built successfully:", len(report.Categories))
+}
+
+func (c *ReportController) SalesSummary(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Building sales summary...")
+
+	if !checkReportFormat(w, r) {
+		return
+	}
+	report, err := c.service.SalesSummary(r.Context(), r.URL.Query())
+	if err != nil {
+		writeReportError(w, err)
+		fmt.Println("Error building sales summary:", err)
+		return
+	}
+
+	writeReport(w, r, "Sales summary built successfully", report)
+	fmt.Println("Sales summary built successfully:", report.Current.SaleCount)
+}
+
+// checkReportFormat answers 400 when ?format= is neither json, the default,
+// nor csv.
+func checkReportFormat(w http.ResponseWriter, r *http.Request) bool {
+	switch strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format"))) {
+	case "", "json", "csv":
+		return true
+	}
+	utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, "format must be json or csv", nil))
+	return false
+}
+
+// writeReport answers with report in the usual JSON envelope, or as a CSV
+// attachment with ?format=csv.
+func writeReport(w http.ResponseWriter, r *http.Request, message string, report services.Report) {
+	if !strings.EqualFold(strings.TrimSpace(r.URL.Query().Get("format")), "csv") {
+		utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, message, report))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", report.Filename()+".csv"))
+	w.WriteHeader(http.StatusOK)
+	if err := csv.NewWriter(w).WriteAll(report.Rows()); err != nil {
+		fmt.Println("Error writing report:", err)
+	}
+}
+
+func writeReportError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrInvalidFilter) {
+		utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	utils.ResponseWritter(w, http.StatusInternalServerError, responseFormatter.FormatResponse(http.StatusInternalServerError, "Error building report", nil))
+}
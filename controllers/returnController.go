@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"productmanagerapi/repository"
+	responseFormatter "productmanagerapi/responseFormatter"
+	"productmanagerapi/services"
+	"productmanagerapi/utils"
+)
+
+type ReturnController struct {
+	service *services.ReturnService
+}
+
+func NewReturnController(service *services.ReturnService) *ReturnController {
+	return &ReturnController{service: service}
+}
+
+func (c *ReturnController) CreateReturn(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Creating a return...")
+
+	saleReturn, err := c.service.CreateReturn(r.Context(), resourceID(r), r.Body)
+	if err != nil {
+		status := http.StatusBadRequest
+		var statusErr *services.SaleStatusError
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.As(err, &statusErr):
+			status = http.StatusConflict
+		}
+		utils.ResponseWritter(w, status, responseFormatter.FormatResponse(status, err.Error(), nil))
+		fmt.Println("Error creating return:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusCreated, responseFormatter.FormatResponse(http.StatusCreated, "Return created successfully", saleReturn))
+	fmt.Println("Return created successfully:", saleReturn.ID, saleReturn.RefundTotal)
+}
+
+func (c *ReturnController) GetSaleReturns(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Fetching returns of sale...")
+
+	returns, err := c.service.GetSaleReturns(r.Context(), resourceID(r))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, repository.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		utils.ResponseWritter(w, status, responseFormatter.FormatResponse(status, err.Error(), nil))
+		fmt.Println("Error fetching returns:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Returns fetched successfully", returns))
+}
+
+func (c *ReturnController) GetReturnByID(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Fetching return...")
+
+	saleReturn, err := c.service.GetReturnByID(r.Context(), resourceID(r))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, repository.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		utils.ResponseWritter(w, status, responseFormatter.FormatResponse(status, err.Error(), nil))
+		fmt.Println("Error fetching return:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Return fetched successfully", saleReturn))
+}
@@ -1,63 +1,75 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"productmanagerapi/payments"
+	"productmanagerapi/repository"
 	responseFormatter "productmanagerapi/responseFormatter"
 	"productmanagerapi/services"
 	"productmanagerapi/utils"
-	requestMethodValidator "productmanagerapi/utils"
+	"strings"
 )
 
-var CreateSale = func(w http.ResponseWriter, r *http.Request) {
-	isValidMethod := requestMethodValidator.RequestMethodValidator(w, *r, http.MethodPost)
-	if !isValidMethod {
-		return
-	}
+type SaleController struct {
+	service *services.SaleService
+}
+
+func NewSaleController(service *services.SaleService) *SaleController {
+	return &SaleController{service: service}
+}
 
+func (c *SaleController) CreateSale(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("Processing sale creation...")
 	w.Header().Set("Content-Type", "application/json")
 
-	sale, err := services.CreateSale(r.Body)
-	if err != nil {
-		utils.ResponseWritter(w, http.StatusInternalServerError, nil)
-		fmt.Println("Error while creating sale")
+	sale, err := c.service.CreateSale(r.Context(), r.Body)
+	if !c.handleSaleError(w, err, "Error while creating sale:") {
+		return
 	}
 
 	utils.ResponseWritter(w, http.StatusCreated, responseFormatter.FormatResponse(http.StatusCreated, "Sale created successfully", sale))
 	fmt.Println("Sale creation response sent successfully")
 }
 
-var GetSales = func(w http.ResponseWriter, r *http.Request) {
-	isValidMethod := requestMethodValidator.RequestMethodValidator(w, *r, http.MethodGet)
-	if !isValidMethod {
+func (c *SaleController) CreateLegacySale(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("Processing sale creation...")
+	w.Header().Set("Content-Type", "application/json")
+
+	sale, err := c.service.CreateLegacySale(r.Context(), r.Body)
+	if !c.handleSaleError(w, err, "Error while creating sale:") {
 		return
 	}
 
+	utils.ResponseWritter(w, http.StatusCreated, responseFormatter.FormatResponse(http.StatusCreated, "Sale created successfully", sale))
+	fmt.Println("Sale creation response sent successfully")
+}
+
+func (c *SaleController) GetSales(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("Processing sale retrieval...")
 	w.Header().Set("Content-Type", "application/json")
 
-	sales, err := services.GetAllSales()
+	sales, page, err := c.service.GetAllSales(r.Context(), r.URL.Query())
+	if errors.Is(err, services.ErrInvalidFilter) {
+		utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
 	if err != nil {
 		utils.ResponseWritter(w, http.StatusInternalServerError, responseFormatter.FormatResponse(http.StatusInternalServerError, "Error while fetching sales", nil))
+		return
 	}
 
-	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Sale fetched successfully", sales))
-
+	writePage(w, r, "Sale fetched successfully", sales, page)
 }
 
-var GetSaleByID = func(w http.ResponseWriter, r *http.Request) {
-	isValidMethod := requestMethodValidator.RequestMethodValidator(w, *r, http.MethodGet)
-	if !isValidMethod {
-		return
-	}
-
+func (c *SaleController) GetSaleByID(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("Processing sale retrieval by ID...")
 	w.Header().Set("Content-Type", "application/json")
 
-	sale, err := services.GetSaleByID(r.URL.Query().Get("id"))
-	if err != nil {
-		utils.ResponseWritter(w, http.StatusInternalServerError, responseFormatter.FormatResponse(http.StatusInternalServerError, "Error while fetching sale", nil))
+	sale, err := c.service.GetSaleByID(r.Context(), resourceID(r))
+	if !c.handleSaleError(w, err, "Error while fetching sale:") {
+		return
 	}
 
 	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Sale fetched successfully", sale))
@@ -65,22 +77,118 @@ var GetSaleByID = func(w http.ResponseWriter, r *http.Request) {
 
 }
 
-var DeleteSale = func(w http.ResponseWriter, r *http.Request) {
-	isValidMethod := requestMethodValidator.RequestMethodValidator(w, *r, http.MethodDelete)
-	if !isValidMethod {
-		return
-	}
-
+func (c *SaleController) DeleteSale(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("Processing sale deletion...")
 	w.Header().Set("Content-Type", "application/json")
 
-	err := services.DeleteSale(r.URL.Query().Get("id"))
-	if err != nil {
-		utils.ResponseWritter(w, http.StatusInternalServerError, responseFormatter.FormatResponse(http.StatusInternalServerError, "Error while deleting sale", nil))
-		fmt.Println("Error while deleting sale")
+	err := c.service.DeleteSale(r.Context(), resourceID(r))
+	if !c.handleSaleError(w, err, "Error while deleting sale:") {
+		return
 	}
 
-	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Sale deleted successfully", r.URL.Query().Get("id")))
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Sale deleted successfully", resourceID(r)))
 	fmt.Println("Sale deleted successfully")
 
 }
+
+func (c *SaleController) AmendSale(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Amending sale...")
+
+	sale, err := c.service.AmendSale(r.Context(), resourceID(r), r.Body)
+	if !c.handleSaleError(w, err, "Error while amending sale:") {
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Sale amended successfully", sale))
+	fmt.Println("Sale amended successfully:", sale.ID)
+}
+
+func (c *SaleController) ConfirmSale(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Confirming sale...")
+
+	sale, err := c.service.ConfirmSale(r.Context(), resourceID(r))
+	if !c.handleSaleError(w, err, "Error while confirming sale:") {
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Sale confirmed successfully", sale))
+	fmt.Println("Sale confirmed successfully:", sale.ID)
+}
+
+func (c *SaleController) PaySale(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Marking sale as paid...")
+
+	sale, err := c.service.PaySale(r.Context(), resourceID(r))
+	if !c.handleSaleError(w, err, "Error while marking sale as paid:") {
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Sale paid successfully", sale))
+	fmt.Println("Sale paid successfully:", sale.ID)
+}
+
+func (c *SaleController) FulfillSale(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Fulfilling sale...")
+
+	sale, err := c.service.FulfillSale(r.Context(), resourceID(r))
+	if !c.handleSaleError(w, err, "Error while fulfilling sale:") {
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Sale fulfilled successfully", sale))
+	fmt.Println("Sale fulfilled successfully:", sale.ID)
+}
+
+func (c *SaleController) CancelSale(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Cancelling sale...")
+
+	sale, err := c.service.CancelSale(r.Context(), resourceID(r), r.Body)
+	if !c.handleSaleError(w, err, "Error while cancelling sale:") {
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Sale cancelled successfully", sale))
+	fmt.Println("Sale cancelled successfully:", sale.ID)
+}
+
+// handleSaleError writes the response for err, if any, and reports whether
+// the handler should go on. Errors the caller cannot fix, such as failures of
+// the store, answer 500 without their details.
+func (c *SaleController) handleSaleError(w http.ResponseWriter, err error, logPrefix string) bool {
+	if err == nil {
+		return true
+	}
+
+	var conflict *services.StockConflictError
+	var statusErr *services.SaleStatusError
+	var balanceErr *services.BalanceDueError
+	switch {
+	case errors.Is(err, payments.ErrDeclined):
+		utils.ResponseWritter(w, http.StatusPaymentRequired, responseFormatter.FormatResponse(http.StatusPaymentRequired, err.Error(), nil))
+	case errors.As(err, &balanceErr):
+		utils.ResponseWritter(w, http.StatusConflict, responseFormatter.FormatResponse(http.StatusConflict, err.Error(), map[string]interface{}{
+			"balance": balanceErr.Balance,
+		}))
+	case errors.As(err, &conflict):
+		utils.ResponseWritter(w, http.StatusConflict, responseFormatter.FormatResponse(http.StatusConflict, err.Error(), map[string]interface{}{
+			"lines": conflict.Lines,
+		}))
+	case errors.Is(err, services.ErrSaleInvoiced), errors.Is(err, services.ErrSaleHasPayments):
+		utils.ResponseWritter(w, http.StatusConflict, responseFormatter.FormatResponse(http.StatusConflict, err.Error(), nil))
+	case errors.As(err, &statusErr):
+		utils.ResponseWritter(w, http.StatusConflict, responseFormatter.FormatResponse(http.StatusConflict, err.Error(), map[string]interface{}{
+			"status":  statusErr.Status,
+			"allowed": statusErr.Allowed(),
+		}))
+	case errors.Is(err, services.ErrPriceOverrideNotAllowed), errors.Is(err, services.ErrCancelPaidNotAllowed), errors.Is(err, services.ErrDiscountNotAllowed):
+		utils.ResponseWritter(w, http.StatusForbidden, responseFormatter.FormatResponse(http.StatusForbidden, err.Error(), nil))
+	case errors.Is(err, repository.ErrNotFound):
+		utils.ResponseWritter(w, http.StatusNotFound, responseFormatter.FormatResponse(http.StatusNotFound, err.Error(), nil))
+	case errors.Is(err, services.ErrInvalidSale), errors.Is(err, payments.ErrUnavailable):
+		utils.ResponseWritter(w, http.StatusBadRequest, responseFormatter.FormatResponse(http.StatusBadRequest, err.Error(), nil))
+	default:
+		utils.ResponseWritter(w, http.StatusInternalServerError, responseFormatter.FormatResponse(http.StatusInternalServerError, strings.TrimSuffix(logPrefix, ":"), nil))
+	}
+	fmt.Println(logPrefix, err)
+	return false
+}
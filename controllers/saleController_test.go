@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"productmanagerapi/auth"
+	"productmanagerapi/payments"
+	"productmanagerapi/repository"
+	"productmanagerapi/services"
+	"strings"
+	"testing"
+)
+
+func TestSaleErrorStatuses(t *testing.T) {
+	c := NewSaleController(services.New(repository.NewMemoryStore(), payments.NewNoneProvider()).Sales)
+	admin := auth.Principal{UserID: 1, Username: "admin", Role: auth.RoleAdmin}
+
+	for _, test := range []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		id      string
+		body    string
+		want    int
+	}{
+		{"missing sale", c.GetSaleByID, http.MethodGet, "42", "", http.StatusNotFound},
+		{"malformed id", c.GetSaleByID, http.MethodGet, "forty-two", "", http.StatusBadRequest},
+		{"no lines", c.CreateSale, http.MethodPost, "", `{"products":[]}`, http.StatusBadRequest},
+		{"malformed body", c.CreateSale, http.MethodPost, "", `{"products":`, http.StatusBadRequest},
+		{"deleting a missing sale", c.DeleteSale, http.MethodDelete, "42", "", http.StatusNotFound},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, "/api/v1/sales", strings.NewReader(test.body))
+			r.SetPathValue("id", test.id)
+			r = r.WithContext(auth.WithPrincipal(r.Context(), admin))
+			w := httptest.NewRecorder()
+			test.handler(w, r)
+			if w.Code != test.want {
+				t.Fatalf("got %d %s, want %d", w.Code, w.Body, test.want)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	if c.handleSaleError(w, errors.New("database is locked"), "Error while creating sale:") {
+		t.Fatal("the handler goes on after an error")
+	}
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "database") {
+		t.Fatalf("got %d %s, want 500 without the details of the failure", w.Code, w.Body)
+	}
+}
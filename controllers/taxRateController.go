@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"productmanagerapi/repository"
+	responseFormatter "productmanagerapi/responseFormatter"
+	"productmanagerapi/services"
+	"productmanagerapi/utils"
+)
+
+type TaxRateController struct {
+	service *services.TaxRateService
+}
+
+func NewTaxRateController(service *services.TaxRateService) *TaxRateController {
+	return &TaxRateController{service: service}
+}
+
+func (c *TaxRateController) GetAllTaxRates(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Fetching all tax rates...")
+
+	rates, err := c.service.GetAllTaxRates(r.Context())
+	if err != nil {
+		utils.ResponseWritter(w, http.StatusInternalServerError, responseFormatter.FormatResponse(http.StatusInternalServerError, "Error fetching tax rates", nil))
+		fmt.Println("Error fetching tax rates:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Tax rates fetched successfully", rates))
+	fmt.Println("Tax rates fetched successfully:", len(rates))
+}
+
+func (c *TaxRateController) GetTaxRateByID(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Fetching tax rate...")
+
+	rate, err := c.service.GetTaxRateByID(r.Context(), resourceID(r))
+	if err != nil {
+		writeTaxRateError(w, err)
+		fmt.Println("Error fetching tax rate:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Tax rate fetched successfully", rate))
+	fmt.Println("Tax rate fetched successfully:", rate.ID)
+}
+
+func (c *TaxRateController) CreateTaxRate(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Creating a new tax rate...")
+
+	rate, err := c.service.CreateTaxRate(r.Context(), r.Body)
+	if err != nil {
+		writeTaxRateError(w, err)
+		fmt.Println("Error creating tax rate:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusCreated, responseFormatter.FormatResponse(http.StatusCreated, "Tax rate created successfully", rate))
+	fmt.Println("Tax rate created successfully:", rate.ID)
+}
+
+func (c *TaxRateController) UpdateTaxRate(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Updating tax rate...")
+
+	rate, err := c.service.UpdateTaxRate(r.Context(), resourceID(r), r.Body)
+	if err != nil {
+		writeTaxRateError(w, err)
+		fmt.Println("Error updating tax rate:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Tax rate updated successfully", rate))
+	fmt.Println("Tax rate updated successfully:", rate.ID)
+}
+
+func (c *TaxRateController) DeleteTaxRate(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Deleting tax rate...")
+	rateID := resourceID(r)
+
+	if err := c.service.DeleteTaxRate(r.Context(), rateID); err != nil {
+		writeTaxRateError(w, err)
+		fmt.Println("Error deleting tax rate:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Tax rate deleted successfully", nil))
+	fmt.Println("Tax rate deleted successfully with ID:", rateID)
+}
+
+func writeTaxRateError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, repository.ErrNotFound) {
+		status = http.StatusNotFound
+	}
+	utils.ResponseWritter(w, status, responseFormatter.FormatResponse(status, err.Error(), nil))
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"productmanagerapi/repository"
+	responseFormatter "productmanagerapi/responseFormatter"
+	"productmanagerapi/services"
+	utils "productmanagerapi/utils"
+)
+
+type UserController struct {
+	service *services.UserService
+}
+
+func NewUserController(service *services.UserService) *UserController {
+	return &UserController{service: service}
+}
+
+func (c *UserController) GetAllUsers(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Fetching all users...")
+
+	users, err := c.service.GetAllUsers(r.Context())
+	if err != nil {
+		utils.ResponseWritter(w, http.StatusInternalServerError, responseFormatter.FormatResponse(http.StatusInternalServerError, "Error fetching users", nil))
+		fmt.Println("Error fetching users:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Users fetched successfully", users))
+	fmt.Println("Users fetched successfully:", len(users))
+}
+
+func (c *UserController) AssignRole(w http.ResponseWriter, r *http.Request) {
+	utils.Log(r, "Assigning user role...")
+
+	user, err := c.service.AssignRole(r.Context(), resourceID(r), r.Body)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, repository.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		utils.ResponseWritter(w, status, responseFormatter.FormatResponse(status, err.Error(), nil))
+		fmt.Println("Error assigning role:", err)
+		return
+	}
+
+	utils.ResponseWritter(w, http.StatusOK, responseFormatter.FormatResponse(http.StatusOK, "Role assigned successfully", user))
+	fmt.Println("Role assigned:", user.ID, user.Role)
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"productmanagerapi/config"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// maxRetryDelay caps the exponential backoff between connection attempts.
+const maxRetryDelay = 30 * time.Second
+
+type Database struct {
+	*gorm.DB
+	sqlDB  *sql.DB
+	driver string
+}
+
+// Open connects to the database described by cfg, retrying with exponential
+// backoff, and applies the connection pool settings.
+func Open(cfg config.DatabaseConfig) (*Database, error) {
+	dialector, err := newDialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var gormDB *gorm.DB
+
+	delay := cfg.ConnectRetryDelay
+	for attempt := 0; attempt <= cfg.ConnectRetries; attempt++ {
+		gormDB, err = gorm.Open(dialector, &gorm.Config{})
+		if err == nil {
+			break
+		}
+
+		if attempt == cfg.ConnectRetries {
+			return nil, fmt.Errorf("connecting to database after %d attempts: %w", attempt+1, err)
+		}
+
+		fmt.Printf("Database connection attempt %d failed: %v (retrying in %s)\n", attempt+1, err, delay)
+		time.Sleep(delay)
+		delay = min(delay*2, maxRetryDelay)
+	}
+
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Driver == config.DriverSQLite && isInMemory(cfg.DSN) {
+		// Every SQLite connection to :memory: gets its own empty database, so
+		// the pool is pinned to a single connection that is never recycled.
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		sqlDB.SetConnMaxIdleTime(0)
+	} else {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+		sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+
+	return &Database{DB: gormDB, sqlDB: sqlDB, driver: cfg.Driver}, nil
+}
+
+func newDialector(cfg config.DatabaseConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case config.DriverPostgres:
+		return postgres.Open(cfg.ConnectionString()), nil
+	case config.DriverSQLite:
+		return sqlite.Open(sqliteDSN(cfg.ConnectionString())), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+}
+
+// sqliteDSN sets the connection options the repositories rely on, unless
+// the DSN already sets them: foreign key enforcement, which SQLite leaves off
+// by default, a busy timeout, and immediate transactions. SQLite has no row
+// locks, so transactions take the write lock when they begin instead, which
+// keeps read-check-write sequences such as stock checks serialized.
+func sqliteDSN(dsn string) string {
+	options := []struct{ key, value string }{
+		{"foreign_keys", "_pragma=foreign_keys(1)"},
+		{"busy_timeout", "_pragma=busy_timeout(5000)"},
+		{"_txlock", "_txlock=immediate"},
+	}
+
+	for _, option := range options {
+		if strings.Contains(dsn, option.key) {
+			continue
+		}
+
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		dsn += separator + option.value
+	}
+
+	return dsn
+}
+
+func isInMemory(dsn string) bool {
+	return strings.HasPrefix(dsn, ":memory:") || strings.Contains(dsn, "mode=memory")
+}
+
+// Driver returns the name of the storage backend in use.
+func (d *Database) Driver() string {
+	return d.driver
+}
+
+// Ping reports whether the database is reachable. It backs the readiness
+// endpoint.
+func (d *Database) Ping(ctx context.Context) error {
+	return d.sqlDB.PingContext(ctx)
+}
+
+func (d *Database) Close() error {
+	return d.sqlDB.Close()
+}
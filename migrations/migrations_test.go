@@ -0,0 +1,142 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"productmanagerapi/config"
+	"productmanagerapi/database"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// openSQLite opens an empty SQLite database in memory.
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	cfg := config.Default().Database
+	cfg.Driver = config.DriverSQLite
+	cfg.DSN = ":memory:"
+	db, err := database.Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db.DB
+}
+
+func TestSaleStatusBackfillsLegacySales(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	migrator, err := New(db, config.DriverSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Bring the schema to just before 0007_sale_status and record a sale
+	// the way the API did then.
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Down(ctx, len(migrator.migrations)-6); err != nil {
+		t.Fatal(err)
+	}
+	createdAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	if err := db.Exec("INSERT INTO sales (created_at, updated_at, total) VALUES (?, ?, ?)", createdAt, createdAt, 12.5).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var sale struct {
+		Status        string
+		ConfirmedAt   *time.Time
+		PaidAt        *time.Time
+		FulfilledAt   *time.Time
+		PaidTotal     float64
+		InvoiceNumber *uint
+	}
+	if err := db.Raw("SELECT status, confirmed_at, paid_at, fulfilled_at, paid_total, invoice_number FROM sales").Scan(&sale).Error; err != nil {
+		t.Fatal(err)
+	}
+	if sale.Status != "fulfilled" || sale.PaidTotal != 12.5 {
+		t.Fatalf("got status %s paid %v, want a fulfilled sale paid in full", sale.Status, sale.PaidTotal)
+	}
+	for name, at := range map[string]*time.Time{"confirmed": sale.ConfirmedAt, "paid": sale.PaidAt, "fulfilled": sale.FulfilledAt} {
+		if at == nil || !at.Equal(createdAt) {
+			t.Fatalf("got %s at %v, want the creation time %v", name, at, createdAt)
+		}
+	}
+	if sale.InvoiceNumber == nil || *sale.InvoiceNumber != 1 {
+		t.Fatalf("got invoice %v, want the legacy sale numbered 1", sale.InvoiceNumber)
+	}
+}
+
+func TestDialectsShareVersions(t *testing.T) {
+	postgres, err := load(config.DriverPostgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := load(config.DriverSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(postgres) != len(sqlite) {
+		t.Fatalf("got %d postgres and %d sqlite migrations, want the same set", len(postgres), len(sqlite))
+	}
+	for i := range postgres {
+		if postgres[i].Version != sqlite[i].Version || postgres[i].Name != sqlite[i].Name {
+			t.Fatalf("migration %d is %04d_%s on postgres but %04d_%s on sqlite", i, postgres[i].Version, postgres[i].Name, sqlite[i].Version, sqlite[i].Name)
+		}
+	}
+}
+
+func TestUpDownRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	migrator, err := New(db, config.DriverSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrator.EnsureUpToDate(ctx); !errors.Is(err, ErrSchemaBehind) {
+		t.Fatalf("got %v, want an empty database behind", err)
+	}
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(migrator.migrations) {
+		t.Fatalf("applied %d of %d migrations", len(applied), len(migrator.migrations))
+	}
+	if err := migrator.EnsureUpToDate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := migrator.Up(ctx); err != nil || len(again) != 0 {
+		t.Fatalf("got %d migrations applied again and %v, want none", len(again), err)
+	}
+
+	rolledBack, err := migrator.Down(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := migrator.migrations[len(migrator.migrations)-1]
+	if len(rolledBack) != 2 || rolledBack[0].Version != last.Version {
+		t.Fatalf("got %d rolled back starting at %v, want the last 2 newest first", len(rolledBack), rolledBack)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses[len(statuses)-1].Applied || !statuses[len(statuses)-3].Applied {
+		t.Fatal("only the last 2 migrations are rolled back")
+	}
+
+	if _, err := migrator.Down(ctx, len(migrator.migrations)); err != nil {
+		t.Fatalf("every down migration must run: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("the schema must build again after a full rollback: %v", err)
+	}
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS sale_products;
+DROP TABLE IF EXISTS sales;
+DROP TABLE IF EXISTS products;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS users;
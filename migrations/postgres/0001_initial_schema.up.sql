@@ -0,0 +1,59 @@
+-- Baseline matching the schema GORM's AutoMigrate used to create. IF NOT
+-- EXISTS lets databases created before migrations existed adopt it as-is.
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    username text,
+    password text,
+    email text,
+    role text
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS categories (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    name text,
+    description text
+);
+CREATE INDEX IF NOT EXISTS idx_categories_deleted_at ON categories (deleted_at);
+
+CREATE TABLE IF NOT EXISTS products (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    name text,
+    description text,
+    price decimal,
+    stock bigint,
+    category_id bigint,
+    CONSTRAINT fk_products_category FOREIGN KEY (category_id) REFERENCES categories (id)
+);
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at ON products (deleted_at);
+
+CREATE TABLE IF NOT EXISTS sales (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    total decimal
+);
+CREATE INDEX IF NOT EXISTS idx_sales_deleted_at ON sales (deleted_at);
+
+CREATE TABLE IF NOT EXISTS sale_products (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    sale_id bigint,
+    product_id bigint,
+    quantity bigint,
+    total decimal,
+    CONSTRAINT fk_sales_products FOREIGN KEY (sale_id) REFERENCES sales (id)
+);
+CREATE INDEX IF NOT EXISTS idx_sale_products_deleted_at ON sale_products (deleted_at);
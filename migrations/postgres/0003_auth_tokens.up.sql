@@ -0,0 +1,31 @@
+CREATE TABLE refresh_tokens (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    token_hash text NOT NULL,
+    family_id text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz,
+    revoked_at timestamptz,
+    access_token_id text,
+    access_token_expires_at timestamptz,
+    CONSTRAINT fk_refresh_tokens_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX idx_refresh_tokens_deleted_at ON refresh_tokens (deleted_at);
+CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+CREATE TABLE revoked_tokens (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    token_id text NOT NULL,
+    user_id bigint,
+    expires_at timestamptz NOT NULL
+);
+CREATE INDEX idx_revoked_tokens_deleted_at ON revoked_tokens (deleted_at);
+CREATE UNIQUE INDEX idx_revoked_tokens_token_id ON revoked_tokens (token_id);
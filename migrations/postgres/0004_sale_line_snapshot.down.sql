@@ -0,0 +1,7 @@
+ALTER TABLE sale_products DROP COLUMN override_reason;
+ALTER TABLE sale_products DROP COLUMN overridden_by_id;
+ALTER TABLE sale_products DROP COLUMN unit_price;
+ALTER TABLE sale_products DROP COLUMN list_price;
+ALTER TABLE sale_products DROP COLUMN category_name;
+ALTER TABLE sale_products DROP COLUMN category_id;
+ALTER TABLE sale_products DROP COLUMN product_name;
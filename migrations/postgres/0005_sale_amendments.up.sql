@@ -0,0 +1,19 @@
+CREATE TABLE sale_amendments (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    sale_id bigint NOT NULL,
+    sale_product_id bigint,
+    product_id bigint,
+    action text NOT NULL,
+    old_quantity bigint,
+    new_quantity bigint,
+    old_total decimal,
+    new_total decimal,
+    amended_by_id bigint,
+    reason text,
+    CONSTRAINT fk_sales_amendments FOREIGN KEY (sale_id) REFERENCES sales (id)
+);
+CREATE INDEX idx_sale_amendments_deleted_at ON sale_amendments (deleted_at);
+CREATE INDEX idx_sale_amendments_sale_id ON sale_amendments (sale_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS sale_return_lines;
+DROP TABLE IF EXISTS sale_returns;
+ALTER TABLE sales DROP COLUMN refunded_total;
@@ -0,0 +1,32 @@
+ALTER TABLE sales ADD COLUMN refunded_total decimal NOT NULL DEFAULT 0;
+
+CREATE TABLE sale_returns (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    sale_id bigint NOT NULL,
+    refund_total decimal,
+    reason text,
+    created_by_id bigint,
+    CONSTRAINT fk_sales_returns FOREIGN KEY (sale_id) REFERENCES sales (id)
+);
+CREATE INDEX idx_sale_returns_deleted_at ON sale_returns (deleted_at);
+CREATE INDEX idx_sale_returns_sale_id ON sale_returns (sale_id);
+
+CREATE TABLE sale_return_lines (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    return_id bigint NOT NULL,
+    sale_product_id bigint,
+    product_id bigint,
+    quantity bigint,
+    unit_price decimal,
+    refund decimal,
+    restocked boolean,
+    CONSTRAINT fk_sale_returns_lines FOREIGN KEY (return_id) REFERENCES sale_returns (id)
+);
+CREATE INDEX idx_sale_return_lines_deleted_at ON sale_return_lines (deleted_at);
+CREATE INDEX idx_sale_return_lines_return_id ON sale_return_lines (return_id);
@@ -0,0 +1,15 @@
+-- Sales go through draft, confirmed, paid and fulfilled, or are cancelled.
+-- Confirmed and paid sales reserve their items on the product.
+ALTER TABLE sales ADD COLUMN status text NOT NULL DEFAULT 'draft';
+ALTER TABLE sales ADD COLUMN confirmed_at timestamptz;
+ALTER TABLE sales ADD COLUMN paid_at timestamptz;
+ALTER TABLE sales ADD COLUMN fulfilled_at timestamptz;
+ALTER TABLE sales ADD COLUMN cancelled_at timestamptz;
+ALTER TABLE sales ADD COLUMN cancel_reason text;
+CREATE INDEX idx_sales_status ON sales (status);
+
+ALTER TABLE products ADD COLUMN reserved bigint NOT NULL DEFAULT 0;
+
+-- Existing sales took their items out of stock, and were paid, when they
+-- were created.
+UPDATE sales SET status = 'fulfilled', confirmed_at = created_at, paid_at = created_at, fulfilled_at = created_at;
@@ -0,0 +1,23 @@
+CREATE TABLE payments (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    sale_id bigint NOT NULL,
+    method text,
+    amount decimal,
+    tendered decimal,
+    change decimal,
+    provider text,
+    reference text,
+    received_by_id bigint,
+    CONSTRAINT fk_sales_payments FOREIGN KEY (sale_id) REFERENCES sales (id)
+);
+CREATE INDEX idx_payments_deleted_at ON payments (deleted_at);
+CREATE INDEX idx_payments_sale_id ON payments (sale_id);
+
+ALTER TABLE sales ADD COLUMN paid_total decimal NOT NULL DEFAULT 0;
+ALTER TABLE customers ADD COLUMN store_credit decimal NOT NULL DEFAULT 0;
+
+-- Sales paid before payments were recorded count as paid in full.
+UPDATE sales SET paid_total = total WHERE status IN ('paid', 'fulfilled');
@@ -0,0 +1,71 @@
+CREATE TABLE promotions (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    name text,
+    kind text,
+    value decimal,
+    buy_quantity bigint,
+    free_quantity bigint,
+    product_id bigint,
+    category_id bigint,
+    starts_at timestamptz,
+    ends_at timestamptz,
+    disabled boolean NOT NULL DEFAULT false,
+    created_by_id bigint,
+    updated_by_id bigint
+);
+CREATE INDEX idx_promotions_deleted_at ON promotions (deleted_at);
+CREATE INDEX idx_promotions_product_id ON promotions (product_id);
+CREATE INDEX idx_promotions_category_id ON promotions (category_id);
+
+CREATE TABLE coupons (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    code text,
+    description text,
+    kind text,
+    value decimal,
+    min_subtotal decimal,
+    max_uses bigint NOT NULL DEFAULT 0,
+    used_count bigint NOT NULL DEFAULT 0,
+    starts_at timestamptz,
+    ends_at timestamptz,
+    disabled boolean NOT NULL DEFAULT false,
+    created_by_id bigint,
+    updated_by_id bigint
+);
+CREATE INDEX idx_coupons_deleted_at ON coupons (deleted_at);
+CREATE INDEX idx_coupons_code ON coupons (code);
+
+CREATE TABLE sale_discounts (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    sale_id bigint NOT NULL,
+    sale_product_id bigint,
+    source text,
+    promotion_id bigint,
+    coupon_id bigint,
+    description text,
+    kind text,
+    value decimal,
+    buy_quantity bigint,
+    free_quantity bigint,
+    amount decimal,
+    applied_by_id bigint,
+    CONSTRAINT fk_sales_discounts FOREIGN KEY (sale_id) REFERENCES sales (id)
+);
+CREATE INDEX idx_sale_discounts_deleted_at ON sale_discounts (deleted_at);
+CREATE INDEX idx_sale_discounts_sale_id ON sale_discounts (sale_id);
+
+ALTER TABLE sales ADD COLUMN subtotal decimal NOT NULL DEFAULT 0;
+ALTER TABLE sales ADD COLUMN discount_total decimal NOT NULL DEFAULT 0;
+ALTER TABLE sale_products ADD COLUMN discount decimal NOT NULL DEFAULT 0;
+
+-- Sales made before discounts had none.
+UPDATE sales SET subtotal = total;
@@ -0,0 +1,62 @@
+CREATE TABLE tax_rates (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    name text,
+    rate decimal,
+    inclusive boolean NOT NULL DEFAULT false,
+    compound boolean NOT NULL DEFAULT false,
+    product_id bigint,
+    category_id bigint,
+    disabled boolean NOT NULL DEFAULT false,
+    created_by_id bigint,
+    updated_by_id bigint
+);
+CREATE INDEX idx_tax_rates_deleted_at ON tax_rates (deleted_at);
+CREATE INDEX idx_tax_rates_product_id ON tax_rates (product_id);
+CREATE INDEX idx_tax_rates_category_id ON tax_rates (category_id);
+
+CREATE TABLE sale_line_taxes (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    sale_product_id bigint NOT NULL,
+    tax_rate_id bigint,
+    name text,
+    rate decimal,
+    inclusive boolean,
+    compound boolean,
+    taxable decimal,
+    amount decimal,
+    CONSTRAINT fk_sale_products_taxes FOREIGN KEY (sale_product_id) REFERENCES sale_products (id)
+);
+CREATE INDEX idx_sale_line_taxes_deleted_at ON sale_line_taxes (deleted_at);
+CREATE INDEX idx_sale_line_taxes_sale_product_id ON sale_line_taxes (sale_product_id);
+
+CREATE TABLE sale_taxes (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    sale_id bigint NOT NULL,
+    tax_rate_id bigint,
+    name text,
+    rate decimal,
+    inclusive boolean,
+    taxable decimal,
+    amount decimal,
+    CONSTRAINT fk_sales_taxes FOREIGN KEY (sale_id) REFERENCES sales (id)
+);
+CREATE INDEX idx_sale_taxes_deleted_at ON sale_taxes (deleted_at);
+CREATE INDEX idx_sale_taxes_sale_id ON sale_taxes (sale_id);
+
+ALTER TABLE sales ADD COLUMN tax_total decimal NOT NULL DEFAULT 0;
+ALTER TABLE sale_products ADD COLUMN taxable_amount decimal NOT NULL DEFAULT 0;
+ALTER TABLE sale_products ADD COLUMN tax decimal NOT NULL DEFAULT 0;
+
+-- Sales made before taxes were untaxed: each line was taxable on its share
+-- of the sale total.
+UPDATE sale_products SET taxable_amount = COALESCE(ROUND(total * (SELECT s.total FROM sales s WHERE s.id = sale_products.sale_id)
+    / NULLIF((SELECT SUM(p.total) FROM sale_products p WHERE p.sale_id = sale_products.sale_id AND p.deleted_at IS NULL), 0), 2), 0);
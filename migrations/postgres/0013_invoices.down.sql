@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_sales_invoice_number;
+ALTER TABLE sales DROP COLUMN invoice_number;
+DROP TABLE IF EXISTS sequences;
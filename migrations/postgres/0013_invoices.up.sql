@@ -0,0 +1,16 @@
+CREATE TABLE sequences (
+    name text PRIMARY KEY,
+    value bigint NOT NULL DEFAULT 0
+);
+
+ALTER TABLE sales ADD COLUMN invoice_number bigint;
+CREATE UNIQUE INDEX idx_sales_invoice_number ON sales (invoice_number);
+
+-- Sales paid before invoices existed are numbered in the order they were
+-- paid, and the sequence carries on after them.
+UPDATE sales SET invoice_number = (
+    SELECT COUNT(*) FROM sales earlier
+    WHERE earlier.paid_at IS NOT NULL
+      AND (earlier.paid_at < sales.paid_at OR (earlier.paid_at = sales.paid_at AND earlier.id <= sales.id))
+) WHERE paid_at IS NOT NULL;
+INSERT INTO sequences (name, value) SELECT 'invoice', COUNT(*) FROM sales WHERE paid_at IS NOT NULL;
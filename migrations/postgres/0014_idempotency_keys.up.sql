@@ -0,0 +1,16 @@
+CREATE TABLE idempotency_keys (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    key text NOT NULL,
+    fingerprint text NOT NULL,
+    status bigint NOT NULL DEFAULT 0,
+    content_type text,
+    body bytea,
+    expires_at timestamptz NOT NULL
+);
+CREATE INDEX idx_idempotency_keys_deleted_at ON idempotency_keys (deleted_at);
+CREATE UNIQUE INDEX idx_idempotency_keys_user_key ON idempotency_keys (user_id, key);
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
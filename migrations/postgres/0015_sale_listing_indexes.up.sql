@@ -0,0 +1,7 @@
+-- Listing sales sorts on these columns, with the ID breaking ties, and
+-- filters on the products of their lines.
+CREATE INDEX idx_sales_created_at ON sales (created_at, id);
+CREATE INDEX idx_sales_total ON sales (total, id);
+CREATE INDEX idx_sales_paid_at ON sales (paid_at);
+CREATE INDEX idx_sale_products_sale_id ON sale_products (sale_id);
+CREATE INDEX idx_sale_products_product_id ON sale_products (product_id);
@@ -0,0 +1,3 @@
+-- Stored responses replay their Location and Link headers too.
+ALTER TABLE idempotency_keys ADD COLUMN location text;
+ALTER TABLE idempotency_keys ADD COLUMN link text;
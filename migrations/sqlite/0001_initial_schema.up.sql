@@ -0,0 +1,59 @@
+-- Baseline matching the schema GORM's AutoMigrate used to create. IF NOT
+-- EXISTS lets databases created before migrations existed adopt it as-is.
+CREATE TABLE IF NOT EXISTS users (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    username text,
+    password text,
+    email text,
+    role text
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS categories (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    name text,
+    description text
+);
+CREATE INDEX IF NOT EXISTS idx_categories_deleted_at ON categories (deleted_at);
+
+CREATE TABLE IF NOT EXISTS products (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    name text,
+    description text,
+    price real,
+    stock integer,
+    category_id integer,
+    CONSTRAINT fk_products_category FOREIGN KEY (category_id) REFERENCES categories (id)
+);
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at ON products (deleted_at);
+
+CREATE TABLE IF NOT EXISTS sales (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    total real
+);
+CREATE INDEX IF NOT EXISTS idx_sales_deleted_at ON sales (deleted_at);
+
+CREATE TABLE IF NOT EXISTS sale_products (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    sale_id integer,
+    product_id integer,
+    quantity integer,
+    total real,
+    CONSTRAINT fk_sales_products FOREIGN KEY (sale_id) REFERENCES sales (id)
+);
+CREATE INDEX IF NOT EXISTS idx_sale_products_deleted_at ON sale_products (deleted_at);
@@ -0,0 +1,2 @@
+-- The previous role values are not recorded, so there is nothing to restore.
+SELECT 1;
@@ -0,0 +1,4 @@
+-- Roles are now enforced. Accounts without a known role fall back to the
+-- least privileged one; admins must be promoted explicitly.
+UPDATE users SET role = 'viewer'
+WHERE role IS NULL OR role NOT IN ('admin', 'manager', 'cashier', 'viewer');
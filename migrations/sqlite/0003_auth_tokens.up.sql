@@ -0,0 +1,31 @@
+CREATE TABLE refresh_tokens (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    user_id integer NOT NULL,
+    token_hash text NOT NULL,
+    family_id text NOT NULL,
+    expires_at datetime NOT NULL,
+    used_at datetime,
+    revoked_at datetime,
+    access_token_id text,
+    access_token_expires_at datetime,
+    CONSTRAINT fk_refresh_tokens_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX idx_refresh_tokens_deleted_at ON refresh_tokens (deleted_at);
+CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+CREATE TABLE revoked_tokens (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    token_id text NOT NULL,
+    user_id integer,
+    expires_at datetime NOT NULL
+);
+CREATE INDEX idx_revoked_tokens_deleted_at ON revoked_tokens (deleted_at);
+CREATE UNIQUE INDEX idx_revoked_tokens_token_id ON revoked_tokens (token_id);
@@ -0,0 +1,19 @@
+-- Sale lines keep the product name, category and prices they were sold
+-- with, and who overrode the catalog price.
+ALTER TABLE sale_products ADD COLUMN product_name text;
+ALTER TABLE sale_products ADD COLUMN category_id integer;
+ALTER TABLE sale_products ADD COLUMN category_name text;
+ALTER TABLE sale_products ADD COLUMN list_price real;
+ALTER TABLE sale_products ADD COLUMN unit_price real;
+ALTER TABLE sale_products ADD COLUMN overridden_by_id integer REFERENCES users (id);
+ALTER TABLE sale_products ADD COLUMN override_reason text;
+
+-- Existing lines only stored their total: derive the unit price from it and
+-- take the rest from the current catalog, the best record left.
+UPDATE sale_products SET unit_price = total / quantity WHERE quantity > 0;
+UPDATE sale_products SET
+    product_name = (SELECT p.name FROM products p WHERE p.id = sale_products.product_id),
+    category_id = (SELECT p.category_id FROM products p WHERE p.id = sale_products.product_id),
+    list_price = (SELECT p.price FROM products p WHERE p.id = sale_products.product_id);
+UPDATE sale_products SET
+    category_name = (SELECT c.name FROM categories c WHERE c.id = sale_products.category_id);
@@ -0,0 +1,19 @@
+CREATE TABLE sale_amendments (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    sale_id integer NOT NULL,
+    sale_product_id integer,
+    product_id integer,
+    action text NOT NULL,
+    old_quantity integer,
+    new_quantity integer,
+    old_total real,
+    new_total real,
+    amended_by_id integer,
+    reason text,
+    CONSTRAINT fk_sales_amendments FOREIGN KEY (sale_id) REFERENCES sales (id)
+);
+CREATE INDEX idx_sale_amendments_deleted_at ON sale_amendments (deleted_at);
+CREATE INDEX idx_sale_amendments_sale_id ON sale_amendments (sale_id);
@@ -0,0 +1,32 @@
+ALTER TABLE sales ADD COLUMN refunded_total real NOT NULL DEFAULT 0;
+
+CREATE TABLE sale_returns (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    sale_id integer NOT NULL,
+    refund_total real,
+    reason text,
+    created_by_id integer,
+    CONSTRAINT fk_sales_returns FOREIGN KEY (sale_id) REFERENCES sales (id)
+);
+CREATE INDEX idx_sale_returns_deleted_at ON sale_returns (deleted_at);
+CREATE INDEX idx_sale_returns_sale_id ON sale_returns (sale_id);
+
+CREATE TABLE sale_return_lines (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    return_id integer NOT NULL,
+    sale_product_id integer,
+    product_id integer,
+    quantity integer,
+    unit_price real,
+    refund real,
+    restocked numeric,
+    CONSTRAINT fk_sale_returns_lines FOREIGN KEY (return_id) REFERENCES sale_returns (id)
+);
+CREATE INDEX idx_sale_return_lines_deleted_at ON sale_return_lines (deleted_at);
+CREATE INDEX idx_sale_return_lines_return_id ON sale_return_lines (return_id);
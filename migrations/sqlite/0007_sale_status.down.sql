@@ -0,0 +1,9 @@
+ALTER TABLE products DROP COLUMN reserved;
+
+DROP INDEX IF EXISTS idx_sales_status;
+ALTER TABLE sales DROP COLUMN cancel_reason;
+ALTER TABLE sales DROP COLUMN cancelled_at;
+ALTER TABLE sales DROP COLUMN fulfilled_at;
+ALTER TABLE sales DROP COLUMN paid_at;
+ALTER TABLE sales DROP COLUMN confirmed_at;
+ALTER TABLE sales DROP COLUMN status;
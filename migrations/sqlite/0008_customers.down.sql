@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_sales_customer_id;
+ALTER TABLE sales DROP COLUMN customer_id;
+DROP TABLE IF EXISTS customer_addresses;
+DROP TABLE IF EXISTS customers;
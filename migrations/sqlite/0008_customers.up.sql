@@ -0,0 +1,33 @@
+CREATE TABLE customers (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    name text,
+    email text,
+    phone text,
+    notes text
+);
+CREATE INDEX idx_customers_deleted_at ON customers (deleted_at);
+CREATE INDEX idx_customers_email ON customers (email);
+
+CREATE TABLE customer_addresses (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    customer_id integer NOT NULL,
+    label text,
+    line1 text,
+    line2 text,
+    city text,
+    region text,
+    postal_code text,
+    country text,
+    CONSTRAINT fk_customers_addresses FOREIGN KEY (customer_id) REFERENCES customers (id)
+);
+CREATE INDEX idx_customer_addresses_deleted_at ON customer_addresses (deleted_at);
+CREATE INDEX idx_customer_addresses_customer_id ON customer_addresses (customer_id);
+
+ALTER TABLE sales ADD COLUMN customer_id integer REFERENCES customers (id);
+CREATE INDEX idx_sales_customer_id ON sales (customer_id);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_sales_created_by_id;
+ALTER TABLE sales DROP COLUMN updated_by_id;
+ALTER TABLE sales DROP COLUMN created_by_id;
+
+DROP INDEX IF EXISTS idx_products_created_by_id;
+ALTER TABLE products DROP COLUMN updated_by_id;
+ALTER TABLE products DROP COLUMN created_by_id;
+
+DROP INDEX IF EXISTS idx_categories_created_by_id;
+ALTER TABLE categories DROP COLUMN updated_by_id;
+ALTER TABLE categories DROP COLUMN created_by_id;
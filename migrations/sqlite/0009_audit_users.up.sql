@@ -0,0 +1,13 @@
+-- Who created and last changed categories, products and sales. Rows from
+-- before this migration keep 0, as do records made outside a request.
+ALTER TABLE categories ADD COLUMN created_by_id integer NOT NULL DEFAULT 0;
+ALTER TABLE categories ADD COLUMN updated_by_id integer NOT NULL DEFAULT 0;
+CREATE INDEX idx_categories_created_by_id ON categories (created_by_id);
+
+ALTER TABLE products ADD COLUMN created_by_id integer NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN updated_by_id integer NOT NULL DEFAULT 0;
+CREATE INDEX idx_products_created_by_id ON products (created_by_id);
+
+ALTER TABLE sales ADD COLUMN created_by_id integer NOT NULL DEFAULT 0;
+ALTER TABLE sales ADD COLUMN updated_by_id integer NOT NULL DEFAULT 0;
+CREATE INDEX idx_sales_created_by_id ON sales (created_by_id);
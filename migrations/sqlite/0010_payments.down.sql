@@ -0,0 +1,3 @@
+ALTER TABLE customers DROP COLUMN store_credit;
+ALTER TABLE sales DROP COLUMN paid_total;
+DROP TABLE IF EXISTS payments;
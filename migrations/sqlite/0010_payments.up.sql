@@ -0,0 +1,23 @@
+CREATE TABLE payments (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    sale_id integer NOT NULL,
+    method text,
+    amount real,
+    tendered real,
+    change real,
+    provider text,
+    reference text,
+    received_by_id integer,
+    CONSTRAINT fk_sales_payments FOREIGN KEY (sale_id) REFERENCES sales (id)
+);
+CREATE INDEX idx_payments_deleted_at ON payments (deleted_at);
+CREATE INDEX idx_payments_sale_id ON payments (sale_id);
+
+ALTER TABLE sales ADD COLUMN paid_total real NOT NULL DEFAULT 0;
+ALTER TABLE customers ADD COLUMN store_credit real NOT NULL DEFAULT 0;
+
+-- Sales paid before payments were recorded count as paid in full.
+UPDATE sales SET paid_total = total WHERE status IN ('paid', 'fulfilled');
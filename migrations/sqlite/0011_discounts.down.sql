@@ -0,0 +1,6 @@
+ALTER TABLE sale_products DROP COLUMN discount;
+ALTER TABLE sales DROP COLUMN discount_total;
+ALTER TABLE sales DROP COLUMN subtotal;
+DROP TABLE IF EXISTS sale_discounts;
+DROP TABLE IF EXISTS coupons;
+DROP TABLE IF EXISTS promotions;
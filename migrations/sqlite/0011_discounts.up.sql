@@ -0,0 +1,71 @@
+CREATE TABLE promotions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    name text,
+    kind text,
+    value real,
+    buy_quantity integer,
+    free_quantity integer,
+    product_id integer,
+    category_id integer,
+    starts_at datetime,
+    ends_at datetime,
+    disabled numeric NOT NULL DEFAULT 0,
+    created_by_id integer,
+    updated_by_id integer
+);
+CREATE INDEX idx_promotions_deleted_at ON promotions (deleted_at);
+CREATE INDEX idx_promotions_product_id ON promotions (product_id);
+CREATE INDEX idx_promotions_category_id ON promotions (category_id);
+
+CREATE TABLE coupons (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    code text,
+    description text,
+    kind text,
+    value real,
+    min_subtotal real,
+    max_uses integer NOT NULL DEFAULT 0,
+    used_count integer NOT NULL DEFAULT 0,
+    starts_at datetime,
+    ends_at datetime,
+    disabled numeric NOT NULL DEFAULT 0,
+    created_by_id integer,
+    updated_by_id integer
+);
+CREATE INDEX idx_coupons_deleted_at ON coupons (deleted_at);
+CREATE INDEX idx_coupons_code ON coupons (code);
+
+CREATE TABLE sale_discounts (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    sale_id integer NOT NULL,
+    sale_product_id integer,
+    source text,
+    promotion_id integer,
+    coupon_id integer,
+    description text,
+    kind text,
+    value real,
+    buy_quantity integer,
+    free_quantity integer,
+    amount real,
+    applied_by_id integer,
+    CONSTRAINT fk_sales_discounts FOREIGN KEY (sale_id) REFERENCES sales (id)
+);
+CREATE INDEX idx_sale_discounts_deleted_at ON sale_discounts (deleted_at);
+CREATE INDEX idx_sale_discounts_sale_id ON sale_discounts (sale_id);
+
+ALTER TABLE sales ADD COLUMN subtotal real NOT NULL DEFAULT 0;
+ALTER TABLE sales ADD COLUMN discount_total real NOT NULL DEFAULT 0;
+ALTER TABLE sale_products ADD COLUMN discount real NOT NULL DEFAULT 0;
+
+-- Sales made before discounts had none.
+UPDATE sales SET subtotal = total;
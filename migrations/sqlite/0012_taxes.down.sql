@@ -0,0 +1,6 @@
+ALTER TABLE sale_products DROP COLUMN tax;
+ALTER TABLE sale_products DROP COLUMN taxable_amount;
+ALTER TABLE sales DROP COLUMN tax_total;
+DROP TABLE IF EXISTS sale_taxes;
+DROP TABLE IF EXISTS sale_line_taxes;
+DROP TABLE IF EXISTS tax_rates;
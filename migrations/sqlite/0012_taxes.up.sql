@@ -0,0 +1,62 @@
+CREATE TABLE tax_rates (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    name text,
+    rate real,
+    inclusive numeric NOT NULL DEFAULT 0,
+    compound numeric NOT NULL DEFAULT 0,
+    product_id integer,
+    category_id integer,
+    disabled numeric NOT NULL DEFAULT 0,
+    created_by_id integer,
+    updated_by_id integer
+);
+CREATE INDEX idx_tax_rates_deleted_at ON tax_rates (deleted_at);
+CREATE INDEX idx_tax_rates_product_id ON tax_rates (product_id);
+CREATE INDEX idx_tax_rates_category_id ON tax_rates (category_id);
+
+CREATE TABLE sale_line_taxes (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    sale_product_id integer NOT NULL,
+    tax_rate_id integer,
+    name text,
+    rate real,
+    inclusive numeric,
+    compound numeric,
+    taxable real,
+    amount real,
+    CONSTRAINT fk_sale_products_taxes FOREIGN KEY (sale_product_id) REFERENCES sale_products (id)
+);
+CREATE INDEX idx_sale_line_taxes_deleted_at ON sale_line_taxes (deleted_at);
+CREATE INDEX idx_sale_line_taxes_sale_product_id ON sale_line_taxes (sale_product_id);
+
+CREATE TABLE sale_taxes (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    sale_id integer NOT NULL,
+    tax_rate_id integer,
+    name text,
+    rate real,
+    inclusive numeric,
+    taxable real,
+    amount real,
+    CONSTRAINT fk_sales_taxes FOREIGN KEY (sale_id) REFERENCES sales (id)
+);
+CREATE INDEX idx_sale_taxes_deleted_at ON sale_taxes (deleted_at);
+CREATE INDEX idx_sale_taxes_sale_id ON sale_taxes (sale_id);
+
+ALTER TABLE sales ADD COLUMN tax_total real NOT NULL DEFAULT 0;
+ALTER TABLE sale_products ADD COLUMN taxable_amount real NOT NULL DEFAULT 0;
+ALTER TABLE sale_products ADD COLUMN tax real NOT NULL DEFAULT 0;
+
+-- Sales made before taxes were untaxed: each line was taxable on its share
+-- of the sale total.
+UPDATE sale_products SET taxable_amount = COALESCE(ROUND(total * (SELECT s.total FROM sales s WHERE s.id = sale_products.sale_id)
+    / NULLIF((SELECT SUM(p.total) FROM sale_products p WHERE p.sale_id = sale_products.sale_id AND p.deleted_at IS NULL), 0), 2), 0);
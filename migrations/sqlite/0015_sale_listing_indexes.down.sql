@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_sale_products_product_id;
+DROP INDEX IF EXISTS idx_sale_products_sale_id;
+DROP INDEX IF EXISTS idx_sales_paid_at;
+DROP INDEX IF EXISTS idx_sales_total;
+DROP INDEX IF EXISTS idx_sales_created_at;
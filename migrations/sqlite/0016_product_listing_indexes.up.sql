@@ -0,0 +1,6 @@
+-- Listing products filters on their category and sorts on these columns,
+-- with the ID breaking ties.
+CREATE INDEX idx_products_category_id ON products (category_id);
+CREATE INDEX idx_products_price ON products (price, id);
+CREATE INDEX idx_products_name ON products (name, id);
+CREATE INDEX idx_products_created_at ON products (created_at, id);
@@ -0,0 +1,2 @@
+-- Payments given back when their sale is cancelled are marked refunded.
+ALTER TABLE payments ADD COLUMN refunded_at datetime;
@@ -1,19 +1,69 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
 	gorm.Model
 	Username string
-	Password string
+	Password string `json:"-"`
 	Email    string
 	Role     string
 }
 
+// RefreshToken is an opaque, single-use token that renews an access token.
+// Only its SHA-256 hash is stored. Tokens rotated from the same login share a
+// FamilyID, so presenting an already used token revokes the whole family.
+type RefreshToken struct {
+	gorm.Model
+	UserID    uint
+	TokenHash string `gorm:"uniqueIndex"`
+	FamilyID  string `gorm:"index"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+
+	// AccessTokenID is the jti of the access token issued alongside, so it
+	// can be revoked together with the family.
+	AccessTokenID        string
+	AccessTokenExpiresAt time.Time
+}
+
+// RevokedToken is an entry of the access token revocation list. It can be
+// dropped once ExpiresAt has passed since the token is rejected anyway.
+type RevokedToken struct {
+	gorm.Model
+	TokenID   string `gorm:"uniqueIndex"`
+	UserID    uint
+	ExpiresAt time.Time
+}
+
+// IdempotencyKey holds the response to a request sent with an
+// Idempotency-Key header, so a retry of the request gets it back instead of
+// running again. Fingerprint is a hash of the request, which a retry must
+// match. Status is 0 while the first request is still running.
+type IdempotencyKey struct {
+	gorm.Model
+	UserID      uint   `gorm:"uniqueIndex:idx_idempotency_keys_user_key"`
+	Key         string `gorm:"uniqueIndex:idx_idempotency_keys_user_key"`
+	Fingerprint string
+	Status      int
+	ContentType string
+	Location    string
+	Link        string
+	Body        []byte
+	ExpiresAt   time.Time `gorm:"index"`
+}
+
 type Category struct {
 	gorm.Model
 	Name        string
 	Description string
+	CreatedByID uint `gorm:"index"`
+	UpdatedByID uint
 }
 
 type Product struct {
@@ -22,20 +72,327 @@ type Product struct {
 	Description string
 	Price       float64
 	Stock       int
+	// Reserved is the part of Stock held by confirmed sales that are not
+	// fulfilled yet. Only sales change it.
+	Reserved    int
 	Category    Category
-	CategoryID  uint
+	CategoryID  uint `gorm:"index"`
+	CreatedByID uint `gorm:"index"`
+	UpdatedByID uint
+}
+
+// Customer is a buyer, as opposed to the staff Users who log in.
+type Customer struct {
+	gorm.Model
+	Name      string
+	Email     string `gorm:"index"`
+	Phone     string
+	Addresses []CustomerAddress `gorm:"foreignKey:CustomerID"`
+	Notes     string
+	// StoreCredit is what the customer can spend with the store_credit
+	// tender.
+	StoreCredit float64
 }
 
+// CustomerAddress is one of a customer's addresses; Label tells them apart,
+// such as "billing" or "shipping".
+type CustomerAddress struct {
+	gorm.Model
+	CustomerID uint `gorm:"index"`
+	Label      string
+	Line1      string
+	Line2      string
+	City       string
+	Region     string
+	PostalCode string
+	Country    string
+}
+
+// Sale statuses. A sale moves forward from draft to fulfilled and can be
+// cancelled until it is fulfilled.
+const (
+	SaleDraft     = "draft"
+	SaleConfirmed = "confirmed"
+	SalePaid      = "paid"
+	SaleFulfilled = "fulfilled"
+	SaleCancelled = "cancelled"
+)
+
 type Sale struct {
 	gorm.Model
-	Products []SaleProduct `gorm:"foreignKey:SaleID"`
-	Total    float64
+	Status     string        `gorm:"index"`
+	CustomerID *uint         `gorm:"index"`
+	Products   []SaleProduct `gorm:"foreignKey:SaleID"`
+
+	// Subtotal is what the lines come to before any discount, and
+	// DiscountTotal the sum of Discounts. TaxTotal is the sum of Taxes,
+	// inclusive and exclusive. Total is what is left to pay: Subtotal minus
+	// DiscountTotal plus the exclusive taxes.
+	Subtotal      float64
+	DiscountTotal float64
+	Discounts     []SaleDiscount `gorm:"foreignKey:SaleID"`
+	TaxTotal      float64
+	Taxes         []SaleTax `gorm:"foreignKey:SaleID"`
+	Total         float64
+
+	Amendments []SaleAmendment `gorm:"foreignKey:SaleID"`
+
+	// RefundedTotal is the sum refunded by Returns, or by cancelling the
+	// sale once paid; Total minus RefundedTotal is what a sale that was not
+	// cancelled finally brought in.
+	RefundedTotal float64
+	Returns       []SaleReturn `gorm:"foreignKey:SaleID"`
+
+	// PaidTotal is the sum of Payments; Total minus PaidTotal is the
+	// balance still owed.
+	PaidTotal float64
+	Payments  []Payment `gorm:"foreignKey:SaleID"`
+
+	// InvoiceNumber is given when the sale is paid, from the invoice
+	// sequence, so paid sales are numbered in the order they were paid
+	// without gaps. Sales never paid have none.
+	InvoiceNumber *uint `gorm:"uniqueIndex"`
+
+	// When the sale entered each status.
+	ConfirmedAt  *time.Time
+	PaidAt       *time.Time `gorm:"index"`
+	FulfilledAt  *time.Time
+	CancelledAt  *time.Time
+	CancelReason string
+
+	// CreatedByID is the cashier who rang the sale up; UpdatedByID the last
+	// user who amended it or moved it to another status.
+	CreatedByID uint `gorm:"index"`
+	UpdatedByID uint
+}
+
+// Sequences.
+const SequenceInvoice = "invoice"
+
+// Sequence is a named counter. Value is the last number handed out.
+type Sequence struct {
+	Name  string `gorm:"primaryKey"`
+	Value uint
+}
+
+// Payment tenders.
+const (
+	TenderCash        = "cash"
+	TenderCard        = "card"
+	TenderMobileMoney = "mobile_money"
+	TenderStoreCredit = "store_credit"
+)
+
+// Payment is one tender of a sale. Amount is what it paid off the sale; for
+// cash, Tendered is what the customer handed over and Change what they got
+// back. Card and mobile money payments keep the provider's reference.
+type Payment struct {
+	gorm.Model
+	SaleID       uint `gorm:"index"`
+	Method       string
+	Amount       float64
+	Tendered     float64
+	Change       float64
+	Provider     string
+	Reference    string
+	ReceivedByID uint
+
+	// RefundedAt is when the payment was given back, by cancelling its sale.
+	RefundedAt *time.Time
 }
 
 type SaleProduct struct {
 	gorm.Model
-	SaleID    uint
-	ProductID uint
-	Quantity  int
-	Total     float64
+	SaleID    uint `gorm:"index"`
+	ProductID uint `gorm:"index"`
+
+	// Snapshot of the product when it was sold, so catalog changes do not
+	// rewrite past sales.
+	ProductName  string
+	CategoryID   uint
+	CategoryName string
+	ListPrice    float64
+
+	// UnitPrice is the price charged. It differs from ListPrice only when
+	// the price was overridden, by OverriddenByID for OverrideReason.
+	UnitPrice      float64
+	Quantity       int
+	OverriddenByID *uint
+	OverrideReason string
+
+	// Discount is what the line's discounts take off; Total is Quantity
+	// times UnitPrice minus Discount.
+	Discount float64
+	Total    float64
+
+	// TaxableAmount is what the line is taxed on: its Total less its share
+	// of the sale-wide discounts and less its inclusive taxes. Tax is the
+	// sum of Taxes.
+	TaxableAmount float64
+	Tax           float64
+	Taxes         []SaleLineTax `gorm:"foreignKey:SaleProductID"`
+}
+
+// TaxRate is a tax charged on a single product when ProductID is set, on a
+// category when CategoryID is set, and on every product otherwise. A product
+// pays its own rates if it has any, else those of its category, else the
+// store-wide ones. Inclusive rates are already part of the prices. Several
+// rates stack: a Compound rate is charged on the price plus the taxes of the
+// rates before it, the others on the price alone. Rates apply in ID order.
+type TaxRate struct {
+	gorm.Model
+	Name        string
+	Rate        float64
+	Inclusive   bool
+	Compound    bool
+	ProductID   *uint `gorm:"index"`
+	CategoryID  *uint `gorm:"index"`
+	Disabled    bool
+	CreatedByID uint
+	UpdatedByID uint
+}
+
+// SaleLineTax is a tax charged on a sale line. The rate is copied from the
+// TaxRate, so amending the sale later keeps taxing the line as it was sold.
+// Taxable is the amount the rate applied to.
+type SaleLineTax struct {
+	gorm.Model
+	SaleProductID uint `gorm:"index"`
+	TaxRateID     uint
+	Name          string
+	Rate          float64
+	Inclusive     bool
+	Compound      bool
+	Taxable       float64
+	Amount        float64
+}
+
+// SaleTax totals one rate over the lines of a sale.
+type SaleTax struct {
+	gorm.Model
+	SaleID    uint `gorm:"index"`
+	TaxRateID uint
+	Name      string
+	Rate      float64
+	Inclusive bool
+	Taxable   float64
+	Amount    float64
+}
+
+// Discount kinds. Fixed takes an amount off a line or a whole sale once;
+// FixedPerUnit, used by promotions, takes it off every unit of a line.
+const (
+	DiscountPercentage   = "percentage"
+	DiscountFixed        = "fixed"
+	DiscountFixedPerUnit = "fixed_per_unit"
+	DiscountBuyXGetY     = "buy_x_get_y"
+)
+
+// Where a sale discount comes from.
+const (
+	DiscountSourceManual    = "manual"
+	DiscountSourcePromotion = "promotion"
+	DiscountSourceCoupon    = "coupon"
+)
+
+// Promotion is an automatic discount on a product or a whole category,
+// valid between StartsAt and EndsAt when they are set. BuyQuantity and
+// FreeQuantity describe buy_x_get_y deals such as buy 2 get 1 free.
+type Promotion struct {
+	gorm.Model
+	Name         string
+	Kind         string
+	Value        float64
+	BuyQuantity  int
+	FreeQuantity int
+	ProductID    *uint `gorm:"index"`
+	CategoryID   *uint `gorm:"index"`
+	StartsAt     *time.Time
+	EndsAt       *time.Time
+	Disabled     bool
+	CreatedByID  uint
+	UpdatedByID  uint
+}
+
+// Coupon is a code that takes a percentage or a fixed amount off a sale. It
+// can be redeemed MaxUses times, or without limit when MaxUses is 0.
+type Coupon struct {
+	gorm.Model
+	Code        string `gorm:"index"`
+	Description string
+	Kind        string
+	Value       float64
+	MinSubtotal float64
+	MaxUses     int
+	UsedCount   int
+	StartsAt    *time.Time
+	EndsAt      *time.Time
+	Disabled    bool
+	CreatedByID uint
+	UpdatedByID uint
+}
+
+// SaleDiscount records a discount applied to a sale, on one line when
+// SaleProductID is set and on the whole sale otherwise. Kind and Value are
+// kept so the Amount can be worked out again when the sale is amended.
+type SaleDiscount struct {
+	gorm.Model
+	SaleID        uint `gorm:"index"`
+	SaleProductID *uint
+	Source        string
+	PromotionID   *uint
+	CouponID      *uint
+	Description   string
+	Kind          string
+	Value         float64
+	BuyQuantity   int
+	FreeQuantity  int
+	Amount        float64
+	AppliedByID   uint
+}
+
+// Amendment actions.
+const (
+	AmendmentAdded   = "added"
+	AmendmentChanged = "changed"
+	AmendmentRemoved = "removed"
+)
+
+// SaleAmendment records one change made to a line of a sale after it was
+// created. Amendments are never edited or deleted.
+type SaleAmendment struct {
+	gorm.Model
+	SaleID        uint
+	SaleProductID uint
+	ProductID     uint
+	Action        string
+	OldQuantity   int
+	NewQuantity   int
+	OldTotal      float64
+	NewTotal      float64
+	AmendedByID   uint
+	Reason        string
+}
+
+// SaleReturn gives back some or all of the items of a sale and refunds them
+// at the price they were sold for.
+type SaleReturn struct {
+	gorm.Model
+	SaleID      uint
+	Lines       []SaleReturnLine `gorm:"foreignKey:ReturnID"`
+	RefundTotal float64
+	Reason      string
+	CreatedByID uint
+}
+
+type SaleReturnLine struct {
+	gorm.Model
+	ReturnID      uint
+	SaleProductID uint
+	ProductID     uint
+	Quantity      int
+	UnitPrice     float64
+	Refund        float64
+	// Restocked items went back to inventory; the others were damaged.
+	Restocked bool
 }
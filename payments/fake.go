@@ -0,0 +1,52 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const FakeProviderName = "fake"
+
+// FakeProvider approves every charge without moving money, for local
+// development and demos. Tokens starting with "decline" are declined, so
+// clients can exercise their error handling.
+type FakeProvider struct {
+	mu      sync.Mutex
+	next    int
+	charges map[string]Charge
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{charges: map[string]Charge{}}
+}
+
+func (p *FakeProvider) Name() string {
+	return FakeProviderName
+}
+
+func (p *FakeProvider) Charge(ctx context.Context, charge Charge) (string, error) {
+	if strings.HasPrefix(charge.Token, "decline") {
+		return "", fmt.Errorf("%w: the fake provider declines token %q", ErrDeclined, charge.Token)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.next++
+	reference := fmt.Sprintf("fake_%06d", p.next)
+	p.charges[reference] = charge
+	return reference, nil
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, reference string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.charges[reference]; !ok {
+		return fmt.Errorf("no charge %s to refund", reference)
+	}
+	delete(p.charges, reference)
+	return nil
+}
@@ -0,0 +1,33 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const NoneProviderName = "none"
+
+// ErrUnavailable is wrapped by providers that do not take a payment method
+// at all, as opposed to declining one charge.
+var ErrUnavailable = errors.New("payment method unavailable")
+
+// NoneProvider is used when no processor is configured: it refuses every
+// card and mobile money charge, so only cash and store credit are taken.
+type NoneProvider struct{}
+
+func NewNoneProvider() *NoneProvider {
+	return &NoneProvider{}
+}
+
+func (p *NoneProvider) Name() string {
+	return NoneProviderName
+}
+
+func (p *NoneProvider) Charge(ctx context.Context, charge Charge) (string, error) {
+	return "", fmt.Errorf("%w: no payment provider is configured, so %s tenders are refused; take cash or store credit", ErrUnavailable, charge.Method)
+}
+
+func (p *NoneProvider) Refund(ctx context.Context, reference string) error {
+	return fmt.Errorf("no charge %s to refund: no payment provider is configured", reference)
+}
@@ -0,0 +1,44 @@
+// Package payments talks to the processors that settle card and mobile
+// money tenders. Cash and store credit are settled in-house and never reach
+// a provider.
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrDeclined is wrapped by providers when the processor refuses a charge.
+var ErrDeclined = errors.New("payment declined")
+
+// Charge asks a provider to take Amount from the card or mobile money
+// account identified by Token, as handed out by the processor's terminal or
+// client SDK.
+type Charge struct {
+	SaleID uint
+	Method string
+	Amount float64
+	Token  string
+}
+
+// Provider is a payment processor.
+type Provider interface {
+	Name() string
+	// Charge takes the money and returns the processor's reference for it.
+	Charge(ctx context.Context, charge Charge) (string, error)
+	// Refund gives back a charge made earlier, such as the first tender of
+	// a split payment whose second tender was declined.
+	Refund(ctx context.Context, reference string) error
+}
+
+// New returns the provider registered under name.
+func New(name string) (Provider, error) {
+	switch name {
+	case NoneProviderName:
+		return NewNoneProvider(), nil
+	case FakeProviderName:
+		return NewFakeProvider(), nil
+	}
+	return nil, fmt.Errorf("unknown payment provider %q (expected %s or %s)", name, NoneProviderName, FakeProviderName)
+}
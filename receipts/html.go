@@ -0,0 +1,23 @@
+package receipts
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+)
+
+//go:embed templates/receipt.html
+var templates embed.FS
+
+var htmlTemplate = template.Must(template.New("receipt.html").Funcs(template.FuncMap{
+	"money": formatMoney,
+}).ParseFS(templates, "templates/receipt.html"))
+
+// renderHTML renders the receipt as a page sized for A4 or letter paper.
+func renderHTML(r Receipt) ([]byte, error) {
+	var out bytes.Buffer
+	if err := htmlTemplate.Execute(&out, r); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
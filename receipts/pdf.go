@@ -0,0 +1,76 @@
+package receipts
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// The PDF prints the text receipt in Courier on a page as wide as 80 mm
+// paper and as long as the receipt.
+const (
+	pdfPageWidth = 80 / 25.4 * 72
+	pdfFontSize  = 8
+	pdfLeading   = 10
+	pdfMargin    = (pdfPageWidth - TextWidth*pdfFontSize*0.6) / 2
+)
+
+// renderPDF writes a single page PDF 1.4 document by hand: the standard
+// Courier font needs no embedding, so the document is only a few objects.
+func renderPDF(r Receipt) []byte {
+	lines := strings.Split(strings.TrimRight(renderText(r), "\n"), "\n")
+	height := float64(len(lines))*pdfLeading + 2*pdfMargin
+
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%.2f %.2f Td\n", pdfFontSize, pdfLeading, pdfMargin, height-pdfMargin-pdfFontSize)
+	for _, line := range lines {
+		fmt.Fprintf(&content, "(%s) Tj T*\n", pdfString(line))
+	}
+	content.WriteString("ET\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>", pdfPageWidth, height),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
+
+// pdfString escapes text for a PDF string in WinAnsiEncoding. Characters
+// the encoding lacks print as a question mark.
+func pdfString(text string) string {
+	var b strings.Builder
+	for _, char := range text {
+		switch {
+		case char == '(' || char == ')' || char == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(char)
+		case char == '€':
+			b.WriteString(`\200`)
+		case char >= 0x20 && char < 0x7f:
+			b.WriteRune(char)
+		case char >= 0xa0 && char <= 0xff:
+			fmt.Fprintf(&b, `\%03o`, char)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,290 @@
+// Package receipts renders the receipt, or invoice once it is paid, of a
+// sale: as an HTML page, as text for 80 mm thermal printers and as a PDF of
+// that text.
+package receipts
+
+import (
+	"errors"
+	"fmt"
+	"productmanagerapi/config"
+	"productmanagerapi/models"
+	"strings"
+	"time"
+)
+
+// Output formats.
+const (
+	FormatHTML = "html"
+	FormatText = "text"
+	FormatPDF  = "pdf"
+)
+
+// ErrUnknownFormat is returned by Render for a format it does not know.
+var ErrUnknownFormat = errors.New("receipt format must be html, text or pdf")
+
+// Receipt is what a receipt shows. Customer and Cashier are looked up by
+// the caller and left empty when unknown. Reprint marks the receipt as a
+// copy of one already handed out.
+type Receipt struct {
+	Store    config.StoreConfig
+	Sale     models.Sale
+	Customer *models.Customer
+	Cashier  string
+	Reprint  bool
+}
+
+// Document is a rendered receipt.
+type Document struct {
+	ContentType string
+	Filename    string
+	Body        []byte
+}
+
+// Render renders receipt in format.
+func Render(receipt Receipt, format string) (Document, error) {
+	var (
+		body        []byte
+		contentType string
+		err         error
+	)
+	switch format {
+	case FormatHTML:
+		body, err = renderHTML(receipt)
+		contentType = "text/html; charset=utf-8"
+	case FormatText:
+		body = []byte(renderText(receipt))
+		contentType = "text/plain; charset=utf-8"
+	case FormatPDF:
+		body = renderPDF(receipt)
+		contentType = "application/pdf"
+	default:
+		return Document{}, ErrUnknownFormat
+	}
+	if err != nil {
+		return Document{}, err
+	}
+
+	extension := format
+	if format == FormatText {
+		extension = "txt"
+	}
+	return Document{ContentType: contentType, Filename: receipt.Reference() + "." + extension, Body: body}, nil
+}
+
+// Amount is a labelled amount in the totals or payments of a receipt.
+// Strong amounts, such as the total, stand out.
+type Amount struct {
+	Label  string
+	Value  float64
+	Strong bool
+}
+
+// Line is a sale line as printed: Amount is the quantity at the unit
+// price, before the Discounts listed under it.
+type Line struct {
+	Name      string
+	Quantity  int
+	UnitPrice float64
+	Amount    float64
+	Discounts []Amount
+}
+
+// Title is Invoice for paid sales, which have an invoice number, and Pro
+// forma for the others.
+func (r Receipt) Title() string {
+	if r.Sale.InvoiceNumber != nil {
+		return "Invoice"
+	}
+	return "Pro forma"
+}
+
+// InvoiceNumber formats the invoice number of the sale with the configured
+// prefix, or returns an empty string when the sale has none.
+func (r Receipt) InvoiceNumber() string {
+	if r.Sale.InvoiceNumber == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s%06d", r.Store.InvoicePrefix, *r.Sale.InvoiceNumber)
+}
+
+// Reference names the receipt: its invoice number, or the sale otherwise.
+func (r Receipt) Reference() string {
+	if number := r.InvoiceNumber(); number != "" {
+		return number
+	}
+	return fmt.Sprintf("sale-%d", r.Sale.ID)
+}
+
+// Date is when the sale was paid, or created when it was not.
+func (r Receipt) Date() time.Time {
+	if r.Sale.PaidAt != nil {
+		return *r.Sale.PaidAt
+	}
+	return r.Sale.CreatedAt
+}
+
+// Cancelled tells whether the sale was cancelled.
+func (r Receipt) Cancelled() bool {
+	return r.Sale.Status == models.SaleCancelled
+}
+
+// StoreLines are the contact details printed under the store name.
+func (r Receipt) StoreLines() []string {
+	lines := splitLines(r.Store.Address)
+	if r.Store.Phone != "" {
+		lines = append(lines, "Tel: "+r.Store.Phone)
+	}
+	for _, value := range []string{r.Store.Email, r.Store.Website} {
+		if value != "" {
+			lines = append(lines, value)
+		}
+	}
+	if r.Store.TaxID != "" {
+		lines = append(lines, "Tax ID: "+r.Store.TaxID)
+	}
+	return lines
+}
+
+// CustomerName is the name of the customer of the sale, if any.
+func (r Receipt) CustomerName() string {
+	if r.Customer == nil {
+		return ""
+	}
+	return r.Customer.Name
+}
+
+// CustomerAddress is the billing address of the customer, or their first
+// address when none is labelled billing.
+func (r Receipt) CustomerAddress() []string {
+	if r.Customer == nil || len(r.Customer.Addresses) == 0 {
+		return nil
+	}
+	address := r.Customer.Addresses[0]
+	for _, candidate := range r.Customer.Addresses {
+		if strings.EqualFold(candidate.Label, "billing") {
+			address = candidate
+			break
+		}
+	}
+
+	var lines []string
+	for _, value := range []string{address.Line1, address.Line2, strings.TrimSpace(address.PostalCode + " " + address.City), address.Region, address.Country} {
+		if value != "" {
+			lines = append(lines, value)
+		}
+	}
+	return lines
+}
+
+// Lines are the sale lines with their discounts.
+func (r Receipt) Lines() []Line {
+	lines := make([]Line, 0, len(r.Sale.Products))
+	for _, product := range r.Sale.Products {
+		line := Line{
+			Name:      product.ProductName,
+			Quantity:  product.Quantity,
+			UnitPrice: product.UnitPrice,
+			Amount:    float64(product.Quantity) * product.UnitPrice,
+		}
+		for _, discount := range r.Sale.Discounts {
+			if discount.SaleProductID != nil && *discount.SaleProductID == product.ID && discount.Amount > 0 {
+				line.Discounts = append(line.Discounts, Amount{Label: discount.Description, Value: -discount.Amount})
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Totals are the subtotal, the line discounts together, the discounts on the
+// whole sale, the taxes and the total. Taxes included in the prices are
+// marked as such: they are not added to the total.
+func (r Receipt) Totals() []Amount {
+	sale := r.Sale
+	totals := []Amount{{Label: "Subtotal", Value: sale.Subtotal}}
+	if lineDiscounts := sale.DiscountTotal - orderDiscounts(sale); lineDiscounts > 0.005 {
+		totals = append(totals, Amount{Label: "Line discounts", Value: -lineDiscounts})
+	}
+	for _, discount := range sale.Discounts {
+		if discount.SaleProductID == nil && discount.Amount > 0 {
+			totals = append(totals, Amount{Label: discount.Description, Value: -discount.Amount})
+		}
+	}
+	for _, tax := range sale.Taxes {
+		label := fmt.Sprintf("%s %s%%", tax.Name, formatRate(tax.Rate))
+		if tax.Inclusive {
+			label += " incl."
+		}
+		totals = append(totals, Amount{Label: label, Value: tax.Amount})
+	}
+
+	label := "Total"
+	if r.Store.Currency != "" {
+		label += " " + r.Store.Currency
+	}
+	return append(totals, Amount{Label: label, Value: sale.Total, Strong: true})
+}
+
+// Payments are the tenders of the sale, then what is left to pay on a sale
+// not paid yet and what was refunded, when anything was.
+func (r Receipt) Payments() []Amount {
+	sale := r.Sale
+	var payments []Amount
+	for _, payment := range sale.Payments {
+		payments = append(payments, Amount{Label: tenderLabel(payment.Method), Value: payment.Amount})
+		if payment.Change > 0 {
+			payments = append(payments,
+				Amount{Label: "  Tendered", Value: payment.Tendered},
+				Amount{Label: "  Change", Value: payment.Change})
+		}
+	}
+	if balance := sale.Total - sale.PaidTotal; balance > 0.005 && sale.PaidAt == nil && !r.Cancelled() {
+		payments = append(payments, Amount{Label: "Balance due", Value: balance, Strong: true})
+	}
+	if sale.RefundedTotal > 0 {
+		payments = append(payments, Amount{Label: "Refunded", Value: -sale.RefundedTotal})
+	}
+	return payments
+}
+
+// FooterLines is the configured footer.
+func (r Receipt) FooterLines() []string {
+	return splitLines(r.Store.Footer)
+}
+
+func orderDiscounts(sale models.Sale) float64 {
+	var total float64
+	for _, discount := range sale.Discounts {
+		if discount.SaleProductID == nil {
+			total += discount.Amount
+		}
+	}
+	return total
+}
+
+func tenderLabel(method string) string {
+	label := strings.ReplaceAll(method, "_", " ")
+	if label == "" {
+		return "Payment"
+	}
+	return strings.ToUpper(label[:1]) + label[1:]
+}
+
+// formatRate prints a tax rate without trailing zeros: 20, 7.5.
+func formatRate(rate float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.3f", rate), "0"), ".")
+}
+
+func formatMoney(value float64) string {
+	return fmt.Sprintf("%.2f", value)
+}
+
+func splitLines(value string) []string {
+	var lines []string
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,158 @@
+package receipts
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// TextWidth is the number of characters in a line of 80 mm thermal paper
+// with the printer's default font.
+const TextWidth = 42
+
+// renderText lays the receipt out in TextWidth columns.
+func renderText(r Receipt) string {
+	var b textBuilder
+
+	if r.Store.Name != "" {
+		b.center(strings.ToUpper(r.Store.Name))
+	}
+	for _, line := range r.StoreLines() {
+		b.center(line)
+	}
+	b.rule('=')
+
+	if r.Cancelled() {
+		b.center("*** CANCELLED ***")
+	}
+	if r.Reprint {
+		b.center("*** COPY ***")
+	}
+	b.columns(strings.ToUpper(r.Title()), r.InvoiceNumber())
+	b.columns("Sale", fmt.Sprintf("#%d", r.Sale.ID))
+	b.columns("Date", r.Date().Format("2006-01-02 15:04"))
+	if r.Cashier != "" {
+		b.columns("Cashier", r.Cashier)
+	}
+	if name := r.CustomerName(); name != "" {
+		b.columns("Customer", name)
+		for _, line := range r.CustomerAddress() {
+			b.columns("", line)
+		}
+	}
+	b.rule('-')
+
+	for _, line := range r.Lines() {
+		b.wrap(line.Name)
+		b.columns(fmt.Sprintf("  %d x %s", line.Quantity, formatMoney(line.UnitPrice)), formatMoney(line.Amount))
+		for _, discount := range line.Discounts {
+			b.columns("  "+discount.Label, formatMoney(discount.Value))
+		}
+	}
+	b.rule('-')
+
+	for _, total := range r.Totals() {
+		label := total.Label
+		if total.Strong {
+			label = strings.ToUpper(label)
+		}
+		b.columns(label, formatMoney(total.Value))
+	}
+
+	if payments := r.Payments(); len(payments) > 0 {
+		b.rule('-')
+		for _, payment := range payments {
+			b.columns(payment.Label, formatMoney(payment.Value))
+		}
+	}
+
+	if footer := r.FooterLines(); len(footer) > 0 {
+		b.rule('=')
+		for _, line := range footer {
+			for _, wrapped := range wrap(line, TextWidth) {
+				b.center(wrapped)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+type textBuilder struct {
+	strings.Builder
+}
+
+func (b *textBuilder) line(text string) {
+	b.WriteString(text)
+	b.WriteByte('\n')
+}
+
+func (b *textBuilder) rule(char rune) {
+	b.line(strings.Repeat(string(char), TextWidth))
+}
+
+func (b *textBuilder) center(text string) {
+	text = truncate(text, TextWidth)
+	b.line(strings.Repeat(" ", (TextWidth-utf8.RuneCountInString(text))/2) + text)
+}
+
+// columns puts left and right at either end of a line, shortening left when
+// both do not fit.
+func (b *textBuilder) columns(left, right string) {
+	right = truncate(right, TextWidth)
+	space := TextWidth - utf8.RuneCountInString(right)
+	if right != "" {
+		space--
+	}
+	left = truncate(left, space)
+	padding := TextWidth - utf8.RuneCountInString(left) - utf8.RuneCountInString(right)
+	b.line(left + strings.Repeat(" ", padding) + right)
+}
+
+func (b *textBuilder) wrap(text string) {
+	for _, line := range wrap(text, TextWidth) {
+		b.line(line)
+	}
+}
+
+// wrap breaks text into lines of at most width characters, between words
+// when it can.
+func wrap(text string, width int) []string {
+	var lines []string
+	current := ""
+	for _, word := range strings.Fields(text) {
+		for utf8.RuneCountInString(word) > width {
+			if current != "" {
+				lines = append(lines, current)
+				current = ""
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:width]))
+			word = string(runes[width:])
+		}
+		switch {
+		case current == "":
+			current = word
+		case utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) <= width:
+			current += " " + word
+		default:
+			lines = append(lines, current)
+			current = word
+		}
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+func truncate(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	runes := []rune(text)
+	if len(runes) <= width {
+		return text
+	}
+	return string(runes[:width])
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+
+	"gorm.io/gorm"
+)
+
+// CategoryFilter narrows FindAll; zero fields match every category.
+type CategoryFilter struct {
+	CreatedByID uint
+}
+
+type CategoryRepository interface {
+	FindAll(ctx context.Context, filter CategoryFilter) ([]models.Category, error)
+	FindByID(ctx context.Context, id uint) (models.Category, error)
+	Create(ctx context.Context, category *models.Category) error
+	Update(ctx context.Context, category *models.Category) error
+	Delete(ctx context.Context, id uint) error
+}
+
+type gormCategoryRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormCategoryRepository) FindAll(ctx context.Context, filter CategoryFilter) ([]models.Category, error) {
+	db := r.db.WithContext(ctx)
+	if filter.CreatedByID != 0 {
+		db = db.Where("created_by_id = ?", filter.CreatedByID)
+	}
+
+	categories := []models.Category{}
+	if err := db.Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (r *gormCategoryRepository) FindByID(ctx context.Context, id uint) (models.Category, error) {
+	var category models.Category
+	if err := r.db.WithContext(ctx).First(&category, id).Error; err != nil {
+		return models.Category{}, translateError(err)
+	}
+	return category, nil
+}
+
+func (r *gormCategoryRepository) Create(ctx context.Context, category *models.Category) error {
+	return r.db.WithContext(ctx).Create(category).Error
+}
+
+func (r *gormCategoryRepository) Update(ctx context.Context, category *models.Category) error {
+	result := r.db.WithContext(ctx).Model(category).Select("*").Omit("created_at").Updates(category)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormCategoryRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.Category{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
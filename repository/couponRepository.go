@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+
+	"gorm.io/gorm"
+)
+
+type CouponRepository interface {
+	FindAll(ctx context.Context) ([]models.Coupon, error)
+	FindByID(ctx context.Context, id uint) (models.Coupon, error)
+	// FindByCode looks a coupon up by its code, which is stored upper case.
+	FindByCode(ctx context.Context, code string) (models.Coupon, error)
+	Create(ctx context.Context, coupon *models.Coupon) error
+	// Update saves the coupon's columns except UsedCount, which only Redeem
+	// and Release change.
+	Update(ctx context.Context, coupon *models.Coupon) error
+	Delete(ctx context.Context, id uint) error
+	// Redeem counts one more use of the coupon. It reports false, changing
+	// nothing, when the coupon has no uses left.
+	Redeem(ctx context.Context, id uint) (bool, error)
+	// Release gives back one use of the coupon, as when the sale it was
+	// redeemed on is cancelled. A coupon deleted since is left alone.
+	Release(ctx context.Context, id uint) error
+}
+
+type gormCouponRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormCouponRepository) FindAll(ctx context.Context) ([]models.Coupon, error) {
+	coupons := []models.Coupon{}
+	if err := r.db.WithContext(ctx).Order("id").Find(&coupons).Error; err != nil {
+		return nil, err
+	}
+	return coupons, nil
+}
+
+func (r *gormCouponRepository) FindByID(ctx context.Context, id uint) (models.Coupon, error) {
+	var coupon models.Coupon
+	if err := r.db.WithContext(ctx).First(&coupon, id).Error; err != nil {
+		return models.Coupon{}, translateError(err)
+	}
+	return coupon, nil
+}
+
+func (r *gormCouponRepository) FindByCode(ctx context.Context, code string) (models.Coupon, error) {
+	var coupon models.Coupon
+	if err := r.db.WithContext(ctx).Where("code = ?", code).First(&coupon).Error; err != nil {
+		return models.Coupon{}, translateError(err)
+	}
+	return coupon, nil
+}
+
+func (r *gormCouponRepository) Create(ctx context.Context, coupon *models.Coupon) error {
+	return r.db.WithContext(ctx).Create(coupon).Error
+}
+
+func (r *gormCouponRepository) Update(ctx context.Context, coupon *models.Coupon) error {
+	result := r.db.WithContext(ctx).Model(coupon).Select("*").Omit("created_at", "created_by_id", "used_count").Updates(coupon)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormCouponRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.Coupon{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormCouponRepository) Redeem(ctx context.Context, id uint) (bool, error) {
+	if _, err := r.FindByID(ctx, id); err != nil {
+		return false, err
+	}
+
+	result := r.db.WithContext(ctx).Model(&models.Coupon{}).
+		Where("id = ? AND (max_uses = 0 OR used_count < max_uses)", id).
+		Update("used_count", gorm.Expr("used_count + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormCouponRepository) Release(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&models.Coupon{}).
+		Where("id = ? AND used_count > 0", id).
+		Update("used_count", gorm.Expr("used_count - 1")).Error
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CustomerRepository interface {
+	// FindAll and FindByID return customers with their addresses.
+	FindAll(ctx context.Context) ([]models.Customer, error)
+	FindByID(ctx context.Context, id uint) (models.Customer, error)
+	// Create stores the customer together with its addresses.
+	Create(ctx context.Context, customer *models.Customer) error
+	// Update saves the customer's own columns, except StoreCredit, and
+	// replaces its addresses.
+	Update(ctx context.Context, customer *models.Customer) error
+	Delete(ctx context.Context, id uint) error
+	// AdjustStoreCredit adds delta to the customer's store credit. It
+	// reports false, changing nothing, when the credit would go negative.
+	AdjustStoreCredit(ctx context.Context, id uint, delta float64) (bool, error)
+}
+
+type gormCustomerRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormCustomerRepository) FindAll(ctx context.Context) ([]models.Customer, error) {
+	customers := []models.Customer{}
+	if err := r.db.WithContext(ctx).Preload("Addresses").Find(&customers).Error; err != nil {
+		return nil, err
+	}
+	return customers, nil
+}
+
+func (r *gormCustomerRepository) FindByID(ctx context.Context, id uint) (models.Customer, error) {
+	var customer models.Customer
+	if err := r.db.WithContext(ctx).Preload("Addresses").First(&customer, id).Error; err != nil {
+		return models.Customer{}, translateError(err)
+	}
+	return customer, nil
+}
+
+func (r *gormCustomerRepository) Create(ctx context.Context, customer *models.Customer) error {
+	return r.db.WithContext(ctx).Create(customer).Error
+}
+
+// Update should run in a transaction so the addresses are replaced at once.
+func (r *gormCustomerRepository) Update(ctx context.Context, customer *models.Customer) error {
+	db := r.db.WithContext(ctx)
+
+	result := db.Model(customer).Select("*").Omit("created_at", "store_credit", clause.Associations).Updates(customer)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	if err := db.Unscoped().Where("customer_id = ?", customer.ID).Delete(&models.CustomerAddress{}).Error; err != nil {
+		return err
+	}
+	for i := range customer.Addresses {
+		customer.Addresses[i].ID = 0
+		customer.Addresses[i].CustomerID = customer.ID
+	}
+	if len(customer.Addresses) == 0 {
+		return nil
+	}
+	return db.Create(&customer.Addresses).Error
+}
+
+func (r *gormCustomerRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.Customer{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormCustomerRepository) AdjustStoreCredit(ctx context.Context, id uint, delta float64) (bool, error) {
+	if _, err := r.FindByID(ctx, id); err != nil {
+		return false, err
+	}
+
+	result := r.db.WithContext(ctx).Model(&models.Customer{}).
+		Where("id = ? AND store_credit + ? >= 0", id, delta).
+		Update("store_credit", gorm.Expr("store_credit + ?", delta))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
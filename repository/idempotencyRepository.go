@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository interface {
+	// Reserve saves key unless the user already has a record with the same
+	// key, and reports whether it did. Records expired at the given time are
+	// deleted first, which frees their keys. It is atomic so two concurrent
+	// requests cannot both reserve a key.
+	Reserve(ctx context.Context, key *models.IdempotencyKey, at time.Time) (bool, error)
+	Find(ctx context.Context, userID uint, key string) (models.IdempotencyKey, error)
+	Update(ctx context.Context, key *models.IdempotencyKey) error
+	Delete(ctx context.Context, id uint) error
+}
+
+type gormIdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormIdempotencyRepository) Reserve(ctx context.Context, key *models.IdempotencyKey, at time.Time) (bool, error) {
+	db := r.db.WithContext(ctx)
+	if err := db.Unscoped().Where("expires_at <= ?", at).Delete(&models.IdempotencyKey{}).Error; err != nil {
+		return false, err
+	}
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormIdempotencyRepository) Find(ctx context.Context, userID uint, key string) (models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND key = ?", userID, key).First(&record).Error; err != nil {
+		return models.IdempotencyKey{}, translateError(err)
+	}
+	return record, nil
+}
+
+func (r *gormIdempotencyRepository) Update(ctx context.Context, key *models.IdempotencyKey) error {
+	result := r.db.WithContext(ctx).Model(key).Select("status", "content_type", "location", "link", "body", "expires_at").Updates(key)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormIdempotencyRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Unscoped().Delete(&models.IdempotencyKey{}, id).Error
+}
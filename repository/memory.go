@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"productmanagerapi/models"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryData is the state shared by the in-memory repositories. A single
+// lock guards every table so cross-repository reads stay consistent.
+type memoryData struct {
+	mu sync.RWMutex
+	// txMu serializes transactions, which stands in for row locking.
+	txMu sync.Mutex
+
+	categories map[uint]models.Category
+	products   map[uint]models.Product
+	customers  map[uint]models.Customer
+	sales      map[uint]models.Sale
+	returns    map[uint]models.SaleReturn
+	payments   map[uint]models.Payment
+	promotions map[uint]models.Promotion
+	coupons    map[uint]models.Coupon
+	taxRates   map[uint]models.TaxRate
+	sequences  map[string]uint
+	users      map[uint]models.User
+
+	refreshTokens map[uint]models.RefreshToken
+	revokedTokens map[string]models.RevokedToken
+
+	idempotencyKeys map[uint]models.IdempotencyKey
+
+	lastID map[string]uint
+}
+
+// NewMemoryStore returns thread-safe repositories that keep everything in
+// process memory. It is meant for unit tests and tools that must not need a
+// database.
+func NewMemoryStore() *Store {
+	data := &memoryData{
+		categories: map[uint]models.Category{},
+		products:   map[uint]models.Product{},
+		customers:  map[uint]models.Customer{},
+		sales:      map[uint]models.Sale{},
+		returns:    map[uint]models.SaleReturn{},
+		payments:   map[uint]models.Payment{},
+		promotions: map[uint]models.Promotion{},
+		coupons:    map[uint]models.Coupon{},
+		taxRates:   map[uint]models.TaxRate{},
+		sequences:  map[string]uint{},
+		users:      map[uint]models.User{},
+
+		refreshTokens: map[uint]models.RefreshToken{},
+		revokedTokens: map[string]models.RevokedToken{},
+
+		idempotencyKeys: map[uint]models.IdempotencyKey{},
+
+		lastID: map[string]uint{},
+	}
+
+	store := &Store{
+		Categories: &memoryCategoryRepository{data: data},
+		Products:   &memoryProductRepository{data: data},
+		Customers:  &memoryCustomerRepository{data: data},
+		Sales:      &memorySaleRepository{data: data},
+		Returns:    &memoryReturnRepository{data: data},
+		Payments:   &memoryPaymentRepository{data: data},
+		Promotions: &memoryPromotionRepository{data: data},
+		Coupons:    &memoryCouponRepository{data: data},
+		TaxRates:   &memoryTaxRateRepository{data: data},
+		Sequences:  &memorySequenceRepository{data: data},
+		Users:      &memoryUserRepository{data: data},
+		Tokens:     &memoryTokenRepository{data: data},
+
+		IdempotencyKeys: &memoryIdempotencyRepository{data: data},
+	}
+
+	// Transactions snapshot every table and restore the snapshot when fn
+	// fails. Writes made outside transactions while one runs are rolled back
+	// with it, which is acceptable for the store's intended use.
+	store.transaction = func(ctx context.Context, fn func(tx *Store) error) error {
+		data.txMu.Lock()
+		defer data.txMu.Unlock()
+
+		data.mu.RLock()
+		snapshot := data.clone()
+		data.mu.RUnlock()
+
+		tx := *store
+		tx.transaction = func(ctx context.Context, fn func(tx *Store) error) error {
+			return fn(&tx)
+		}
+
+		if err := fn(&tx); err != nil {
+			data.mu.Lock()
+			data.restore(snapshot)
+			data.mu.Unlock()
+			return err
+		}
+		return nil
+	}
+
+	return store
+}
+
+// clone copies every table. Callers must hold mu.
+func (d *memoryData) clone() *memoryData {
+	return &memoryData{
+		categories:    maps.Clone(d.categories),
+		products:      maps.Clone(d.products),
+		customers:     maps.Clone(d.customers),
+		sales:         maps.Clone(d.sales),
+		returns:       maps.Clone(d.returns),
+		payments:      maps.Clone(d.payments),
+		promotions:    maps.Clone(d.promotions),
+		coupons:       maps.Clone(d.coupons),
+		taxRates:      maps.Clone(d.taxRates),
+		sequences:     maps.Clone(d.sequences),
+		users:         maps.Clone(d.users),
+		refreshTokens: maps.Clone(d.refreshTokens),
+		revokedTokens: maps.Clone(d.revokedTokens),
+		lastID:        maps.Clone(d.lastID),
+
+		idempotencyKeys: maps.Clone(d.idempotencyKeys),
+	}
+}
+
+// restore puts back the tables of a snapshot taken by clone. Callers must
+// hold mu.
+func (d *memoryData) restore(snapshot *memoryData) {
+	d.categories = snapshot.categories
+	d.products = snapshot.products
+	d.customers = snapshot.customers
+	d.sales = snapshot.sales
+	d.returns = snapshot.returns
+	d.payments = snapshot.payments
+	d.promotions = snapshot.promotions
+	d.coupons = snapshot.coupons
+	d.taxRates = snapshot.taxRates
+	d.sequences = snapshot.sequences
+	d.users = snapshot.users
+	d.refreshTokens = snapshot.refreshTokens
+	d.revokedTokens = snapshot.revokedTokens
+	d.idempotencyKeys = snapshot.idempotencyKeys
+	d.lastID = snapshot.lastID
+}
+
+// nextID hands out auto-increment IDs per table. Callers must hold mu.
+func (d *memoryData) nextID(table string) uint {
+	d.lastID[table]++
+	return d.lastID[table]
+}
+
+// stampCreated fills the gorm.Model bookkeeping fields for a new record.
+// Callers must hold mu.
+func (d *memoryData) stampCreated(table string, model *gorm.Model) {
+	now := time.Now()
+	if model.ID == 0 {
+		model.ID = d.nextID(table)
+	} else if model.ID > d.lastID[table] {
+		d.lastID[table] = model.ID
+	}
+	model.CreatedAt = now
+	model.UpdatedAt = now
+}
+
+// sortedByID returns the values of records ordered by ID, matching the
+// insertion order a database would return.
+func sortedByID[T any](records map[uint]T) []T {
+	ids := make([]uint, 0, len(records))
+	for id := range records {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	values := make([]T, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, records[id])
+	}
+	return values
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+	"time"
+)
+
+type memoryCategoryRepository struct {
+	data *memoryData
+}
+
+func (r *memoryCategoryRepository) FindAll(ctx context.Context, filter CategoryFilter) ([]models.Category, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	categories := []models.Category{}
+	for _, category := range sortedByID(r.data.categories) {
+		if filter.CreatedByID != 0 && category.CreatedByID != filter.CreatedByID {
+			continue
+		}
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+func (r *memoryCategoryRepository) FindByID(ctx context.Context, id uint) (models.Category, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	category, ok := r.data.categories[id]
+	if !ok {
+		return models.Category{}, ErrNotFound
+	}
+	return category, nil
+}
+
+func (r *memoryCategoryRepository) Create(ctx context.Context, category *models.Category) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	r.data.stampCreated("categories", &category.Model)
+	r.data.categories[category.ID] = *category
+	return nil
+}
+
+func (r *memoryCategoryRepository) Update(ctx context.Context, category *models.Category) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	existing, ok := r.data.categories[category.ID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	category.CreatedAt = existing.CreatedAt
+	category.UpdatedAt = time.Now()
+	r.data.categories[category.ID] = *category
+	return nil
+}
+
+func (r *memoryCategoryRepository) Delete(ctx context.Context, id uint) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if _, ok := r.data.categories[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.data.categories, id)
+	return nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+	"time"
+)
+
+type memoryCouponRepository struct {
+	data *memoryData
+}
+
+func (r *memoryCouponRepository) FindAll(ctx context.Context) ([]models.Coupon, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	return sortedByID(r.data.coupons), nil
+}
+
+func (r *memoryCouponRepository) FindByID(ctx context.Context, id uint) (models.Coupon, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	coupon, ok := r.data.coupons[id]
+	if !ok {
+		return models.Coupon{}, ErrNotFound
+	}
+	return coupon, nil
+}
+
+func (r *memoryCouponRepository) FindByCode(ctx context.Context, code string) (models.Coupon, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	for _, coupon := range r.data.coupons {
+		if coupon.Code == code {
+			return coupon, nil
+		}
+	}
+	return models.Coupon{}, ErrNotFound
+}
+
+func (r *memoryCouponRepository) Create(ctx context.Context, coupon *models.Coupon) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	r.data.stampCreated("coupons", &coupon.Model)
+	r.data.coupons[coupon.ID] = *coupon
+	return nil
+}
+
+func (r *memoryCouponRepository) Update(ctx context.Context, coupon *models.Coupon) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	existing, ok := r.data.coupons[coupon.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	coupon.CreatedAt = existing.CreatedAt
+	coupon.CreatedByID = existing.CreatedByID
+	coupon.UsedCount = existing.UsedCount
+	coupon.UpdatedAt = time.Now()
+	r.data.coupons[coupon.ID] = *coupon
+	return nil
+}
+
+func (r *memoryCouponRepository) Delete(ctx context.Context, id uint) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if _, ok := r.data.coupons[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.data.coupons, id)
+	return nil
+}
+
+func (r *memoryCouponRepository) Redeem(ctx context.Context, id uint) (bool, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	coupon, ok := r.data.coupons[id]
+	if !ok {
+		return false, ErrNotFound
+	}
+	if coupon.MaxUses > 0 && coupon.UsedCount >= coupon.MaxUses {
+		return false, nil
+	}
+	coupon.UsedCount++
+	coupon.UpdatedAt = time.Now()
+	r.data.coupons[id] = coupon
+	return true, nil
+}
+
+func (r *memoryCouponRepository) Release(ctx context.Context, id uint) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	coupon, ok := r.data.coupons[id]
+	if !ok || coupon.UsedCount == 0 {
+		return nil
+	}
+	coupon.UsedCount--
+	coupon.UpdatedAt = time.Now()
+	r.data.coupons[id] = coupon
+	return nil
+}
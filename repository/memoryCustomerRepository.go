@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+	"time"
+)
+
+type memoryCustomerRepository struct {
+	data *memoryData
+}
+
+// copyCustomer detaches the addresses so callers cannot mutate stored state.
+func copyCustomer(customer models.Customer) models.Customer {
+	customer.Addresses = append([]models.CustomerAddress(nil), customer.Addresses...)
+	return customer
+}
+
+func (r *memoryCustomerRepository) FindAll(ctx context.Context) ([]models.Customer, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	customers := sortedByID(r.data.customers)
+	for i := range customers {
+		customers[i] = copyCustomer(customers[i])
+	}
+	return customers, nil
+}
+
+func (r *memoryCustomerRepository) FindByID(ctx context.Context, id uint) (models.Customer, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	customer, ok := r.data.customers[id]
+	if !ok {
+		return models.Customer{}, ErrNotFound
+	}
+	return copyCustomer(customer), nil
+}
+
+func (r *memoryCustomerRepository) Create(ctx context.Context, customer *models.Customer) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	r.data.stampCreated("customers", &customer.Model)
+	r.data.stampAddresses(customer)
+	r.data.customers[customer.ID] = copyCustomer(*customer)
+	return nil
+}
+
+func (r *memoryCustomerRepository) Update(ctx context.Context, customer *models.Customer) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	existing, ok := r.data.customers[customer.ID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	customer.CreatedAt = existing.CreatedAt
+	customer.UpdatedAt = time.Now()
+	customer.StoreCredit = existing.StoreCredit
+	for i := range customer.Addresses {
+		customer.Addresses[i].ID = 0
+	}
+	r.data.stampAddresses(customer)
+	r.data.customers[customer.ID] = copyCustomer(*customer)
+	return nil
+}
+
+func (r *memoryCustomerRepository) Delete(ctx context.Context, id uint) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if _, ok := r.data.customers[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.data.customers, id)
+	return nil
+}
+
+func (r *memoryCustomerRepository) AdjustStoreCredit(ctx context.Context, id uint, delta float64) (bool, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	customer, ok := r.data.customers[id]
+	if !ok {
+		return false, ErrNotFound
+	}
+	if customer.StoreCredit+delta < 0 {
+		return false, nil
+	}
+	customer.StoreCredit += delta
+	customer.UpdatedAt = time.Now()
+	r.data.customers[id] = customer
+	return true, nil
+}
+
+// stampAddresses gives the customer's new addresses their IDs. Callers must
+// hold mu.
+func (d *memoryData) stampAddresses(customer *models.Customer) {
+	for i := range customer.Addresses {
+		d.stampCreated("customer_addresses", &customer.Addresses[i].Model)
+		customer.Addresses[i].CustomerID = customer.ID
+	}
+}
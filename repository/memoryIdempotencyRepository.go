@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+	"slices"
+	"time"
+)
+
+type memoryIdempotencyRepository struct {
+	data *memoryData
+}
+
+func (r *memoryIdempotencyRepository) Reserve(ctx context.Context, key *models.IdempotencyKey, at time.Time) (bool, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	taken := false
+	for id, existing := range r.data.idempotencyKeys {
+		switch {
+		case !existing.ExpiresAt.After(at):
+			delete(r.data.idempotencyKeys, id)
+		case existing.UserID == key.UserID && existing.Key == key.Key:
+			taken = true
+		}
+	}
+	if taken {
+		return false, nil
+	}
+
+	r.data.stampCreated("idempotency_keys", &key.Model)
+	record := *key
+	record.Body = slices.Clone(key.Body)
+	r.data.idempotencyKeys[key.ID] = record
+	return true, nil
+}
+
+func (r *memoryIdempotencyRepository) Find(ctx context.Context, userID uint, key string) (models.IdempotencyKey, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	for _, record := range r.data.idempotencyKeys {
+		if record.UserID == userID && record.Key == key {
+			record.Body = slices.Clone(record.Body)
+			return record, nil
+		}
+	}
+	return models.IdempotencyKey{}, ErrNotFound
+}
+
+func (r *memoryIdempotencyRepository) Update(ctx context.Context, key *models.IdempotencyKey) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	record, ok := r.data.idempotencyKeys[key.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	record.Status = key.Status
+	record.ContentType = key.ContentType
+	record.Location = key.Location
+	record.Link = key.Link
+	record.Body = slices.Clone(key.Body)
+	record.ExpiresAt = key.ExpiresAt
+	record.UpdatedAt = time.Now()
+	r.data.idempotencyKeys[key.ID] = record
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Delete(ctx context.Context, id uint) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	delete(r.data.idempotencyKeys, id)
+	return nil
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+	"time"
+)
+
+type memoryPaymentRepository struct {
+	data *memoryData
+}
+
+// paymentsOf lists the payments of a sale. Callers must hold mu.
+func (d *memoryData) paymentsOf(saleID uint) []models.Payment {
+	payments := []models.Payment{}
+	for _, payment := range sortedByID(d.payments) {
+		if payment.SaleID == saleID {
+			payments = append(payments, payment)
+		}
+	}
+	return payments
+}
+
+func (r *memoryPaymentRepository) FindBySale(ctx context.Context, saleID uint) ([]models.Payment, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	return r.data.paymentsOf(saleID), nil
+}
+
+func (r *memoryPaymentRepository) Create(ctx context.Context, payments []models.Payment) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for i := range payments {
+		r.data.stampCreated("payments", &payments[i].Model)
+		r.data.payments[payments[i].ID] = payments[i]
+	}
+	return nil
+}
+
+func (r *memoryPaymentRepository) MarkRefunded(ctx context.Context, ids []uint, at time.Time) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for _, id := range ids {
+		if payment, found := r.data.payments[id]; found {
+			payment.RefundedAt = &at
+			payment.UpdatedAt = at
+			r.data.payments[id] = payment
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"productmanagerapi/models"
+	"slices"
+	"strings"
+	"time"
+)
+
+type memoryProductRepository struct {
+	data *memoryData
+}
+
+// withCategory mirrors Preload("Category"). Callers must hold mu.
+func (r *memoryProductRepository) withCategory(product models.Product) models.Product {
+	product.Category = r.data.categories[product.CategoryID]
+	return product
+}
+
+func (r *memoryProductRepository) FindAll(ctx context.Context, filter ProductFilter) ([]models.Product, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	products := []models.Product{}
+	for _, product := range sortedByID(r.data.products) {
+		if matchesProductFilter(product, filter) {
+			products = append(products, r.withCategory(product))
+		}
+	}
+	return products, nil
+}
+
+func (r *memoryProductRepository) FindPage(ctx context.Context, filter ProductFilter, page ProductPage) ([]models.Product, int64, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	var matching []models.Product
+	for _, product := range sortedByID(r.data.products) {
+		if matchesProductFilter(product, filter) {
+			matching = append(matching, product)
+		}
+	}
+
+	sorts := withIDSort(page.Sorts)
+	compare := func(a, b models.Product) int {
+		for _, sort := range sorts {
+			order := compareProducts(sort.Field, a, b)
+			if sort.Desc {
+				order = -order
+			}
+			if order != 0 {
+				return order
+			}
+		}
+		return 0
+	}
+	slices.SortStableFunc(matching, compare)
+
+	products := []models.Product{}
+	skipped := 0
+	for _, product := range matching {
+		if page.After != nil && compare(*page.After, product) >= 0 {
+			continue
+		}
+		if skipped < page.Offset {
+			skipped++
+			continue
+		}
+		if len(products) == page.Limit {
+			break
+		}
+		products = append(products, r.withCategory(product))
+	}
+	return products, int64(len(matching)), nil
+}
+
+func (r *memoryProductRepository) FindByID(ctx context.Context, id uint) (models.Product, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	product, ok := r.data.products[id]
+	if !ok {
+		return models.Product{}, ErrNotFound
+	}
+	return r.withCategory(product), nil
+}
+
+// FindByIDForUpdate needs no row lock: memory transactions are serialized.
+func (r *memoryProductRepository) FindByIDForUpdate(ctx context.Context, id uint) (models.Product, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	product, ok := r.data.products[id]
+	if !ok {
+		return models.Product{}, ErrNotFound
+	}
+	return product, nil
+}
+
+func (r *memoryProductRepository) Create(ctx context.Context, product *models.Product) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	r.data.stampCreated("products", &product.Model)
+	stored := *product
+	stored.Category = models.Category{}
+	r.data.products[product.ID] = stored
+	return nil
+}
+
+func (r *memoryProductRepository) Update(ctx context.Context, product *models.Product) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	existing, ok := r.data.products[product.ID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	product.CreatedAt = existing.CreatedAt
+	product.UpdatedAt = time.Now()
+	product.Reserved = existing.Reserved
+	stored := *product
+	stored.Category = models.Category{}
+	r.data.products[product.ID] = stored
+	return nil
+}
+
+func (r *memoryProductRepository) Delete(ctx context.Context, id uint) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if _, ok := r.data.products[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.data.products, id)
+	return nil
+}
+
+func (r *memoryProductRepository) AdjustStock(ctx context.Context, id uint, delta int) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	product, ok := r.data.products[id]
+	if !ok {
+		return ErrNotFound
+	}
+	product.Stock += delta
+	product.UpdatedAt = time.Now()
+	r.data.products[id] = product
+	return nil
+}
+
+func (r *memoryProductRepository) AdjustReserved(ctx context.Context, id uint, delta int) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	product, ok := r.data.products[id]
+	if !ok {
+		return ErrNotFound
+	}
+	product.Reserved += delta
+	product.UpdatedAt = time.Now()
+	r.data.products[id] = product
+	return nil
+}
+
+func matchesProductFilter(product models.Product, filter ProductFilter) bool {
+	switch {
+	case filter.CreatedByID != 0 && product.CreatedByID != filter.CreatedByID:
+		return false
+	case filter.CategoryID != 0 && product.CategoryID != filter.CategoryID:
+		return false
+	case filter.MinPrice != nil && product.Price < *filter.MinPrice:
+		return false
+	case filter.MaxPrice != nil && product.Price > *filter.MaxPrice:
+		return false
+	case filter.InStock && product.Stock <= product.Reserved:
+		return false
+	case filter.NameContains != "" && !strings.Contains(strings.ToLower(product.Name), strings.ToLower(filter.NameContains)):
+		return false
+	case !filter.CreatedFrom.IsZero() && product.CreatedAt.Before(filter.CreatedFrom):
+		return false
+	}
+	return true
+}
+
+// compareProducts compares a and b on field, in ascending order, as
+// cmp.Compare does.
+func compareProducts(field string, a, b models.Product) int {
+	switch field {
+	case ProductSortName:
+		return strings.Compare(a.Name, b.Name)
+	case ProductSortPrice:
+		return cmp.Compare(a.Price, b.Price)
+	case ProductSortStock:
+		return cmp.Compare(a.Stock, b.Stock)
+	case ProductSortCategoryID:
+		return cmp.Compare(a.CategoryID, b.CategoryID)
+	case ProductSortCreatedAt:
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+	"time"
+)
+
+type memoryPromotionRepository struct {
+	data *memoryData
+}
+
+func (r *memoryPromotionRepository) FindAll(ctx context.Context) ([]models.Promotion, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	return sortedByID(r.data.promotions), nil
+}
+
+func (r *memoryPromotionRepository) FindByID(ctx context.Context, id uint) (models.Promotion, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	promotion, ok := r.data.promotions[id]
+	if !ok {
+		return models.Promotion{}, ErrNotFound
+	}
+	return promotion, nil
+}
+
+func (r *memoryPromotionRepository) FindActive(ctx context.Context, at time.Time) ([]models.Promotion, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	promotions := []models.Promotion{}
+	for _, promotion := range sortedByID(r.data.promotions) {
+		if promotion.Disabled {
+			continue
+		}
+		if promotion.StartsAt != nil && promotion.StartsAt.After(at) {
+			continue
+		}
+		if promotion.EndsAt != nil && !promotion.EndsAt.After(at) {
+			continue
+		}
+		promotions = append(promotions, promotion)
+	}
+	return promotions, nil
+}
+
+func (r *memoryPromotionRepository) Create(ctx context.Context, promotion *models.Promotion) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	r.data.stampCreated("promotions", &promotion.Model)
+	r.data.promotions[promotion.ID] = *promotion
+	return nil
+}
+
+func (r *memoryPromotionRepository) Update(ctx context.Context, promotion *models.Promotion) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	existing, ok := r.data.promotions[promotion.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	promotion.CreatedAt = existing.CreatedAt
+	promotion.CreatedByID = existing.CreatedByID
+	promotion.UpdatedAt = time.Now()
+	r.data.promotions[promotion.ID] = *promotion
+	return nil
+}
+
+func (r *memoryPromotionRepository) Delete(ctx context.Context, id uint) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if _, ok := r.data.promotions[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.data.promotions, id)
+	return nil
+}
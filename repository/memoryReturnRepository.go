@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+	"slices"
+	"time"
+)
+
+type memoryReturnRepository struct {
+	data *memoryData
+}
+
+// copyReturn detaches the line slice so callers cannot mutate stored state.
+func copyReturn(saleReturn models.SaleReturn) models.SaleReturn {
+	saleReturn.Lines = append([]models.SaleReturnLine(nil), saleReturn.Lines...)
+	return saleReturn
+}
+
+// returnsOf lists the returns of a sale. Callers must hold mu.
+func (d *memoryData) returnsOf(saleID uint) []models.SaleReturn {
+	returns := []models.SaleReturn{}
+	for _, saleReturn := range sortedByID(d.returns) {
+		if saleReturn.SaleID == saleID {
+			returns = append(returns, copyReturn(saleReturn))
+		}
+	}
+	return returns
+}
+
+func (r *memoryReturnRepository) FindBySale(ctx context.Context, saleID uint) ([]models.SaleReturn, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	return r.data.returnsOf(saleID), nil
+}
+
+func (r *memoryReturnRepository) FindCreatedBetween(ctx context.Context, from, before time.Time) ([]models.SaleReturn, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	returns := []models.SaleReturn{}
+	for _, saleReturn := range sortedByID(r.data.returns) {
+		if !saleReturn.CreatedAt.Before(from) && saleReturn.CreatedAt.Before(before) {
+			returns = append(returns, copyReturn(saleReturn))
+		}
+	}
+	return returns, nil
+}
+
+func (r *memoryReturnRepository) FindByID(ctx context.Context, id uint) (models.SaleReturn, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	saleReturn, ok := r.data.returns[id]
+	if !ok {
+		return models.SaleReturn{}, ErrNotFound
+	}
+	return copyReturn(saleReturn), nil
+}
+
+func (r *memoryReturnRepository) SumReturned(ctx context.Context, query SalesTotalsQuery) ([]SalesTotal, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	totals := salesTotals{}
+	for _, saleReturn := range r.data.returns {
+		if saleReturn.CreatedAt.Before(query.From) || !saleReturn.CreatedAt.Before(query.To) {
+			continue
+		}
+		sale := r.data.sales[saleReturn.SaleID]
+		for _, returned := range saleReturn.Lines {
+			i := slices.IndexFunc(sale.Products, func(line models.SaleProduct) bool { return line.ID == returned.SaleProductID })
+			if i < 0 || sale.Products[i].Quantity == 0 {
+				continue
+			}
+			line := sale.Products[i]
+			revenue := memoryLineRevenue(line) * float64(returned.Quantity) / float64(line.Quantity)
+			totals.add(salesTotalsKeyOf(query, saleReturn.CreatedAt, line), sale.ID, line.ID, returned.Quantity, revenue)
+		}
+	}
+	return totals.list(), nil
+}
+
+func (r *memoryReturnRepository) Create(ctx context.Context, saleReturn *models.SaleReturn) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	r.data.stampCreated("sale_returns", &saleReturn.Model)
+	for i := range saleReturn.Lines {
+		r.data.stampCreated("sale_return_lines", &saleReturn.Lines[i].Model)
+		saleReturn.Lines[i].ReturnID = saleReturn.ID
+	}
+	r.data.returns[saleReturn.ID] = copyReturn(*saleReturn)
+	return nil
+}
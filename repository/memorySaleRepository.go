@@ -0,0 +1,456 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"productmanagerapi/models"
+	"slices"
+	"sort"
+	"time"
+)
+
+type memorySaleRepository struct {
+	data *memoryData
+}
+
+// copySale detaches the slices so callers cannot mutate stored state.
+func copySale(sale models.Sale) models.Sale {
+	sale.Products = append([]models.SaleProduct(nil), sale.Products...)
+	for i := range sale.Products {
+		sale.Products[i].Taxes = append([]models.SaleLineTax(nil), sale.Products[i].Taxes...)
+	}
+	sale.Taxes = append([]models.SaleTax(nil), sale.Taxes...)
+	sale.Discounts = append([]models.SaleDiscount(nil), sale.Discounts...)
+	sale.Amendments = append([]models.SaleAmendment(nil), sale.Amendments...)
+	return sale
+}
+
+func (r *memorySaleRepository) FindAll(ctx context.Context, filter SaleFilter) ([]models.Sale, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	sales := []models.Sale{}
+	for _, sale := range sortedByID(r.data.sales) {
+		if !matchesSaleFilter(sale, filter) {
+			continue
+		}
+		sale = copySale(sale)
+		sale.Returns = r.data.returnsOf(sale.ID)
+		sale.Payments = r.data.paymentsOf(sale.ID)
+		sales = append(sales, sale)
+	}
+	return sales, nil
+}
+
+func (r *memorySaleRepository) FindPage(ctx context.Context, filter SaleFilter, page SalePage) ([]models.Sale, int64, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	var matching []models.Sale
+	for _, sale := range sortedByID(r.data.sales) {
+		if matchesSaleFilter(sale, filter) {
+			matching = append(matching, sale)
+		}
+	}
+
+	column := saleSortColumn(page.Sort)
+	// less orders a before b in the ascending order of the page.
+	less := func(a, b models.Sale) bool {
+		switch column {
+		case SaleSortCreatedAt:
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+		case SaleSortTotal:
+			if a.Total != b.Total {
+				return a.Total < b.Total
+			}
+		}
+		return a.ID < b.ID
+	}
+	if page.Desc {
+		ascending := less
+		less = func(a, b models.Sale) bool { return ascending(b, a) }
+	}
+	sort.SliceStable(matching, func(i, j int) bool { return less(matching[i], matching[j]) })
+
+	sales := []models.Sale{}
+	for _, sale := range matching {
+		if page.After != nil && !less(*page.After, sale) {
+			continue
+		}
+		if len(sales) == page.Limit {
+			break
+		}
+		sale = copySale(sale)
+		sale.Returns = r.data.returnsOf(sale.ID)
+		sale.Payments = r.data.paymentsOf(sale.ID)
+		sales = append(sales, sale)
+	}
+	return sales, int64(len(matching)), nil
+}
+
+func matchesSaleFilter(sale models.Sale, filter SaleFilter) bool {
+	switch {
+	case filter.CreatedByID != 0 && sale.CreatedByID != filter.CreatedByID:
+		return false
+	case !filter.CreatedFrom.IsZero() && sale.CreatedAt.Before(filter.CreatedFrom):
+		return false
+	case !filter.CreatedBefore.IsZero() && !sale.CreatedAt.Before(filter.CreatedBefore):
+		return false
+	case !filter.PaidFrom.IsZero() && (sale.PaidAt == nil || sale.PaidAt.Before(filter.PaidFrom)):
+		return false
+	case !filter.PaidBefore.IsZero() && (sale.PaidAt == nil || !sale.PaidAt.Before(filter.PaidBefore)):
+		return false
+	case filter.MinTotal != nil && sale.Total < *filter.MinTotal:
+		return false
+	case filter.MaxTotal != nil && sale.Total > *filter.MaxTotal:
+		return false
+	case len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, sale.Status):
+		return false
+	}
+
+	if filter.ProductID != 0 {
+		return slices.ContainsFunc(sale.Products, func(line models.SaleProduct) bool {
+			return line.ProductID == filter.ProductID
+		})
+	}
+	return true
+}
+
+func (r *memorySaleRepository) FindByID(ctx context.Context, id uint) (models.Sale, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	sale, ok := r.data.sales[id]
+	if !ok {
+		return models.Sale{}, ErrNotFound
+	}
+	sale = copySale(sale)
+	sale.Returns = r.data.returnsOf(id)
+	sale.Payments = r.data.paymentsOf(id)
+	return sale, nil
+}
+
+func (r *memorySaleRepository) FindByCustomer(ctx context.Context, customerID uint) ([]models.Sale, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	sales := []models.Sale{}
+	for _, sale := range sortedByID(r.data.sales) {
+		if sale.CustomerID == nil || *sale.CustomerID != customerID {
+			continue
+		}
+		sale = copySale(sale)
+		sale.Returns = r.data.returnsOf(sale.ID)
+		sale.Payments = r.data.paymentsOf(sale.ID)
+		sales = append(sales, sale)
+	}
+	return sales, nil
+}
+
+func (r *memorySaleRepository) Create(ctx context.Context, sale *models.Sale) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	r.data.stampCreated("sales", &sale.Model)
+	for i := range sale.Products {
+		r.data.stampCreated("sale_products", &sale.Products[i].Model)
+		sale.Products[i].SaleID = sale.ID
+	}
+	for i := range sale.Discounts {
+		r.data.stampCreated("sale_discounts", &sale.Discounts[i].Model)
+		sale.Discounts[i].SaleID = sale.ID
+	}
+	r.data.sales[sale.ID] = copySale(*sale)
+	return nil
+}
+
+// FindByIDForUpdate needs no row lock: memory transactions are serialized.
+func (r *memorySaleRepository) FindByIDForUpdate(ctx context.Context, id uint) (models.Sale, error) {
+	return r.FindByID(ctx, id)
+}
+
+func (r *memorySaleRepository) Update(ctx context.Context, sale *models.Sale) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	existing, ok := r.data.sales[sale.ID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	updated := copySale(*sale)
+	updated.CreatedAt = existing.CreatedAt
+	updated.UpdatedAt = time.Now()
+	updated.Products = existing.Products
+	updated.Discounts = existing.Discounts
+	updated.Taxes = existing.Taxes
+	updated.Amendments = existing.Amendments
+	updated.Returns = nil
+	updated.Payments = nil
+	sale.CreatedAt = updated.CreatedAt
+	sale.UpdatedAt = updated.UpdatedAt
+	r.data.sales[sale.ID] = updated
+	return nil
+}
+
+func (r *memorySaleRepository) Delete(ctx context.Context, id uint) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if _, ok := r.data.sales[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.data.sales, id)
+	return nil
+}
+
+func (r *memorySaleRepository) SaveLine(ctx context.Context, line *models.SaleProduct) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	sale, ok := r.data.sales[line.SaleID]
+	if !ok {
+		return ErrNotFound
+	}
+	sale = copySale(sale)
+
+	if line.ID == 0 {
+		r.data.stampCreated("sale_products", &line.Model)
+		sale.Products = append(sale.Products, *line)
+		r.data.sales[sale.ID] = sale
+		return nil
+	}
+
+	for i, existing := range sale.Products {
+		if existing.ID == line.ID {
+			line.CreatedAt = existing.CreatedAt
+			line.UpdatedAt = time.Now()
+			sale.Products[i] = *line
+			r.data.sales[sale.ID] = sale
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *memorySaleRepository) DeleteLine(ctx context.Context, saleID, lineID uint) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	sale, ok := r.data.sales[saleID]
+	if !ok {
+		return ErrNotFound
+	}
+	sale = copySale(sale)
+
+	for i, existing := range sale.Products {
+		if existing.ID == lineID {
+			sale.Products = append(sale.Products[:i], sale.Products[i+1:]...)
+			r.data.sales[saleID] = sale
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *memorySaleRepository) AddAmendments(ctx context.Context, amendments []models.SaleAmendment) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for _, amendment := range amendments {
+		sale, ok := r.data.sales[amendment.SaleID]
+		if !ok {
+			return ErrNotFound
+		}
+		sale = copySale(sale)
+
+		r.data.stampCreated("sale_amendments", &amendment.Model)
+		sale.Amendments = append(sale.Amendments, amendment)
+		r.data.sales[sale.ID] = sale
+	}
+	return nil
+}
+
+func (r *memorySaleRepository) SaveDiscount(ctx context.Context, discount *models.SaleDiscount) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	sale, ok := r.data.sales[discount.SaleID]
+	if !ok {
+		return ErrNotFound
+	}
+	sale = copySale(sale)
+
+	if discount.ID == 0 {
+		r.data.stampCreated("sale_discounts", &discount.Model)
+		sale.Discounts = append(sale.Discounts, *discount)
+		r.data.sales[sale.ID] = sale
+		return nil
+	}
+
+	for i, existing := range sale.Discounts {
+		if existing.ID == discount.ID {
+			discount.CreatedAt = existing.CreatedAt
+			discount.UpdatedAt = time.Now()
+			sale.Discounts[i] = *discount
+			r.data.sales[sale.ID] = sale
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *memorySaleRepository) DeleteDiscount(ctx context.Context, saleID, discountID uint) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	sale, ok := r.data.sales[saleID]
+	if !ok {
+		return ErrNotFound
+	}
+	sale = copySale(sale)
+
+	for i, existing := range sale.Discounts {
+		if existing.ID == discountID {
+			sale.Discounts = append(sale.Discounts[:i], sale.Discounts[i+1:]...)
+			r.data.sales[saleID] = sale
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *memorySaleRepository) SaveTaxes(ctx context.Context, sale *models.Sale) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	stored, ok := r.data.sales[sale.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	stored = copySale(stored)
+
+	lines := map[uint][]models.SaleLineTax{}
+	for i := range sale.Products {
+		for j := range sale.Products[i].Taxes {
+			tax := &sale.Products[i].Taxes[j]
+			tax.ID = 0
+			tax.SaleProductID = sale.Products[i].ID
+			r.data.stampCreated("sale_line_taxes", &tax.Model)
+		}
+		lines[sale.Products[i].ID] = append([]models.SaleLineTax(nil), sale.Products[i].Taxes...)
+	}
+	for i := range stored.Products {
+		stored.Products[i].Taxes = lines[stored.Products[i].ID]
+	}
+
+	for i := range sale.Taxes {
+		sale.Taxes[i].ID = 0
+		sale.Taxes[i].SaleID = sale.ID
+		r.data.stampCreated("sale_taxes", &sale.Taxes[i].Model)
+	}
+	stored.Taxes = append([]models.SaleTax(nil), sale.Taxes...)
+
+	r.data.sales[sale.ID] = stored
+	return nil
+}
+
+func (r *memorySaleRepository) FindLines(ctx context.Context, ids []uint) ([]models.SaleProduct, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	lines := []models.SaleProduct{}
+	for _, sale := range r.data.sales {
+		for _, line := range sale.Products {
+			if slices.Contains(ids, line.ID) {
+				line.Taxes = nil
+				lines = append(lines, line)
+			}
+		}
+	}
+	slices.SortFunc(lines, func(a, b models.SaleProduct) int { return cmp.Compare(a.ID, b.ID) })
+	return lines, nil
+}
+
+func (r *memorySaleRepository) SumSold(ctx context.Context, query SalesTotalsQuery) ([]SalesTotal, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	totals := salesTotals{}
+	for _, sale := range r.data.sales {
+		if sale.Status == models.SaleCancelled || sale.PaidAt == nil || sale.PaidAt.Before(query.From) || !sale.PaidAt.Before(query.To) {
+			continue
+		}
+		if len(sale.Products) == 0 && query.GroupBy != SalesByProduct && query.GroupBy != SalesByCategory {
+			totals.add(salesTotalsKeyOf(query, *sale.PaidAt, models.SaleProduct{}), sale.ID, 0, 0, 0)
+		}
+		for _, line := range sale.Products {
+			totals.add(salesTotalsKeyOf(query, *sale.PaidAt, line), sale.ID, line.ID, line.Quantity, memoryLineRevenue(line))
+		}
+	}
+	return totals.list(), nil
+}
+
+// memoryLineRevenue is the revenue of a sale line as SumSold counts it: its
+// taxable amount plus its inclusive taxes.
+func memoryLineRevenue(line models.SaleProduct) float64 {
+	revenue := line.TaxableAmount
+	for _, tax := range line.Taxes {
+		if tax.Inclusive {
+			revenue += tax.Amount
+		}
+	}
+	return revenue
+}
+
+// salesTotalsKeyOf is the group of a line at the given time in query.
+func salesTotalsKeyOf(query SalesTotalsQuery, at time.Time, line models.SaleProduct) uint {
+	switch query.GroupBy {
+	case SalesByProduct:
+		return line.ProductID
+	case SalesByCategory:
+		return line.CategoryID
+	case SalesByPeriod:
+		if len(query.Periods) == 0 {
+			return 0
+		}
+		i := sort.Search(len(query.Periods)-1, func(i int) bool { return at.Before(query.Periods[i]) })
+		return uint(i)
+	}
+	return 0
+}
+
+// salesTotals accumulates SalesTotals by key, like the GROUP BY of
+// sumSalesTotals.
+type salesTotals map[uint]*salesGroup
+
+type salesGroup struct {
+	total SalesTotal
+	sales map[uint]bool
+}
+
+func (t salesTotals) add(key, saleID, lineID uint, units int, revenue float64) {
+	group, ok := t[key]
+	if !ok {
+		group = &salesGroup{total: SalesTotal{Key: key}, sales: map[uint]bool{}}
+		t[key] = group
+	}
+	group.total.LastLineID = max(group.total.LastLineID, lineID)
+	group.total.Units += units
+	group.total.Revenue += revenue
+	if !group.sales[saleID] {
+		group.sales[saleID] = true
+		group.total.SaleCount++
+	}
+}
+
+func (t salesTotals) list() []SalesTotal {
+	totals := []SalesTotal{}
+	for _, group := range t {
+		totals = append(totals, group.total)
+	}
+	slices.SortFunc(totals, func(a, b SalesTotal) int { return cmp.Compare(a.Key, b.Key) })
+	return totals
+}
@@ -0,0 +1,15 @@
+package repository
+
+import "context"
+
+type memorySequenceRepository struct {
+	data *memoryData
+}
+
+func (r *memorySequenceRepository) Next(ctx context.Context, name string) (uint, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	r.data.sequences[name]++
+	return r.data.sequences[name], nil
+}
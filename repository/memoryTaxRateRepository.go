@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+	"time"
+)
+
+type memoryTaxRateRepository struct {
+	data *memoryData
+}
+
+func (r *memoryTaxRateRepository) FindAll(ctx context.Context) ([]models.TaxRate, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	return sortedByID(r.data.taxRates), nil
+}
+
+func (r *memoryTaxRateRepository) FindEnabled(ctx context.Context) ([]models.TaxRate, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	rates := []models.TaxRate{}
+	for _, rate := range sortedByID(r.data.taxRates) {
+		if !rate.Disabled {
+			rates = append(rates, rate)
+		}
+	}
+	return rates, nil
+}
+
+func (r *memoryTaxRateRepository) FindByID(ctx context.Context, id uint) (models.TaxRate, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	rate, ok := r.data.taxRates[id]
+	if !ok {
+		return models.TaxRate{}, ErrNotFound
+	}
+	return rate, nil
+}
+
+func (r *memoryTaxRateRepository) Create(ctx context.Context, rate *models.TaxRate) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	r.data.stampCreated("tax_rates", &rate.Model)
+	r.data.taxRates[rate.ID] = *rate
+	return nil
+}
+
+func (r *memoryTaxRateRepository) Update(ctx context.Context, rate *models.TaxRate) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	existing, ok := r.data.taxRates[rate.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	rate.CreatedAt = existing.CreatedAt
+	rate.CreatedByID = existing.CreatedByID
+	rate.UpdatedAt = time.Now()
+	r.data.taxRates[rate.ID] = *rate
+	return nil
+}
+
+func (r *memoryTaxRateRepository) Delete(ctx context.Context, id uint) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	if _, ok := r.data.taxRates[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.data.taxRates, id)
+	return nil
+}
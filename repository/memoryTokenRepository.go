@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+	"time"
+)
+
+type memoryTokenRepository struct {
+	data *memoryData
+}
+
+func (r *memoryTokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	r.data.stampCreated("refresh_tokens", &token.Model)
+	r.data.refreshTokens[token.ID] = *token
+	return nil
+}
+
+func (r *memoryTokenRepository) FindRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	for _, token := range r.data.refreshTokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return models.RefreshToken{}, ErrNotFound
+}
+
+func (r *memoryTokenRepository) ConsumeRefreshToken(ctx context.Context, id uint, at time.Time) (bool, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	token, ok := r.data.refreshTokens[id]
+	if !ok || token.UsedAt != nil || token.RevokedAt != nil {
+		return false, nil
+	}
+
+	token.UsedAt = &at
+	token.UpdatedAt = time.Now()
+	r.data.refreshTokens[id] = token
+	return true, nil
+}
+
+func (r *memoryTokenRepository) RevokeRefreshTokens(ctx context.Context, userID uint, familyID string, at time.Time) ([]models.RefreshToken, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	live := []models.RefreshToken{}
+	for _, token := range sortedByID(r.data.refreshTokens) {
+		if token.UserID != userID || (familyID != "" && token.FamilyID != familyID) {
+			continue
+		}
+
+		if token.AccessTokenExpiresAt.After(at) {
+			live = append(live, token)
+		}
+		if token.RevokedAt == nil {
+			token.RevokedAt = &at
+			token.UpdatedAt = time.Now()
+			r.data.refreshTokens[token.ID] = token
+		}
+	}
+	return live, nil
+}
+
+func (r *memoryTokenRepository) RevokeAccessTokens(ctx context.Context, tokens []models.RevokedToken) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for _, token := range tokens {
+		if _, ok := r.data.revokedTokens[token.TokenID]; ok {
+			continue
+		}
+		r.data.stampCreated("revoked_tokens", &token.Model)
+		r.data.revokedTokens[token.TokenID] = token
+	}
+	return nil
+}
+
+func (r *memoryTokenRepository) IsAccessTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	_, ok := r.data.revokedTokens[tokenID]
+	return ok, nil
+}
+
+func (r *memoryTokenRepository) DeleteExpired(ctx context.Context, before time.Time) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	for tokenID, token := range r.data.revokedTokens {
+		if token.ExpiresAt.Before(before) {
+			delete(r.data.revokedTokens, tokenID)
+		}
+	}
+	for id, token := range r.data.refreshTokens {
+		if token.ExpiresAt.Before(before) {
+			delete(r.data.refreshTokens, id)
+		}
+	}
+	return nil
+}
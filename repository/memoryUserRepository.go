@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+	"time"
+)
+
+type memoryUserRepository struct {
+	data *memoryData
+}
+
+func (r *memoryUserRepository) FindAll(ctx context.Context) ([]models.User, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	return sortedByID(r.data.users), nil
+}
+
+func (r *memoryUserRepository) FindByID(ctx context.Context, id uint) (models.User, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	user, ok := r.data.users[id]
+	if !ok {
+		return models.User{}, ErrNotFound
+	}
+	return user, nil
+}
+
+func (r *memoryUserRepository) FindByUsername(ctx context.Context, username string) (models.User, error) {
+	r.data.mu.RLock()
+	defer r.data.mu.RUnlock()
+
+	for _, user := range sortedByID(r.data.users) {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return models.User{}, ErrNotFound
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	r.data.stampCreated("users", &user.Model)
+	r.data.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUserRepository) Update(ctx context.Context, user *models.User) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+
+	existing, ok := r.data.users[user.ID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	user.CreatedAt = existing.CreatedAt
+	user.UpdatedAt = time.Now()
+	r.data.users[user.ID] = *user
+	return nil
+}
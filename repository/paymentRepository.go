@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PaymentRepository interface {
+	FindBySale(ctx context.Context, saleID uint) ([]models.Payment, error)
+	Create(ctx context.Context, payments []models.Payment) error
+	// MarkRefunded records that the payments ids were given back at at.
+	MarkRefunded(ctx context.Context, ids []uint, at time.Time) error
+}
+
+type gormPaymentRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormPaymentRepository) FindBySale(ctx context.Context, saleID uint) ([]models.Payment, error) {
+	payments := []models.Payment{}
+	if err := r.db.WithContext(ctx).Where("sale_id = ?", saleID).Order("id").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+func (r *gormPaymentRepository) Create(ctx context.Context, payments []models.Payment) error {
+	if len(payments) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&payments).Error
+}
+
+func (r *gormPaymentRepository) MarkRefunded(ctx context.Context, ids []uint, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&models.Payment{}).Where("id IN ?", ids).Update("refunded_at", at).Error
+}
@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProductFilter narrows FindAll and FindPage; zero fields match every
+// product.
+type ProductFilter struct {
+	CreatedByID uint
+	CategoryID  uint
+	MinPrice    *float64
+	MaxPrice    *float64
+	// InStock keeps the products with stock left once reservations are
+	// taken off.
+	InStock bool
+	// NameContains keeps the products whose name contains it, ignoring
+	// case.
+	NameContains string
+	CreatedFrom  time.Time
+}
+
+// Fields FindPage sorts products on.
+const (
+	ProductSortID         = "id"
+	ProductSortName       = "name"
+	ProductSortPrice      = "price"
+	ProductSortStock      = "stock"
+	ProductSortCategoryID = "category_id"
+	ProductSortCreatedAt  = "created_at"
+)
+
+// ProductSort orders products on Field, one of the ProductSort constants,
+// descending when Desc is set.
+type ProductSort struct {
+	Field string
+	Desc  bool
+}
+
+// ProductPage selects a page of FindPage: Limit products ordered by Sorts
+// in turn, then by ID, skipping the first Offset or starting after the
+// product After when it is set. Only the ID and the sorted fields of After
+// are used.
+type ProductPage struct {
+	Sorts  []ProductSort
+	After  *models.Product
+	Offset int
+	Limit  int
+}
+
+type ProductRepository interface {
+	// FindAll and FindByID return products with their Category loaded.
+	FindAll(ctx context.Context, filter ProductFilter) ([]models.Product, error)
+	// FindPage returns a page of the products matching filter, with their
+	// Category, and how many products match filter in all pages.
+	FindPage(ctx context.Context, filter ProductFilter, page ProductPage) ([]models.Product, int64, error)
+	FindByID(ctx context.Context, id uint) (models.Product, error)
+	// FindByIDForUpdate loads a product and locks its row until the end of
+	// the surrounding transaction. The Category is not loaded.
+	FindByIDForUpdate(ctx context.Context, id uint) (models.Product, error)
+	Create(ctx context.Context, product *models.Product) error
+	Update(ctx context.Context, product *models.Product) error
+	Delete(ctx context.Context, id uint) error
+	// AdjustStock adds delta, which may be negative, to the product's stock.
+	AdjustStock(ctx context.Context, id uint, delta int) error
+	// AdjustReserved adds delta to the product's reserved quantity. Update
+	// never changes it.
+	AdjustReserved(ctx context.Context, id uint, delta int) error
+}
+
+type gormProductRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormProductRepository) FindAll(ctx context.Context, filter ProductFilter) ([]models.Product, error) {
+	products := []models.Product{}
+	if err := r.db.WithContext(ctx).Preload("Category").Scopes(filterProducts(filter)).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (r *gormProductRepository) FindPage(ctx context.Context, filter ProductFilter, page ProductPage) ([]models.Product, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&models.Product{}).Scopes(filterProducts(filter)).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	sorts := withIDSort(page.Sorts)
+	db := r.db.WithContext(ctx).Preload("Category").Scopes(filterProducts(filter))
+	if page.After != nil {
+		// Products after After differ from it on a sorted field and equal
+		// it on every field sorted before that one.
+		var (
+			conditions []string
+			args       []any
+			equal      string
+			equalArgs  []any
+		)
+		for _, sort := range sorts {
+			column, value := productSortColumn(sort.Field), productSortValue(sort.Field, *page.After)
+			operator := ">"
+			if sort.Desc {
+				operator = "<"
+			}
+			conditions = append(conditions, "("+equal+column+" "+operator+" ?)")
+			args = append(append(args, equalArgs...), value)
+			equal += column + " = ? AND "
+			equalArgs = append(equalArgs, value)
+		}
+		db = db.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+	for _, sort := range sorts {
+		direction := "ASC"
+		if sort.Desc {
+			direction = "DESC"
+		}
+		db = db.Order(productSortColumn(sort.Field) + " " + direction)
+	}
+
+	products := []models.Product{}
+	if err := db.Offset(page.Offset).Limit(page.Limit).Find(&products).Error; err != nil {
+		return nil, 0, err
+	}
+	return products, total, nil
+}
+
+func filterProducts(filter ProductFilter) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.CreatedByID != 0 {
+			db = db.Where("created_by_id = ?", filter.CreatedByID)
+		}
+		if filter.CategoryID != 0 {
+			db = db.Where("category_id = ?", filter.CategoryID)
+		}
+		if filter.MinPrice != nil {
+			db = db.Where("price >= ?", *filter.MinPrice)
+		}
+		if filter.MaxPrice != nil {
+			db = db.Where("price <= ?", *filter.MaxPrice)
+		}
+		if filter.InStock {
+			db = db.Where("stock > reserved")
+		}
+		if filter.NameContains != "" {
+			pattern := "%" + likeEscaper.Replace(strings.ToLower(filter.NameContains)) + "%"
+			db = db.Where(`LOWER(name) LIKE ? ESCAPE '\'`, pattern)
+		}
+		if !filter.CreatedFrom.IsZero() {
+			db = db.Where("created_at >= ?", filter.CreatedFrom)
+		}
+		return db
+	}
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// withIDSort appends the ID to sorts, unless it is already sorted on, so
+// that products with the same values keep a stable order.
+func withIDSort(sorts []ProductSort) []ProductSort {
+	for _, sort := range sorts {
+		if sort.Field == ProductSortID {
+			return sorts
+		}
+	}
+	return append(sorts[:len(sorts):len(sorts)], ProductSort{Field: ProductSortID})
+}
+
+// productSortColumn maps a ProductSort field to its column, defaulting to
+// id.
+func productSortColumn(field string) string {
+	switch field {
+	case ProductSortName, ProductSortPrice, ProductSortStock, ProductSortCategoryID, ProductSortCreatedAt:
+		return field
+	}
+	return ProductSortID
+}
+
+func productSortValue(field string, product models.Product) any {
+	switch field {
+	case ProductSortName:
+		return product.Name
+	case ProductSortPrice:
+		return product.Price
+	case ProductSortStock:
+		return product.Stock
+	case ProductSortCategoryID:
+		return product.CategoryID
+	case ProductSortCreatedAt:
+		return product.CreatedAt
+	}
+	return product.ID
+}
+
+func (r *gormProductRepository) FindByID(ctx context.Context, id uint) (models.Product, error) {
+	var product models.Product
+	if err := r.db.WithContext(ctx).Preload("Category").First(&product, id).Error; err != nil {
+		return models.Product{}, translateError(err)
+	}
+	return product, nil
+}
+
+func (r *gormProductRepository) FindByIDForUpdate(ctx context.Context, id uint) (models.Product, error) {
+	var product models.Product
+	if err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, id).Error; err != nil {
+		return models.Product{}, translateError(err)
+	}
+	return product, nil
+}
+
+func (r *gormProductRepository) Create(ctx context.Context, product *models.Product) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(product).Error
+}
+
+func (r *gormProductRepository) Update(ctx context.Context, product *models.Product) error {
+	result := r.db.WithContext(ctx).Model(product).Select("*").Omit("created_at", "reserved", clause.Associations).Updates(product)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormProductRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.Product{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormProductRepository) AdjustStock(ctx context.Context, id uint, delta int) error {
+	result := r.db.WithContext(ctx).Model(&models.Product{}).Where("id = ?", id).
+		Update("stock", gorm.Expr("stock + ?", delta))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormProductRepository) AdjustReserved(ctx context.Context, id uint, delta int) error {
+	result := r.db.WithContext(ctx).Model(&models.Product{}).Where("id = ?", id).
+		Update("reserved", gorm.Expr("reserved + ?", delta))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
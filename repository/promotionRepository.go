@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PromotionRepository interface {
+	FindAll(ctx context.Context) ([]models.Promotion, error)
+	FindByID(ctx context.Context, id uint) (models.Promotion, error)
+	// FindActive returns the promotions that are enabled and within their
+	// dates at the given time.
+	FindActive(ctx context.Context, at time.Time) ([]models.Promotion, error)
+	Create(ctx context.Context, promotion *models.Promotion) error
+	Update(ctx context.Context, promotion *models.Promotion) error
+	Delete(ctx context.Context, id uint) error
+}
+
+type gormPromotionRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormPromotionRepository) FindAll(ctx context.Context) ([]models.Promotion, error) {
+	promotions := []models.Promotion{}
+	if err := r.db.WithContext(ctx).Order("id").Find(&promotions).Error; err != nil {
+		return nil, err
+	}
+	return promotions, nil
+}
+
+func (r *gormPromotionRepository) FindByID(ctx context.Context, id uint) (models.Promotion, error) {
+	var promotion models.Promotion
+	if err := r.db.WithContext(ctx).First(&promotion, id).Error; err != nil {
+		return models.Promotion{}, translateError(err)
+	}
+	return promotion, nil
+}
+
+func (r *gormPromotionRepository) FindActive(ctx context.Context, at time.Time) ([]models.Promotion, error) {
+	promotions := []models.Promotion{}
+	err := r.db.WithContext(ctx).
+		Where("disabled = ?", false).
+		Where("starts_at IS NULL OR starts_at <= ?", at).
+		Where("ends_at IS NULL OR ends_at > ?", at).
+		Order("id").Find(&promotions).Error
+	if err != nil {
+		return nil, err
+	}
+	return promotions, nil
+}
+
+func (r *gormPromotionRepository) Create(ctx context.Context, promotion *models.Promotion) error {
+	return r.db.WithContext(ctx).Create(promotion).Error
+}
+
+func (r *gormPromotionRepository) Update(ctx context.Context, promotion *models.Promotion) error {
+	result := r.db.WithContext(ctx).Model(promotion).Select("*").Omit("created_at", "created_by_id").Updates(promotion)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormPromotionRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.Promotion{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
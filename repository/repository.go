@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned by every repository when the requested record does
+// not exist. Its message matches gorm.ErrRecordNotFound so existing error
+// handling keeps working.
+var ErrNotFound = errors.New("record not found")
+
+// Store groups the repositories the services depend on.
+type Store struct {
+	Categories CategoryRepository
+	Products   ProductRepository
+	Customers  CustomerRepository
+	Sales      SaleRepository
+	Returns    ReturnRepository
+	Payments   PaymentRepository
+	Promotions PromotionRepository
+	Coupons    CouponRepository
+	TaxRates   TaxRateRepository
+	Sequences  SequenceRepository
+	Users      UserRepository
+	Tokens     TokenRepository
+
+	IdempotencyKeys IdempotencyRepository
+
+	transaction func(ctx context.Context, fn func(tx *Store) error) error
+}
+
+// Transaction runs fn with repositories bound to a single transaction. It
+// commits when fn returns nil and rolls everything back otherwise. Calling
+// Transaction on tx nests within the same transaction.
+func (s *Store) Transaction(ctx context.Context, fn func(tx *Store) error) error {
+	return s.transaction(ctx, fn)
+}
+
+// NewGormStore returns repositories backed by db.
+func NewGormStore(db *gorm.DB) *Store {
+	return &Store{
+		Categories: &gormCategoryRepository{db: db},
+		Products:   &gormProductRepository{db: db},
+		Customers:  &gormCustomerRepository{db: db},
+		Sales:      &gormSaleRepository{db: db},
+		Returns:    &gormReturnRepository{db: db},
+		Payments:   &gormPaymentRepository{db: db},
+		Promotions: &gormPromotionRepository{db: db},
+		Coupons:    &gormCouponRepository{db: db},
+		TaxRates:   &gormTaxRateRepository{db: db},
+		Sequences:  &gormSequenceRepository{db: db},
+		Users:      &gormUserRepository{db: db},
+		Tokens:     &gormTokenRepository{db: db},
+
+		IdempotencyKeys: &gormIdempotencyRepository{db: db},
+
+		transaction: func(ctx context.Context, fn func(tx *Store) error) error {
+			return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				return fn(NewGormStore(tx))
+			})
+		},
+	}
+}
+
+func translateError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReturnRepository interface {
+	// FindBySale, FindCreatedBetween and FindByID return returns with their
+	// lines loaded.
+	FindBySale(ctx context.Context, saleID uint) ([]models.SaleReturn, error)
+	// FindCreatedBetween lists the returns made in [from, before).
+	FindCreatedBetween(ctx context.Context, from, before time.Time) ([]models.SaleReturn, error)
+	FindByID(ctx context.Context, id uint) (models.SaleReturn, error)
+	// SumReturned adds up the items returned in the range of query,
+	// whenever their sale was paid. SaleCount counts the sales the items
+	// came from.
+	SumReturned(ctx context.Context, query SalesTotalsQuery) ([]SalesTotal, error)
+	// Create stores the return together with its lines.
+	Create(ctx context.Context, saleReturn *models.SaleReturn) error
+}
+
+type gormReturnRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormReturnRepository) FindBySale(ctx context.Context, saleID uint) ([]models.SaleReturn, error) {
+	returns := []models.SaleReturn{}
+	if err := r.db.WithContext(ctx).Preload("Lines").Where("sale_id = ?", saleID).Order("id").Find(&returns).Error; err != nil {
+		return nil, err
+	}
+	return returns, nil
+}
+
+func (r *gormReturnRepository) FindCreatedBetween(ctx context.Context, from, before time.Time) ([]models.SaleReturn, error) {
+	returns := []models.SaleReturn{}
+	err := r.db.WithContext(ctx).Preload("Lines").
+		Where("created_at >= ? AND created_at < ?", from, before).
+		Order("id").Find(&returns).Error
+	if err != nil {
+		return nil, err
+	}
+	return returns, nil
+}
+
+func (r *gormReturnRepository) FindByID(ctx context.Context, id uint) (models.SaleReturn, error) {
+	var saleReturn models.SaleReturn
+	if err := r.db.WithContext(ctx).Preload("Lines").First(&saleReturn, id).Error; err != nil {
+		return models.SaleReturn{}, translateError(err)
+	}
+	return saleReturn, nil
+}
+
+func (r *gormReturnRepository) SumReturned(ctx context.Context, query SalesTotalsQuery) ([]SalesTotal, error) {
+	db := r.db.WithContext(ctx)
+	key, keyArgs := salesTotalsKey(query, "sr.created_at")
+	lines := db.Table("sale_return_lines rl").
+		Select("sr.sale_id AS sale_id, sp.id AS line_id, rl.quantity AS units, ("+lineRevenueSQL+") * rl.quantity / sp.quantity AS revenue, "+key+" AS group_key", append([]any{true}, keyArgs...)...).
+		Joins("JOIN sale_returns sr ON sr.id = rl.return_id AND sr.deleted_at IS NULL").
+		Joins("JOIN sale_products sp ON sp.id = rl.sale_product_id").
+		Where("rl.deleted_at IS NULL AND sp.quantity > 0 AND sr.created_at >= ? AND sr.created_at < ?", query.From, query.To)
+	return sumSalesTotals(db, lines)
+}
+
+func (r *gormReturnRepository) Create(ctx context.Context, saleReturn *models.SaleReturn) error {
+	return r.db.WithContext(ctx).Create(saleReturn).Error
+}
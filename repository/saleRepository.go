@@ -0,0 +1,445 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"productmanagerapi/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaleFilter narrows FindAll and FindPage; zero fields match every sale.
+type SaleFilter struct {
+	CreatedByID uint
+	// CreatedFrom and CreatedBefore keep the sales created in
+	// [CreatedFrom, CreatedBefore).
+	CreatedFrom   time.Time
+	CreatedBefore time.Time
+	// PaidFrom and PaidBefore keep the sales paid in [PaidFrom, PaidBefore).
+	PaidFrom   time.Time
+	PaidBefore time.Time
+	// ProductID keeps the sales with a line of the product.
+	ProductID uint
+	MinTotal  *float64
+	MaxTotal  *float64
+	Statuses  []string
+}
+
+// Orders of FindPage. Sales with the same value are ordered by ID.
+const (
+	SaleSortCreatedAt = "created_at"
+	SaleSortTotal     = "total"
+	SaleSortID        = "id"
+)
+
+// SalePage selects a page of FindPage: Limit sales in the Sort order,
+// descending when Desc is set, starting after the sale After when it is
+// set. Only the ID and the sorted field of After are used.
+type SalePage struct {
+	Sort  string
+	Desc  bool
+	After *models.Sale
+	Limit int
+}
+
+// Groupings of SumSold and SumReturned.
+const (
+	SalesByPeriod   = "period"
+	SalesByProduct  = "product"
+	SalesByCategory = "category"
+)
+
+// SalesTotalsQuery selects what SumSold and SumReturned add up: the lines
+// of the sales paid, or the items returned, in [From, To), grouped by
+// GroupBy or all together when it is empty. SalesByPeriod splits the range
+// at Periods, the ends of consecutive periods, the last one at or after To.
+type SalesTotalsQuery struct {
+	From    time.Time
+	To      time.Time
+	GroupBy string
+	Periods []time.Time
+}
+
+// SalesTotal sums a group of sale lines or returned items. Key is the index
+// of the period in Periods, the product ID or the category ID, and zero
+// when nothing is grouped. LastLineID is the most recent sale line of the
+// group, to name it. Revenue is what the lines came to after every
+// discount, before exclusive taxes, in proportion for returned items.
+type SalesTotal struct {
+	Key        uint `gorm:"column:group_key"`
+	LastLineID uint
+	SaleCount  int
+	Units      int
+	Revenue    float64
+}
+
+type SaleRepository interface {
+	// FindAll and FindByID return sales with their lines, discounts, taxes,
+	// amendments, returns and payments loaded.
+	FindAll(ctx context.Context, filter SaleFilter) ([]models.Sale, error)
+	// FindPage returns a page of the sales matching filter, with details as
+	// FindAll, and how many sales match filter in all pages.
+	FindPage(ctx context.Context, filter SaleFilter, page SalePage) ([]models.Sale, int64, error)
+	FindByID(ctx context.Context, id uint) (models.Sale, error)
+	FindByCustomer(ctx context.Context, customerID uint) ([]models.Sale, error)
+	// FindByIDForUpdate loads a sale with its lines, discounts, taxes and
+	// returns and locks its row until the end of the surrounding
+	// transaction.
+	FindByIDForUpdate(ctx context.Context, id uint) (models.Sale, error)
+	// FindLines returns the sale lines with the given IDs, without their
+	// taxes.
+	FindLines(ctx context.Context, ids []uint) ([]models.SaleProduct, error)
+	// SumSold adds up the lines of the sales paid in the range of query,
+	// except those cancelled since. SaleCount counts the sales of each
+	// group.
+	SumSold(ctx context.Context, query SalesTotalsQuery) ([]SalesTotal, error)
+	// Create stores the sale together with its lines.
+	Create(ctx context.Context, sale *models.Sale) error
+	// Update saves the sale's own columns; lines and amendments are left
+	// alone.
+	Update(ctx context.Context, sale *models.Sale) error
+	Delete(ctx context.Context, id uint) error
+
+	// SaveLine creates line when its ID is zero and updates it otherwise.
+	SaveLine(ctx context.Context, line *models.SaleProduct) error
+	DeleteLine(ctx context.Context, saleID, lineID uint) error
+	AddAmendments(ctx context.Context, amendments []models.SaleAmendment) error
+
+	// SaveDiscount creates discount when its ID is zero and updates it
+	// otherwise.
+	SaveDiscount(ctx context.Context, discount *models.SaleDiscount) error
+	DeleteDiscount(ctx context.Context, saleID, discountID uint) error
+
+	// SaveTaxes replaces the taxes of the sale and of its lines with
+	// sale.Taxes and the Taxes of sale.Products.
+	SaveTaxes(ctx context.Context, sale *models.Sale) error
+}
+
+type gormSaleRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormSaleRepository) FindAll(ctx context.Context, filter SaleFilter) ([]models.Sale, error) {
+	sales := []models.Sale{}
+	if err := r.withDetails(ctx).Scopes(filterSales(filter)).Find(&sales).Error; err != nil {
+		return nil, err
+	}
+	return sales, nil
+}
+
+func (r *gormSaleRepository) FindPage(ctx context.Context, filter SaleFilter, page SalePage) ([]models.Sale, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&models.Sale{}).Scopes(filterSales(filter)).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	column := saleSortColumn(page.Sort)
+	direction, after := "ASC", ">"
+	if page.Desc {
+		direction, after = "DESC", "<"
+	}
+
+	db := r.withDetails(ctx).Scopes(filterSales(filter))
+	if page.After != nil {
+		if column == SaleSortID {
+			db = db.Where("id "+after+" ?", page.After.ID)
+		} else {
+			value := saleSortValue(column, *page.After)
+			db = db.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, after), value, value, page.After.ID)
+		}
+	}
+
+	sales := []models.Sale{}
+	if err := db.Order(column + " " + direction).Order("id " + direction).Limit(page.Limit).Find(&sales).Error; err != nil {
+		return nil, 0, err
+	}
+	return sales, total, nil
+}
+
+func filterSales(filter SaleFilter) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.CreatedByID != 0 {
+			db = db.Where("created_by_id = ?", filter.CreatedByID)
+		}
+		if !filter.CreatedFrom.IsZero() {
+			db = db.Where("created_at >= ?", filter.CreatedFrom)
+		}
+		if !filter.CreatedBefore.IsZero() {
+			db = db.Where("created_at < ?", filter.CreatedBefore)
+		}
+		if !filter.PaidFrom.IsZero() {
+			db = db.Where("paid_at >= ?", filter.PaidFrom)
+		}
+		if !filter.PaidBefore.IsZero() {
+			db = db.Where("paid_at < ?", filter.PaidBefore)
+		}
+		if filter.ProductID != 0 {
+			db = db.Where("id IN (?)", db.Session(&gorm.Session{NewDB: true}).Model(&models.SaleProduct{}).Select("sale_id").Where("product_id = ?", filter.ProductID))
+		}
+		if filter.MinTotal != nil {
+			db = db.Where("total >= ?", *filter.MinTotal)
+		}
+		if filter.MaxTotal != nil {
+			db = db.Where("total <= ?", *filter.MaxTotal)
+		}
+		if len(filter.Statuses) > 0 {
+			db = db.Where("status IN ?", filter.Statuses)
+		}
+		return db
+	}
+}
+
+// saleSortColumn maps a SaleSort constant to its column, defaulting to id.
+func saleSortColumn(sort string) string {
+	switch sort {
+	case SaleSortCreatedAt, SaleSortTotal:
+		return sort
+	}
+	return SaleSortID
+}
+
+func saleSortValue(column string, sale models.Sale) any {
+	switch column {
+	case SaleSortCreatedAt:
+		return sale.CreatedAt
+	case SaleSortTotal:
+		return sale.Total
+	}
+	return sale.ID
+}
+
+func (r *gormSaleRepository) FindByID(ctx context.Context, id uint) (models.Sale, error) {
+	var sale models.Sale
+	if err := r.withDetails(ctx).First(&sale, id).Error; err != nil {
+		return models.Sale{}, translateError(err)
+	}
+	return sale, nil
+}
+
+func (r *gormSaleRepository) FindLines(ctx context.Context, ids []uint) ([]models.SaleProduct, error) {
+	lines := []models.SaleProduct{}
+	if len(ids) == 0 {
+		return lines, nil
+	}
+	if err := r.db.WithContext(ctx).Unscoped().Where("id IN ?", ids).Order("id").Find(&lines).Error; err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+func (r *gormSaleRepository) SumSold(ctx context.Context, query SalesTotalsQuery) ([]SalesTotal, error) {
+	db := r.db.WithContext(ctx)
+	key, keyArgs := salesTotalsKey(query, "s.paid_at")
+	lines := db.Table("sales s").
+		Select("s.id AS sale_id, sp.id AS line_id, COALESCE(sp.quantity, 0) AS units, COALESCE("+lineRevenueSQL+", 0) AS revenue, "+key+" AS group_key", append([]any{true}, keyArgs...)...).
+		Joins("LEFT JOIN sale_products sp ON sp.sale_id = s.id AND sp.deleted_at IS NULL").
+		Where("s.deleted_at IS NULL AND s.status <> ? AND s.paid_at >= ? AND s.paid_at < ?", models.SaleCancelled, query.From, query.To)
+	if query.GroupBy == SalesByProduct || query.GroupBy == SalesByCategory {
+		lines = lines.Where("sp.id IS NOT NULL")
+	}
+	return sumSalesTotals(db, lines)
+}
+
+// lineRevenueSQL is the revenue of the sale line sp: its taxable amount
+// plus its inclusive taxes. It takes true as argument.
+const lineRevenueSQL = "sp.taxable_amount + (SELECT COALESCE(SUM(t.amount), 0) FROM sale_line_taxes t WHERE t.sale_product_id = sp.id AND t.inclusive = ? AND t.deleted_at IS NULL)"
+
+// salesTotalsKey returns the expression grouping the rows of a
+// SalesTotalsQuery, with its arguments. Periods are told apart with at.
+func salesTotalsKey(query SalesTotalsQuery, at string) (string, []any) {
+	switch query.GroupBy {
+	case SalesByProduct:
+		return "sp.product_id", nil
+	case SalesByCategory:
+		return "sp.category_id", nil
+	case SalesByPeriod:
+		if len(query.Periods) < 2 {
+			return "0", nil
+		}
+		var key strings.Builder
+		var args []any
+		key.WriteString("CASE")
+		for i, end := range query.Periods[:len(query.Periods)-1] {
+			fmt.Fprintf(&key, " WHEN %s < ? THEN %d", at, i)
+			args = append(args, end)
+		}
+		fmt.Fprintf(&key, " ELSE %d END", len(query.Periods)-1)
+		return key.String(), args
+	}
+	return "0", nil
+}
+
+// sumSalesTotals groups lines, rows of sale_id, line_id, units, revenue and
+// group_key, into SalesTotals.
+func sumSalesTotals(db *gorm.DB, lines *gorm.DB) ([]SalesTotal, error) {
+	totals := []SalesTotal{}
+	err := db.Table("(?) AS line_totals", lines).
+		Select("group_key, MAX(line_id) AS last_line_id, COUNT(DISTINCT sale_id) AS sale_count, SUM(units) AS units, SUM(revenue) AS revenue").
+		Group("group_key").Order("group_key").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+func (r *gormSaleRepository) FindByCustomer(ctx context.Context, customerID uint) ([]models.Sale, error) {
+	sales := []models.Sale{}
+	if err := r.withDetails(ctx).Where("customer_id = ?", customerID).Order("id").Find(&sales).Error; err != nil {
+		return nil, err
+	}
+	return sales, nil
+}
+
+func (r *gormSaleRepository) withDetails(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Preload("Products.Taxes", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Preload("Discounts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Preload("Taxes", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Preload("Amendments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Preload("Returns.Lines").Preload("Payments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	})
+}
+
+func (r *gormSaleRepository) FindByIDForUpdate(ctx context.Context, id uint) (models.Sale, error) {
+	var sale models.Sale
+	if err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Products.Taxes", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Preload("Discounts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Preload("Taxes", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Preload("Returns.Lines").First(&sale, id).Error; err != nil {
+		return models.Sale{}, translateError(err)
+	}
+	return sale, nil
+}
+
+func (r *gormSaleRepository) Create(ctx context.Context, sale *models.Sale) error {
+	return r.db.WithContext(ctx).Create(sale).Error
+}
+
+func (r *gormSaleRepository) Update(ctx context.Context, sale *models.Sale) error {
+	result := r.db.WithContext(ctx).Model(sale).Select("*").Omit("created_at", clause.Associations).Updates(sale)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormSaleRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.Sale{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormSaleRepository) SaveLine(ctx context.Context, line *models.SaleProduct) error {
+	if line.ID == 0 {
+		return r.db.WithContext(ctx).Create(line).Error
+	}
+
+	result := r.db.WithContext(ctx).Model(line).Select("*").Omit("created_at", clause.Associations).Updates(line)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormSaleRepository) DeleteLine(ctx context.Context, saleID, lineID uint) error {
+	result := r.db.WithContext(ctx).Where("sale_id = ?", saleID).Delete(&models.SaleProduct{}, lineID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormSaleRepository) AddAmendments(ctx context.Context, amendments []models.SaleAmendment) error {
+	if len(amendments) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&amendments).Error
+}
+
+func (r *gormSaleRepository) SaveDiscount(ctx context.Context, discount *models.SaleDiscount) error {
+	if discount.ID == 0 {
+		return r.db.WithContext(ctx).Create(discount).Error
+	}
+
+	result := r.db.WithContext(ctx).Model(discount).Select("*").Omit("created_at").Updates(discount)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormSaleRepository) DeleteDiscount(ctx context.Context, saleID, discountID uint) error {
+	result := r.db.WithContext(ctx).Where("sale_id = ?", saleID).Delete(&models.SaleDiscount{}, discountID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SaveTaxes should run in a transaction so the taxes are replaced at once.
+func (r *gormSaleRepository) SaveTaxes(ctx context.Context, sale *models.Sale) error {
+	db := r.db.WithContext(ctx)
+
+	lines := db.Unscoped().Model(&models.SaleProduct{}).Select("id").Where("sale_id = ?", sale.ID)
+	if err := db.Unscoped().Where("sale_product_id IN (?)", lines).Delete(&models.SaleLineTax{}).Error; err != nil {
+		return err
+	}
+	if err := db.Unscoped().Where("sale_id = ?", sale.ID).Delete(&models.SaleTax{}).Error; err != nil {
+		return err
+	}
+
+	var lineTaxes []*models.SaleLineTax
+	for i := range sale.Products {
+		for j := range sale.Products[i].Taxes {
+			tax := &sale.Products[i].Taxes[j]
+			tax.ID = 0
+			tax.SaleProductID = sale.Products[i].ID
+			lineTaxes = append(lineTaxes, tax)
+		}
+	}
+	for i := range sale.Taxes {
+		sale.Taxes[i].ID = 0
+		sale.Taxes[i].SaleID = sale.ID
+	}
+
+	if len(lineTaxes) > 0 {
+		if err := db.Create(lineTaxes).Error; err != nil {
+			return err
+		}
+	}
+	if len(sale.Taxes) > 0 {
+		return db.Create(&sale.Taxes).Error
+	}
+	return nil
+}
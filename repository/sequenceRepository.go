@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SequenceRepository interface {
+	// Next returns the number after the last one handed out by the sequence
+	// name, starting at 1. The sequence stays locked until the transaction
+	// ends and goes back when it rolls back, so numbers taken within
+	// Store.Transaction have no gaps.
+	Next(ctx context.Context, name string) (uint, error)
+}
+
+type gormSequenceRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormSequenceRepository) Next(ctx context.Context, name string) (uint, error) {
+	db := r.db.WithContext(ctx)
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Sequence{Name: name}).Error; err != nil {
+		return 0, err
+	}
+
+	// The update locks the row until the transaction ends.
+	if err := db.Model(&models.Sequence{}).Where("name = ?", name).Update("value", gorm.Expr("value + 1")).Error; err != nil {
+		return 0, err
+	}
+
+	var sequence models.Sequence
+	if err := db.Where("name = ?", name).First(&sequence).Error; err != nil {
+		return 0, translateError(err)
+	}
+	return sequence.Value, nil
+}
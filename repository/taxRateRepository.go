@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+
+	"gorm.io/gorm"
+)
+
+type TaxRateRepository interface {
+	// FindAll and FindEnabled return rates in ID order, the order they
+	// stack in.
+	FindAll(ctx context.Context) ([]models.TaxRate, error)
+	FindEnabled(ctx context.Context) ([]models.TaxRate, error)
+	FindByID(ctx context.Context, id uint) (models.TaxRate, error)
+	Create(ctx context.Context, rate *models.TaxRate) error
+	Update(ctx context.Context, rate *models.TaxRate) error
+	Delete(ctx context.Context, id uint) error
+}
+
+type gormTaxRateRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormTaxRateRepository) FindAll(ctx context.Context) ([]models.TaxRate, error) {
+	rates := []models.TaxRate{}
+	if err := r.db.WithContext(ctx).Order("id").Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (r *gormTaxRateRepository) FindEnabled(ctx context.Context) ([]models.TaxRate, error) {
+	rates := []models.TaxRate{}
+	if err := r.db.WithContext(ctx).Where("disabled = ?", false).Order("id").Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (r *gormTaxRateRepository) FindByID(ctx context.Context, id uint) (models.TaxRate, error) {
+	var rate models.TaxRate
+	if err := r.db.WithContext(ctx).First(&rate, id).Error; err != nil {
+		return models.TaxRate{}, translateError(err)
+	}
+	return rate, nil
+}
+
+func (r *gormTaxRateRepository) Create(ctx context.Context, rate *models.TaxRate) error {
+	return r.db.WithContext(ctx).Create(rate).Error
+}
+
+func (r *gormTaxRateRepository) Update(ctx context.Context, rate *models.TaxRate) error {
+	result := r.db.WithContext(ctx).Model(rate).Select("*").Omit("created_at", "created_by_id").Updates(rate)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormTaxRateRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.TaxRate{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TokenRepository stores refresh tokens and the access token revocation list.
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	FindRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error)
+	// ConsumeRefreshToken marks the token as used unless it was already used
+	// or revoked, and reports whether it did. It is atomic so two concurrent
+	// refreshes cannot both succeed.
+	ConsumeRefreshToken(ctx context.Context, id uint, at time.Time) (bool, error)
+	// RevokeRefreshTokens revokes the refresh tokens of userID, limited to
+	// familyID when it is set. It returns the matching tokens whose access
+	// token is still valid at the given time.
+	RevokeRefreshTokens(ctx context.Context, userID uint, familyID string, at time.Time) ([]models.RefreshToken, error)
+	RevokeAccessTokens(ctx context.Context, tokens []models.RevokedToken) error
+	IsAccessTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+	// DeleteExpired removes refresh tokens and revocation entries that
+	// expired before the given time.
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+type gormTokenRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormTokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *gormTokenRepository) FindRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return models.RefreshToken{}, translateError(err)
+	}
+	return token, nil
+}
+
+func (r *gormTokenRepository) ConsumeRefreshToken(ctx context.Context, id uint, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormTokenRepository) RevokeRefreshTokens(ctx context.Context, userID uint, familyID string, at time.Time) ([]models.RefreshToken, error) {
+	live := []models.RefreshToken{}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		scope := func() *gorm.DB {
+			query := tx.Model(&models.RefreshToken{}).Where("user_id = ?", userID)
+			if familyID != "" {
+				query = query.Where("family_id = ?", familyID)
+			}
+			return query
+		}
+
+		if err := scope().Where("access_token_expires_at > ?", at).Find(&live).Error; err != nil {
+			return err
+		}
+		return scope().Where("revoked_at IS NULL").Update("revoked_at", at).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return live, nil
+}
+
+func (r *gormTokenRepository) RevokeAccessTokens(ctx context.Context, tokens []models.RevokedToken) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "token_id"}}, DoNothing: true}).
+		Create(&tokens).Error
+}
+
+func (r *gormTokenRepository) IsAccessTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.RevokedToken{}).Where("token_id = ?", tokenID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *gormTokenRepository) DeleteExpired(ctx context.Context, before time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("expires_at < ?", before).Delete(&models.RevokedToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("expires_at < ?", before).Delete(&models.RefreshToken{}).Error
+	})
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"productmanagerapi/models"
+
+	"gorm.io/gorm"
+)
+
+type UserRepository interface {
+	FindAll(ctx context.Context) ([]models.User, error)
+	FindByID(ctx context.Context, id uint) (models.User, error)
+	FindByUsername(ctx context.Context, username string) (models.User, error)
+	Create(ctx context.Context, user *models.User) error
+	Update(ctx context.Context, user *models.User) error
+}
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormUserRepository) FindAll(ctx context.Context) ([]models.User, error) {
+	users := []models.User{}
+	if err := r.db.WithContext(ctx).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *gormUserRepository) FindByID(ctx context.Context, id uint) (models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return models.User{}, translateError(err)
+	}
+	return user, nil
+}
+
+func (r *gormUserRepository) FindByUsername(ctx context.Context, username string) (models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		return models.User{}, translateError(err)
+	}
+	return user, nil
+}
+
+func (r *gormUserRepository) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+func (r *gormUserRepository) Update(ctx context.Context, user *models.User) error {
+	result := r.db.WithContext(ctx).Model(user).Select("*").Omit("created_at").Updates(user)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
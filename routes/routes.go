@@ -2,30 +2,218 @@ package router
 
 import (
 	"net/http"
+	"productmanagerapi/auth"
 	controllers "productmanagerapi/controllers"
+	"productmanagerapi/utils"
+	"strings"
+	"time"
 
 	swaggerFiles "github.com/swaggo/files"
 )
 
-var Routes = map[string]func(http.ResponseWriter, *http.Request){
-	"/":                controllers.HomeController,
-	"/products":        controllers.GetAllProducts,
-	"/product":         controllers.GetProductByID,
-	"/create-product":  controllers.CreateProduct,
-	"/update-product":  controllers.UpdateProduct,
-	"/delete-product":  controllers.DeleteProduct,
-	"/categories":      controllers.GetAllCategories,
-	"/category":        controllers.GetCategoryByID,
-	"/create-category": controllers.CreateCategory,
-	"/update-category": controllers.UpdateCategory,
-	"/delete-category": controllers.DeleteCategory,
-	"/sales":           controllers.GetSales,
-	"/create-sale":     controllers.CreateSale,
-	// "/update-sale": controllers.,
-	"/delete-sale":   controllers.DeleteSale,
-	"/auth/login":    controllers.Login,
-	"/auth/register": controllers.Register,
-	"/swagger/*any":  swaggerFiles.NewHandler().ServeHTTP,
-	"/refresh-token": controllers.RefreshToken,
-	"/logout":        controllers.Logout,
+type Route struct {
+	// Pattern is a net/http ServeMux pattern such as "GET /products/{id}",
+	// relative to the version prefix. The mux answers 405 with an Allow
+	// header when only the method differs.
+	Pattern string
+	Handler http.HandlerFunc
+	// Public routes skip the authentication middleware.
+	Public bool
+	// Permission, when set, is required from the caller's role.
+	Permission auth.Permission
+	// Successor is set on legacy aliases to the route that replaces them.
+	Successor string
+}
+
+// Version is a set of routes served under a common prefix. Versions are
+// registered side by side, so a /api/v2 table can change payloads while
+// /api/v1 keeps serving existing clients.
+type Version struct {
+	Prefix string
+	Routes []Route
+	// Sunset, when set, marks every route of the version as deprecated and
+	// announces the date it will be removed.
+	Sunset time.Time
+}
+
+// Versions lists the API versions currently served. The last entry is the
+// one unversioned legacy paths redirect clients to.
+func Versions(c *controllers.Controllers) []Version {
+	return []Version{
+		{Prefix: "/api/v1", Routes: V1(c)},
+	}
+}
+
+func V1(c *controllers.Controllers) []Route {
+	return []Route{
+		{Pattern: "GET /products", Handler: c.Products.GetAllProducts, Permission: auth.ProductsRead},
+		{Pattern: "POST /products", Handler: c.Products.CreateProduct, Permission: auth.ProductsWrite},
+		{Pattern: "GET /products/{id}", Handler: c.Products.GetProductByID, Permission: auth.ProductsRead},
+		{Pattern: "PUT /products/{id}", Handler: c.Products.UpdateProduct, Permission: auth.ProductsWrite},
+		{Pattern: "PATCH /products/{id}", Handler: c.Products.PatchProduct, Permission: auth.ProductsWrite},
+		{Pattern: "DELETE /products/{id}", Handler: c.Products.DeleteProduct, Permission: auth.ProductsDelete},
+
+		{Pattern: "GET /categories", Handler: c.Categories.GetAllCategories, Permission: auth.CategoriesRead},
+		{Pattern: "POST /categories", Handler: c.Categories.CreateCategory, Permission: auth.CategoriesWrite},
+		{Pattern: "GET /categories/{id}", Handler: c.Categories.GetCategoryByID, Permission: auth.CategoriesRead},
+		{Pattern: "PUT /categories/{id}", Handler: c.Categories.UpdateCategory, Permission: auth.CategoriesWrite},
+		{Pattern: "PATCH /categories/{id}", Handler: c.Categories.PatchCategory, Permission: auth.CategoriesWrite},
+		{Pattern: "DELETE /categories/{id}", Handler: c.Categories.DeleteCategory, Permission: auth.CategoriesDelete},
+
+		{Pattern: "GET /customers", Handler: c.Customers.GetAllCustomers, Permission: auth.CustomersRead},
+		{Pattern: "POST /customers", Handler: c.Customers.CreateCustomer, Permission: auth.CustomersWrite},
+		{Pattern: "GET /customers/{id}", Handler: c.Customers.GetCustomerByID, Permission: auth.CustomersRead},
+		{Pattern: "PUT /customers/{id}", Handler: c.Customers.UpdateCustomer, Permission: auth.CustomersWrite},
+		{Pattern: "DELETE /customers/{id}", Handler: c.Customers.DeleteCustomer, Permission: auth.CustomersDelete},
+		{Pattern: "GET /customers/{id}/sales", Handler: c.Customers.GetPurchaseHistory, Permission: auth.CustomersRead},
+		{Pattern: "POST /customers/{id}/store-credit", Handler: c.Customers.AdjustStoreCredit, Permission: auth.SalesReturn},
+
+		{Pattern: "GET /sales", Handler: c.Sales.GetSales, Permission: auth.SalesRead},
+		{Pattern: "POST /sales", Handler: c.Sales.CreateSale, Permission: auth.SalesCreate},
+		{Pattern: "GET /sales/{id}", Handler: c.Sales.GetSaleByID, Permission: auth.SalesRead},
+		{Pattern: "PATCH /sales/{id}", Handler: c.Sales.AmendSale, Permission: auth.SalesAmend},
+		{Pattern: "DELETE /sales/{id}", Handler: c.Sales.DeleteSale, Permission: auth.SalesDelete},
+		{Pattern: "POST /sales/{id}/confirm", Handler: c.Sales.ConfirmSale, Permission: auth.SalesCreate},
+		{Pattern: "POST /sales/{id}/pay", Handler: c.Sales.PaySale, Permission: auth.SalesCreate},
+		{Pattern: "POST /sales/{id}/fulfill", Handler: c.Sales.FulfillSale, Permission: auth.SalesCreate},
+		{Pattern: "POST /sales/{id}/cancel", Handler: c.Sales.CancelSale, Permission: auth.SalesCreate},
+		{Pattern: "GET /sales/{id}/payments", Handler: c.Payments.GetSalePayments, Permission: auth.SalesRead},
+		{Pattern: "POST /sales/{id}/payments", Handler: c.Payments.CreatePayment, Permission: auth.SalesCreate},
+		{Pattern: "GET /sales/{id}/receipt", Handler: c.Receipts.GetReceipt, Permission: auth.SalesRead},
+		{Pattern: "GET /sales/{id}/returns", Handler: c.Returns.GetSaleReturns, Permission: auth.SalesRead},
+		{Pattern: "POST /sales/{id}/returns", Handler: c.Returns.CreateReturn, Permission: auth.SalesReturn},
+		{Pattern: "GET /returns/{id}", Handler: c.Returns.GetReturnByID, Permission: auth.SalesRead},
+
+		{Pattern: "GET /promotions", Handler: c.Promotions.GetAllPromotions, Permission: auth.SalesRead},
+		{Pattern: "POST /promotions", Handler: c.Promotions.CreatePromotion, Permission: auth.PromotionsManage},
+		{Pattern: "GET /promotions/{id}", Handler: c.Promotions.GetPromotionByID, Permission: auth.SalesRead},
+		{Pattern: "PUT /promotions/{id}", Handler: c.Promotions.UpdatePromotion, Permission: auth.PromotionsManage},
+		{Pattern: "DELETE /promotions/{id}", Handler: c.Promotions.DeletePromotion, Permission: auth.PromotionsManage},
+
+		{Pattern: "GET /coupons", Handler: c.Coupons.GetAllCoupons, Permission: auth.PromotionsManage},
+		{Pattern: "POST /coupons", Handler: c.Coupons.CreateCoupon, Permission: auth.PromotionsManage},
+		{Pattern: "GET /coupons/{id}", Handler: c.Coupons.GetCouponByID, Permission: auth.PromotionsManage},
+		{Pattern: "PUT /coupons/{id}", Handler: c.Coupons.UpdateCoupon, Permission: auth.PromotionsManage},
+		{Pattern: "DELETE /coupons/{id}", Handler: c.Coupons.DeleteCoupon, Permission: auth.PromotionsManage},
+
+		{Pattern: "GET /tax-rates", Handler: c.TaxRates.GetAllTaxRates, Permission: auth.SalesRead},
+		{Pattern: "POST /tax-rates", Handler: c.TaxRates.CreateTaxRate, Permission: auth.TaxesManage},
+		{Pattern: "GET /tax-rates/{id}", Handler: c.TaxRates.GetTaxRateByID, Permission: auth.SalesRead},
+		{Pattern: "PUT /tax-rates/{id}", Handler: c.TaxRates.UpdateTaxRate, Permission: auth.TaxesManage},
+		{Pattern: "DELETE /tax-rates/{id}", Handler: c.TaxRates.DeleteTaxRate, Permission: auth.TaxesManage},
+
+		{Pattern: "GET /reports/sales", Handler: c.Reports.SalesSummary, Permission: auth.ReportsRead},
+		{Pattern: "GET /reports/revenue", Handler: c.Reports.RevenueByPeriod, Permission: auth.ReportsRead},
+		{Pattern: "GET /reports/top-products", Handler: c.Reports.TopProducts, Permission: auth.ReportsRead},
+		{Pattern: "GET /reports/top-categories", Handler: c.Reports.TopCategories, Permission: auth.ReportsRead},
+		{Pattern: "GET /reports/taxes", Handler: c.Reports.TaxSummary, Permission: auth.ReportsRead},
+
+		{Pattern: "GET /users", Handler: c.Users.GetAllUsers, Permission: auth.UsersManage},
+		{Pattern: "PUT /users/{id}/role", Handler: c.Users.AssignRole, Permission: auth.UsersManage},
+
+		{Pattern: "POST /auth/login", Handler: c.Auth.Login, Public: true},
+		{Pattern: "POST /auth/register", Handler: c.Auth.Register, Public: true},
+		{Pattern: "POST /auth/refresh", Handler: c.Auth.RefreshToken, Public: true},
+		{Pattern: "POST /auth/logout", Handler: c.Auth.Logout},
+		{Pattern: "POST /auth/logout-all", Handler: c.Auth.LogoutAll},
+	}
+}
+
+// Unversioned returns the routes that live outside the API namespace.
+func Unversioned(c *controllers.Controllers) []Route {
+	return []Route{
+		{Pattern: "GET /{$}", Handler: controllers.HomeController},
+		{Pattern: "GET /health/live", Handler: c.Health.Live, Public: true},
+		{Pattern: "GET /health/ready", Handler: c.Health.Ready, Public: true},
+		{Pattern: "GET /swagger/", Handler: swaggerFiles.NewHandler().ServeHTTP, Public: true},
+	}
+}
+
+// Legacy returns the verb-in-path routes that predate the resource routes.
+// Successors are relative to the current version prefix. The update routes
+// keep their original behaviour: fields left empty or zero keep their stored
+// value, and products still need a name and a price.
+func Legacy(c *controllers.Controllers) []Route {
+	return []Route{
+		{Pattern: "GET /product", Handler: c.Products.GetProductByID, Permission: auth.ProductsRead, Successor: "/products/{id}"},
+		{Pattern: "POST /create-product", Handler: c.Products.CreateProduct, Permission: auth.ProductsWrite, Successor: "/products"},
+		{Pattern: "PUT /update-product", Handler: c.Products.UpdateProduct, Permission: auth.ProductsWrite, Successor: "/products/{id}"},
+		{Pattern: "DELETE /delete-product", Handler: c.Products.DeleteProduct, Permission: auth.ProductsDelete, Successor: "/products/{id}"},
+		{Pattern: "GET /category", Handler: c.Categories.GetCategoryByID, Permission: auth.CategoriesRead, Successor: "/categories/{id}"},
+		{Pattern: "POST /create-category", Handler: c.Categories.CreateCategory, Permission: auth.CategoriesWrite, Successor: "/categories"},
+		{Pattern: "PUT /update-category", Handler: c.Categories.UpdateLegacyCategory, Permission: auth.CategoriesWrite, Successor: "/categories/{id}"},
+		{Pattern: "DELETE /delete-category", Handler: c.Categories.DeleteCategory, Permission: auth.CategoriesDelete, Successor: "/categories/{id}"},
+		{Pattern: "POST /create-sale", Handler: c.Sales.CreateLegacySale, Permission: auth.SalesCreate, Successor: "/sales"},
+		{Pattern: "DELETE /delete-sale", Handler: c.Sales.DeleteSale, Permission: auth.SalesDelete, Successor: "/sales/{id}"},
+		{Pattern: "POST /refresh-token", Handler: c.Auth.RefreshToken, Public: true, Successor: "/auth/refresh"},
+		{Pattern: "POST /logout", Handler: c.Auth.Logout, Successor: "/auth/logout"},
+	}
+}
+
+// Register adds every route to mux. Versioned routes are served under their
+// prefix; the current version is also served at the root, as are the legacy
+// routes, both flagged as deprecated with legacySunset as removal date.
+func Register(mux *http.ServeMux, c *controllers.Controllers, legacySunset time.Time) {
+	register(mux, c, "", Unversioned(c), time.Time{})
+
+	versions := Versions(c)
+	for _, version := range versions {
+		register(mux, c, version.Prefix, version.Routes, version.Sunset)
+	}
+
+	current := versions[len(versions)-1]
+
+	var aliases []Route
+	for _, route := range current.Routes {
+		route.Successor = patternPath(route.Pattern)
+		aliases = append(aliases, route)
+	}
+	aliases = append(aliases, Legacy(c)...)
+
+	for _, route := range aliases {
+		route.Successor = current.Prefix + route.Successor
+		register(mux, c, "", []Route{route}, legacySunset)
+	}
+}
+
+// register adds routes to mux under prefix. Authenticated routes honour the
+// Idempotency-Key header; see controllers.IdempotencyController.
+func register(mux *http.ServeMux, c *controllers.Controllers, prefix string, routes []Route, sunset time.Time) {
+	for _, route := range routes {
+		handler := route.Handler
+
+		if !route.Public {
+			handler = c.Idempotency.Wrap(handler)
+		}
+
+		if route.Permission != "" {
+			handler = utils.RequirePermission(route.Permission, handler)
+		}
+
+		if !route.Public {
+			handler = utils.AuthMiddleware(handler)
+		}
+
+		if route.Successor != "" || !sunset.IsZero() {
+			handler = utils.Deprecated(route.Successor, sunset, handler)
+		}
+
+		mux.HandleFunc(prefixPattern(prefix, route.Pattern), handler)
+	}
+}
+
+// prefixPattern inserts prefix between the method and the path of pattern.
+func prefixPattern(prefix, pattern string) string {
+	method, path, found := strings.Cut(pattern, " ")
+	if !found {
+		return prefix + pattern
+	}
+	return method + " " + prefix + path
+}
+
+func patternPath(pattern string) string {
+	if _, path, found := strings.Cut(pattern, " "); found {
+		return path
+	}
+	return pattern
 }
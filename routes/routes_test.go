@@ -0,0 +1,230 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"productmanagerapi/config"
+	"productmanagerapi/controllers"
+	"productmanagerapi/models"
+	"productmanagerapi/payments"
+	"productmanagerapi/repository"
+	"productmanagerapi/services"
+	"productmanagerapi/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	config.App = config.Default()
+	config.App.Auth.SecretKey = strings.Repeat("k", 32)
+	os.Exit(m.Run())
+}
+
+// alwaysUp is a database that always answers the readiness probe.
+type alwaysUp struct{}
+
+func (alwaysUp) Ping(ctx context.Context) error { return nil }
+
+// newServer registers every route on a mux backed by the memory store, with
+// an admin account "admin" whose password is "admin password".
+func newServer(t *testing.T) *http.ServeMux {
+	t.Helper()
+	appServices := services.New(repository.NewMemoryStore(), payments.NewFakeProvider())
+	if _, err := appServices.Auth.EnsureAdmin(context.Background(), models.User{Username: "admin", Password: "admin password", Email: "admin@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	utils.IsTokenRevoked = appServices.Auth.IsRevoked
+	t.Cleanup(func() {
+		utils.IsTokenRevoked = func(ctx context.Context, tokenID string) (bool, error) { return false, nil }
+	})
+
+	mux := http.NewServeMux()
+	Register(mux, controllers.New(appServices, alwaysUp{}), time.Time{})
+	return mux
+}
+
+// request is a call to the API. Token is sent as a bearer token unless
+// Cookies are given.
+type request struct {
+	Method  string
+	Path    string
+	Token   string
+	Body    any
+	Cookies []*http.Cookie
+	Header  map[string]string
+}
+
+func serve(t *testing.T, mux *http.ServeMux, req request) *httptest.ResponseRecorder {
+	t.Helper()
+	var body strings.Builder
+	if req.Body != nil {
+		if err := json.NewEncoder(&body).Encode(req.Body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := httptest.NewRequest(req.Method, req.Path, strings.NewReader(body.String()))
+	r.Header.Set("Content-Type", "application/json")
+	if req.Token != "" {
+		r.Header.Set("Authorization", "Bearer "+req.Token)
+	}
+	for _, cookie := range req.Cookies {
+		r.AddCookie(cookie)
+	}
+	for name, value := range req.Header {
+		r.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	return w
+}
+
+// session is what logging in hands out.
+type session struct {
+	AccessToken string `json:"access_token"`
+	CSRFToken   string `json:"csrf_token"`
+	Cookies     []*http.Cookie
+}
+
+func login(t *testing.T, mux *http.ServeMux, username, password string) session {
+	t.Helper()
+	w := serve(t, mux, request{Method: "POST", Path: "/api/v1/auth/login", Body: map[string]string{"username": username, "password": password}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("logging in as %s answered %d: %s", username, w.Code, w.Body)
+	}
+	var response struct {
+		Data session `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	response.Data.Cookies = w.Result().Cookies()
+	return response.Data
+}
+
+func TestRoutesDispatchOnMethodAndPath(t *testing.T) {
+	mux := newServer(t)
+	admin := login(t, mux, "admin", "admin password")
+
+	w := serve(t, mux, request{Method: "POST", Path: "/api/v1/categories", Token: admin.AccessToken, Body: map[string]string{"Name": "Tools", "Description": "Hand tools"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("creating a category answered %d: %s", w.Code, w.Body)
+	}
+
+	if w := serve(t, mux, request{Method: "GET", Path: "/api/v1/categories/1", Token: admin.AccessToken}); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Tools") {
+		t.Fatalf("got %d %s, want the category by its path ID", w.Code, w.Body)
+	}
+	if w := serve(t, mux, request{Method: "POST", Path: "/api/v1/categories/1", Token: admin.AccessToken}); w.Code != http.StatusMethodNotAllowed || !strings.Contains(w.Header().Get("Allow"), "PATCH") {
+		t.Fatalf("got %d with Allow %q, want 405 listing the methods of the path", w.Code, w.Header().Get("Allow"))
+	}
+
+	w = serve(t, mux, request{Method: "GET", Path: "/category?id=1", Token: admin.AccessToken})
+	if w.Code != http.StatusOK || w.Header().Get("Deprecation") != "true" || w.Header().Get("Link") != `</api/v1/categories/1>; rel="successor-version"` {
+		t.Fatalf("got %d with Deprecation %q and Link %q, want the legacy alias flagged", w.Code, w.Header().Get("Deprecation"), w.Header().Get("Link"))
+	}
+	if w := serve(t, mux, request{Method: "GET", Path: "/categories/1", Token: admin.AccessToken}); w.Code != http.StatusOK || w.Header().Get("Deprecation") != "true" {
+		t.Fatalf("got %d, want the unversioned path served and deprecated", w.Code)
+	}
+}
+
+func TestLegacyUpdatesKeepEmptyFields(t *testing.T) {
+	mux := newServer(t)
+	admin := login(t, mux, "admin", "admin password")
+
+	if w := serve(t, mux, request{Method: "POST", Path: "/api/v1/categories", Token: admin.AccessToken, Body: map[string]string{"Name": "Tools", "Description": "Hand tools"}}); w.Code != http.StatusCreated {
+		t.Fatalf("creating a category answered %d: %s", w.Code, w.Body)
+	}
+	if w := serve(t, mux, request{Method: "POST", Path: "/api/v1/products", Token: admin.AccessToken, Body: map[string]any{
+		"Name": "Hammer", "Description": "Claw hammer", "Price": 10, "Stock": 5, "CategoryID": 1,
+	}}); w.Code != http.StatusCreated {
+		t.Fatalf("creating a product answered %d: %s", w.Code, w.Body)
+	}
+
+	w := serve(t, mux, request{Method: "PUT", Path: "/update-category?id=1", Token: admin.AccessToken, Body: map[string]string{"Name": "Hardware", "Description": ""}})
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"Hardware"`) || !strings.Contains(w.Body.String(), `"Hand tools"`) {
+		t.Fatalf("got %d %s, want the name changed and the empty description kept", w.Code, w.Body)
+	}
+
+	if w := serve(t, mux, request{Method: "PUT", Path: "/update-product?id=1", Token: admin.AccessToken, Body: map[string]any{"Price": 12}}); w.Code != http.StatusBadRequest {
+		t.Fatalf("got %d %s, want the product name still required", w.Code, w.Body)
+	}
+	w = serve(t, mux, request{Method: "PUT", Path: "/update-product?id=1", Token: admin.AccessToken, Body: map[string]any{"Name": "Hammer", "Price": 12}})
+	var response struct {
+		Data models.Product `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("got %d and %v, want the product updated", w.Code, err)
+	}
+	if product := response.Data; product.Price != 12 || product.Stock != 5 || product.Description != "Claw hammer" {
+		t.Fatalf("got price %v stock %d description %q, want the new price and the other fields kept", product.Price, product.Stock, product.Description)
+	}
+}
+
+func TestRolesGrantPermissions(t *testing.T) {
+	mux := newServer(t)
+	admin := login(t, mux, "admin", "admin password")
+
+	w := serve(t, mux, request{Method: "POST", Path: "/api/v1/auth/register", Body: map[string]string{"username": "bob", "password": "bob password", "email": "bob@example.com"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("registering answered %d: %s", w.Code, w.Body)
+	}
+	viewer := login(t, mux, "bob", "bob password")
+
+	if w := serve(t, mux, request{Method: "GET", Path: "/api/v1/products", Token: viewer.AccessToken}); w.Code != http.StatusOK {
+		t.Fatalf("got %d, want viewers to read products", w.Code)
+	}
+	w = serve(t, mux, request{Method: "GET", Path: "/api/v1/customers", Token: viewer.AccessToken})
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), `"required_permission":"customers:read"`) {
+		t.Fatalf("got %d %s, want viewers kept from customers", w.Code, w.Body)
+	}
+	if w := serve(t, mux, request{Method: "PUT", Path: "/api/v1/users/2/role", Token: viewer.AccessToken, Body: map[string]string{"role": "admin"}}); w.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want viewers unable to promote themselves", w.Code)
+	}
+
+	if w := serve(t, mux, request{Method: "PUT", Path: "/api/v1/users/2/role", Token: admin.AccessToken, Body: map[string]string{"role": "cashier"}}); w.Code != http.StatusOK {
+		t.Fatalf("assigning a role answered %d: %s", w.Code, w.Body)
+	}
+	cashier := login(t, mux, "bob", "bob password")
+	if w := serve(t, mux, request{Method: "GET", Path: "/api/v1/customers", Token: cashier.AccessToken}); w.Code != http.StatusOK {
+		t.Fatalf("got %d, want cashiers to read customers once logged in again", w.Code)
+	}
+	if w := serve(t, mux, request{Method: "POST", Path: "/api/v1/products", Token: cashier.AccessToken, Body: map[string]any{"Name": "Saw"}}); w.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want cashiers kept from editing the catalog", w.Code)
+	}
+}
+
+func TestCookieWritesNeedTheCSRFToken(t *testing.T) {
+	mux := newServer(t)
+	admin := login(t, mux, "admin", "admin password")
+	category := map[string]string{"Name": "Tools", "Description": "Hand tools"}
+
+	if w := serve(t, mux, request{Method: "GET", Path: "/api/v1/categories", Cookies: admin.Cookies}); w.Code != http.StatusOK {
+		t.Fatalf("got %d, want cookie reads to need no CSRF token", w.Code)
+	}
+	if w := serve(t, mux, request{Method: "POST", Path: "/api/v1/categories", Cookies: admin.Cookies, Body: category}); w.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want a cookie write without the CSRF token refused", w.Code)
+	}
+	if w := serve(t, mux, request{Method: "POST", Path: "/api/v1/categories", Cookies: admin.Cookies, Body: category, Header: map[string]string{utils.CSRFHeader: "forged"}}); w.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want a wrong CSRF token refused", w.Code)
+	}
+	if w := serve(t, mux, request{Method: "POST", Path: "/api/v1/categories", Cookies: admin.Cookies, Body: category, Header: map[string]string{utils.CSRFHeader: admin.CSRFToken}}); w.Code != http.StatusCreated {
+		t.Fatalf("got %d %s, want a cookie write with the CSRF token accepted", w.Code, w.Body)
+	}
+
+	if w := serve(t, mux, request{Method: "POST", Path: "/api/v1/categories", Token: admin.AccessToken, Body: category}); w.Code != http.StatusCreated {
+		t.Fatalf("got %d, want bearer tokens to need no CSRF token", w.Code)
+	}
+	if w := serve(t, mux, request{Method: "GET", Path: "/api/v1/categories", Header: map[string]string{"Authorization": "Token " + admin.AccessToken}}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("got %d, want a malformed Authorization header refused", w.Code)
+	}
+
+	if w := serve(t, mux, request{Method: "POST", Path: "/api/v1/auth/logout", Token: admin.AccessToken}); w.Code != http.StatusOK {
+		t.Fatalf("logging out answered %d: %s", w.Code, w.Body)
+	}
+	if w := serve(t, mux, request{Method: "GET", Path: "/api/v1/categories", Token: admin.AccessToken}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("got %d, want the token revoked by logging out", w.Code)
+	}
+}
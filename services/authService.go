@@ -1,45 +1,303 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"productmanagerapi/auth"
 	"productmanagerapi/config"
 	"productmanagerapi/models"
+	"productmanagerapi/repository"
+	"productmanagerapi/types"
+	"strconv"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var Login = func(username, password string) (string, error, models.User) {
+// Token errors surfaced to clients as 401 responses.
+var (
+	ErrInvalidCredentials  = errors.New("invalid username or password")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token already used; the session has been revoked")
+)
+
+type AuthService struct {
+	users  repository.UserRepository
+	tokens repository.TokenRepository
+}
+
+func NewAuthService(users repository.UserRepository, tokens repository.TokenRepository) *AuthService {
+	return &AuthService{users: users, tokens: tokens}
+}
+
+func (s *AuthService) Login(ctx context.Context, username, password string) (types.TokenPair, models.User, error) {
 	// Fetch user from database
-	var user models.User
-	result := config.Db.Where("username = ? ", username).First(&user)
+	user, err := s.users.FindByUsername(ctx, username)
 
-	if result.Error != nil {
-		if result.Error.Error() == "record not found" {
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			fmt.Println("Invalid username or password for user:", username)
-			return "", fmt.Errorf("invalid username or password"), models.User{}
+			return types.TokenPair{}, models.User{}, ErrInvalidCredentials
 		}
-		fmt.Println("Error fetching user:", result.Error)
-		return "", fmt.Errorf("error fetching user: %v", result.Error), models.User{}
+		fmt.Println("Error fetching user:", err)
+		return types.TokenPair{}, models.User{}, fmt.Errorf("error fetching user: %v", err)
 	}
 
 	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
 		fmt.Println("Invalid username or password for user:", username)
-		return "", fmt.Errorf("invalid username or password"), models.User{}
+		return types.TokenPair{}, models.User{}, ErrInvalidCredentials
+	}
+
+	if err := s.tokens.DeleteExpired(ctx, time.Now()); err != nil {
+		fmt.Println("Error pruning expired tokens:", err)
+	}
+
+	familyID, err := randomToken(16)
+	if err != nil {
+		return types.TokenPair{}, models.User{}, err
+	}
+
+	pair, err := s.issueTokens(ctx, user, familyID)
+	if err != nil {
+		return types.TokenPair{}, models.User{}, err
+	}
+
+	return pair, user, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. Each refresh token
+// works once: presenting it again means it leaked, so every token of its
+// family is revoked and the legitimate client has to log in again.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (types.TokenPair, models.User, error) {
+	if refreshToken == "" {
+		return types.TokenPair{}, models.User{}, ErrInvalidRefreshToken
+	}
+
+	stored, err := s.tokens.FindRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return types.TokenPair{}, models.User{}, ErrInvalidRefreshToken
+		}
+		return types.TokenPair{}, models.User{}, err
+	}
+
+	now := time.Now()
+	if stored.RevokedAt != nil || !now.Before(stored.ExpiresAt) {
+		return types.TokenPair{}, models.User{}, ErrInvalidRefreshToken
+	}
+
+	consumed := false
+	if stored.UsedAt == nil {
+		consumed, err = s.tokens.ConsumeRefreshToken(ctx, stored.ID, now)
+		if err != nil {
+			return types.TokenPair{}, models.User{}, err
+		}
+	}
+	if !consumed {
+		fmt.Printf("Refresh token reuse detected for user %d, revoking session %s\n", stored.UserID, stored.FamilyID)
+		if err := s.revokeSessions(ctx, stored.UserID, stored.FamilyID); err != nil {
+			return types.TokenPair{}, models.User{}, err
+		}
+		return types.TokenPair{}, models.User{}, ErrRefreshTokenReused
+	}
+
+	// The user is reloaded so a role change applies from the next refresh.
+	user, err := s.users.FindByID(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return types.TokenPair{}, models.User{}, ErrInvalidRefreshToken
+		}
+		return types.TokenPair{}, models.User{}, err
+	}
+
+	pair, err := s.issueTokens(ctx, user, stored.FamilyID)
+	if err != nil {
+		return types.TokenPair{}, models.User{}, err
+	}
+
+	return pair, user, nil
+}
+
+// SessionID returns the session a refresh token belongs to, without using it.
+func (s *AuthService) SessionID(ctx context.Context, refreshToken string) (string, error) {
+	stored, err := s.tokens.FindRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", ErrInvalidRefreshToken
+		}
+		return "", err
+	}
+	return stored.FamilyID, nil
+}
+
+// Logout revokes the access token of the request and the session it belongs
+// to, including access tokens issued by earlier refreshes.
+func (s *AuthService) Logout(ctx context.Context, principal auth.Principal) error {
+	if err := s.revokeAccessToken(ctx, principal); err != nil {
+		return err
+	}
+	if principal.SessionID == "" {
+		return nil
+	}
+	return s.revokeSessions(ctx, principal.UserID, principal.SessionID)
+}
+
+// LogoutAll signs the user out of every device.
+func (s *AuthService) LogoutAll(ctx context.Context, principal auth.Principal) error {
+	if err := s.revokeAccessToken(ctx, principal); err != nil {
+		return err
+	}
+	return s.revokeSessions(ctx, principal.UserID, "")
+}
+
+// IsRevoked reports whether the access token with the given jti was revoked.
+func (s *AuthService) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	return s.tokens.IsAccessTokenRevoked(ctx, tokenID)
+}
+
+func (s *AuthService) revokeAccessToken(ctx context.Context, principal auth.Principal) error {
+	return s.tokens.RevokeAccessTokens(ctx, []models.RevokedToken{{
+		TokenID:   principal.TokenID,
+		UserID:    principal.UserID,
+		ExpiresAt: principal.ExpiresAt,
+	}})
+}
+
+// revokeSessions revokes the refresh tokens of userID, only those of
+// familyID when set, and puts the access tokens they issued that have not
+// expired yet on the revocation list.
+func (s *AuthService) revokeSessions(ctx context.Context, userID uint, familyID string) error {
+	live, err := s.tokens.RevokeRefreshTokens(ctx, userID, familyID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	revoked := make([]models.RevokedToken, 0, len(live))
+	for _, token := range live {
+		revoked = append(revoked, models.RevokedToken{
+			TokenID:   token.AccessTokenID,
+			UserID:    token.UserID,
+			ExpiresAt: token.AccessTokenExpiresAt,
+		})
+	}
+	return s.tokens.RevokeAccessTokens(ctx, revoked)
+}
+
+// issueTokens signs a new access token for user and stores a refresh token
+// in the given family alongside it.
+func (s *AuthService) issueTokens(ctx context.Context, user models.User, familyID string) (types.TokenPair, error) {
+	settings := config.App.Auth
+	now := time.Now()
+	accessExpiresAt := now.Add(settings.AccessTokenTTL)
+	refreshExpiresAt := now.Add(settings.RefreshTokenTTL)
+
+	tokenID, err := randomToken(16)
+	if err != nil {
+		return types.TokenPair{}, err
 	}
 
-	// generate a token for the user
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id":  user.ID,
 		"username": user.Username,
 		"email":    user.Email,
 		"role":     user.Role,
+		"sid":      familyID,
+		"jti":      tokenID,
+		"iss":      settings.Issuer,
+		"sub":      strconv.FormatUint(uint64(user.ID), 10),
+		"iat":      jwt.NewNumericDate(now),
+		"exp":      jwt.NewNumericDate(accessExpiresAt),
+	})
+
+	accessToken, err := token.SignedString([]byte(settings.SecretKey))
+	if err != nil {
+		return types.TokenPair{}, err
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return types.TokenPair{}, err
+	}
+
+	err = s.tokens.CreateRefreshToken(ctx, &models.RefreshToken{
+		UserID:               user.ID,
+		TokenHash:            hashToken(refreshToken),
+		FamilyID:             familyID,
+		ExpiresAt:            refreshExpiresAt,
+		AccessTokenID:        tokenID,
+		AccessTokenExpiresAt: accessExpiresAt,
 	})
+	if err != nil {
+		return types.TokenPair{}, fmt.Errorf("error storing refresh token: %v", err)
+	}
 
-	tokenString, err := token.SignedString([]byte(config.SECRET_KEY))
+	return types.TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int(settings.AccessTokenTTL.Seconds()),
+		RefreshExpiresIn: int(settings.RefreshTokenTTL.Seconds()),
+		SessionID:        familyID,
+	}, nil
+}
+
+// randomToken returns size random bytes encoded for use in URLs and cookies.
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("error generating token: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken is how refresh tokens are stored, so a database leak does not
+// hand out usable tokens.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Register creates an account with the default role, whatever the caller
+// put in user.Role.
+func (s *AuthService) Register(ctx context.Context, user models.User) (models.User, error) {
+	user.Role = string(auth.DefaultRole)
+	return s.create(ctx, user)
+}
+
+// EnsureAdmin creates the bootstrap admin account unless a user with the
+// same username already exists. It reports whether the account was created.
+func (s *AuthService) EnsureAdmin(ctx context.Context, user models.User) (bool, error) {
+	_, err := s.users.FindByUsername(ctx, user.Username)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return false, err
+	}
+
+	user.Role = string(auth.RoleAdmin)
+	if _, err := s.create(ctx, user); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *AuthService) create(ctx context.Context, user models.User) (models.User, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return "", err, models.User{}
+		return models.User{}, fmt.Errorf("error hashing password: %v", err)
+	}
+	user.Password = string(hashedPassword)
+
+	if err := s.users.Create(ctx, &user); err != nil {
+		return models.User{}, fmt.Errorf("error creating user: %v", err)
 	}
 
-	return tokenString, nil, user
+	return user, nil
 }
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"productmanagerapi/models"
+	"testing"
+)
+
+func TestLoginAndRefreshRotation(t *testing.T) {
+	forEachStore(t, func(t *testing.T, services *Services) {
+		ctx := context.Background()
+		if _, err := services.Auth.Register(ctx, models.User{Username: "alice", Password: "correct horse", Email: "alice@example.com"}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, _, err := services.Auth.Login(ctx, "alice", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("got %v, want invalid credentials", err)
+		}
+		first, user, err := services.Auth.Login(ctx, "alice", "correct horse")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.Username != "alice" || first.AccessToken == "" || first.RefreshToken == "" {
+			t.Fatalf("got user %q and tokens %+v", user.Username, first)
+		}
+
+		second, _, err := services.Auth.Refresh(ctx, first.RefreshToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if second.RefreshToken == first.RefreshToken || second.SessionID != first.SessionID {
+			t.Fatal("refreshing rotates the refresh token within the same session")
+		}
+
+		if _, _, err := services.Auth.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+			t.Fatalf("got %v, want the reuse detected", err)
+		}
+		if _, _, err := services.Auth.Refresh(ctx, second.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Fatalf("got %v, want the session revoked after the reuse", err)
+		}
+
+		if _, _, err := services.Auth.Login(ctx, "alice", "correct horse"); err != nil {
+			t.Fatalf("logging in again starts a new session, got %v", err)
+		}
+	})
+}
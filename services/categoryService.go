@@ -1,77 +1,145 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
-	"productmanagerapi/config"
+	"net/url"
+	"productmanagerapi/auth"
 	"productmanagerapi/models"
+	"productmanagerapi/repository"
 	"productmanagerapi/types"
+	"strconv"
 	"strings"
 )
 
-var GetAllCategories = func() ([]models.Category, error) {
-	listCategories := []models.Category{}
-	categories := config.Db.Find(&listCategories)
+type CategoryService struct {
+	categories repository.CategoryRepository
+}
+
+func NewCategoryService(categories repository.CategoryRepository) *CategoryService {
+	return &CategoryService{categories: categories}
+}
 
-	if categories.Error != nil {
-		return nil, categories.Error
+// GetAllCategories lists categories, only those created by a given user
+// with ?created_by=.
+func (s *CategoryService) GetAllCategories(ctx context.Context, query url.Values) ([]models.Category, error) {
+	createdBy, err := parseUserFilter(query, "created_by")
+	if err != nil {
+		return nil, err
 	}
 
-	if len(listCategories) == 0 {
-		return []models.Category{}, nil
+	return s.categories.FindAll(ctx, repository.CategoryFilter{CreatedByID: createdBy})
+}
+
+func (s *CategoryService) GetCategoryByID(ctx context.Context, categoryID string) (models.Category, error) {
+	id, err := parseCategoryID(categoryID)
+	if err != nil {
+		return models.Category{}, err
 	}
 
-	return listCategories, nil
+	return s.categories.FindByID(ctx, id)
 }
 
-var GetCategoryByID = func(categoryID string) (models.Category, error) {
+func (s *CategoryService) CreateCategory(ctx context.Context, Body io.ReadCloser) (models.Category, error) {
 
-	if strings.TrimSpace(categoryID) == "" {
-		return models.Category{}, errors.New("category ID is required")
+	var category models.Category
+	if err := json.NewDecoder(Body).Decode(&category); err != nil {
+		return models.Category{}, errors.New("invalid request body: " + err.Error())
 	}
 
-	var category models.Category
-	result := config.Db.First(&category, types.ID{ID: categoryID})
+	if err := validateCategory(category); err != nil {
+		return models.Category{}, err
+	}
+	category.CreatedByID = auth.UserID(ctx)
+	category.UpdatedByID = category.CreatedByID
 
-	if result.Error != nil {
-		return models.Category{}, result.Error
+	if err := s.categories.Create(ctx, &category); err != nil {
+		return models.Category{}, err
 	}
 
 	return category, nil
 }
 
-var CreateCategory = func(Body io.ReadCloser) (models.Category, error) {
+// UpdateCategory replaces every editable field of the category.
+func (s *CategoryService) UpdateCategory(ctx context.Context, categoryID string, Body io.ReadCloser) (models.Category, error) {
+	id, err := parseCategoryID(categoryID)
+	if err != nil {
+		return models.Category{}, err
+	}
+
+	existingCategory, err := s.categories.FindByID(ctx, id)
+	if err != nil {
+		return models.Category{}, err
+	}
 
 	var category models.Category
 	if err := json.NewDecoder(Body).Decode(&category); err != nil {
 		return models.Category{}, errors.New("invalid request body: " + err.Error())
 	}
 
-	if strings.TrimSpace(category.Name) == "" {
-		return models.Category{}, errors.New("category name is required")
+	existingCategory.Name = category.Name
+	existingCategory.Description = category.Description
+
+	if err := validateCategory(existingCategory); err != nil {
+		return models.Category{}, err
 	}
+	existingCategory.UpdatedByID = auth.UserID(ctx)
 
-	if strings.TrimSpace(category.Description) == "" {
-		return models.Category{}, errors.New("category description is required")
+	if err := s.categories.Update(ctx, &existingCategory); err != nil {
+		return models.Category{}, err
 	}
 
-	result := config.Db.Create(&category)
+	return existingCategory, nil
+}
+
+// PatchCategory changes only the fields present in the body.
+func (s *CategoryService) PatchCategory(ctx context.Context, categoryID string, Body io.ReadCloser) (models.Category, error) {
+	id, err := parseCategoryID(categoryID)
+	if err != nil {
+		return models.Category{}, err
+	}
 
-	if result.Error != nil {
-		return models.Category{}, result.Error
+	existingCategory, err := s.categories.FindByID(ctx, id)
+	if err != nil {
+		return models.Category{}, err
 	}
 
-	return category, nil
+	var patch types.CategoryPatch
+	if err := json.NewDecoder(Body).Decode(&patch); err != nil {
+		return models.Category{}, errors.New("invalid request body: " + err.Error())
+	}
+
+	if patch.Name != nil {
+		existingCategory.Name = *patch.Name
+	}
+	if patch.Description != nil {
+		existingCategory.Description = *patch.Description
+	}
+
+	if err := validateCategory(existingCategory); err != nil {
+		return models.Category{}, err
+	}
+	existingCategory.UpdatedByID = auth.UserID(ctx)
+
+	if err := s.categories.Update(ctx, &existingCategory); err != nil {
+		return models.Category{}, err
+	}
+
+	return existingCategory, nil
 }
 
-var UpdateCategory = func(categoryID string, Body io.ReadCloser) (models.Category, error) {
-	if strings.TrimSpace(categoryID) == "" {
-		return models.Category{}, errors.New("category ID is required")
+// UpdateLegacyCategory updates a category as PUT /update-category always
+// did: fields left empty keep their stored value.
+func (s *CategoryService) UpdateLegacyCategory(ctx context.Context, categoryID string, Body io.ReadCloser) (models.Category, error) {
+	id, err := parseCategoryID(categoryID)
+	if err != nil {
+		return models.Category{}, err
 	}
 
-	var existingCategory models.Category
-	if err := config.Db.First(&existingCategory, types.ID{ID: categoryID}).Error; err != nil {
+	existingCategory, err := s.categories.FindByID(ctx, id)
+	if err != nil {
 		return models.Category{}, err
 	}
 
@@ -80,29 +148,62 @@ var UpdateCategory = func(categoryID string, Body io.ReadCloser) (models.Categor
 		return models.Category{}, errors.New("invalid request body: " + err.Error())
 	}
 
-	result := config.Db.Model(&existingCategory).Where("id = ?", categoryID).Updates(category)
+	if category.Name != "" {
+		existingCategory.Name = category.Name
+	}
+	if category.Description != "" {
+		existingCategory.Description = category.Description
+	}
 
-	if result.Error != nil {
-		return models.Category{}, result.Error
+	if err := validateCategory(existingCategory); err != nil {
+		return models.Category{}, err
+	}
+	existingCategory.UpdatedByID = auth.UserID(ctx)
+
+	if err := s.categories.Update(ctx, &existingCategory); err != nil {
+		return models.Category{}, err
 	}
 
 	return existingCategory, nil
 }
 
-var DeleteCategory = func(categoryID string) error {
-	if strings.TrimSpace(categoryID) == "" {
-		return errors.New("category ID is required")
+func (s *CategoryService) DeleteCategory(ctx context.Context, categoryID string) error {
+	id, err := parseCategoryID(categoryID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.categories.Delete(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errors.New("no category found with the given ID")
+		}
+		return err
 	}
 
-	result := config.Db.Delete(&models.Category{}, types.ID{ID: categoryID})
+	return nil
+}
 
-	if result.Error != nil {
-		return result.Error
+func validateCategory(category models.Category) error {
+	if strings.TrimSpace(category.Name) == "" {
+		return errors.New("category name is required")
 	}
 
-	if result.RowsAffected == 0 {
-		return errors.New("no category found with the given ID")
+	if strings.TrimSpace(category.Description) == "" {
+		return errors.New("category description is required")
 	}
 
 	return nil
 }
+
+func parseCategoryID(categoryID string) (uint, error) {
+	if strings.TrimSpace(categoryID) == "" {
+		return 0, errors.New("category ID is required")
+	}
+
+	id, err := strconv.ParseUint(strings.TrimSpace(categoryID), 10, 64)
+	if err != nil || id == 0 {
+		return 0, errors.New("invalid category ID")
+	}
+
+	return uint(id), nil
+}
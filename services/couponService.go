@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"productmanagerapi/auth"
+	"productmanagerapi/models"
+	"productmanagerapi/repository"
+	"strconv"
+)
+
+type CouponService struct {
+	store   *repository.Store
+	coupons repository.CouponRepository
+}
+
+func NewCouponService(store *repository.Store) *CouponService {
+	return &CouponService{store: store, coupons: store.Coupons}
+}
+
+func (s *CouponService) GetAllCoupons(ctx context.Context) ([]models.Coupon, error) {
+	return s.coupons.FindAll(ctx)
+}
+
+func (s *CouponService) GetCouponByID(ctx context.Context, couponID string) (models.Coupon, error) {
+	id, err := parseCouponID(couponID)
+	if err != nil {
+		return models.Coupon{}, err
+	}
+
+	return s.coupons.FindByID(ctx, id)
+}
+
+// CreateCoupon stores a new coupon. Codes are case-insensitive and stored
+// upper case.
+func (s *CouponService) CreateCoupon(ctx context.Context, body io.ReadCloser) (models.Coupon, error) {
+	var coupon models.Coupon
+	if err := json.NewDecoder(body).Decode(&coupon); err != nil {
+		return models.Coupon{}, errors.New("invalid request body: " + err.Error())
+	}
+
+	coupon.ID = 0
+	coupon.UsedCount = 0
+	coupon.CreatedByID = auth.UserID(ctx)
+	coupon.UpdatedByID = coupon.CreatedByID
+
+	err := s.store.Transaction(ctx, func(tx *repository.Store) error {
+		if err := validateCoupon(ctx, tx.Coupons, &coupon); err != nil {
+			return err
+		}
+		return tx.Coupons.Create(ctx, &coupon)
+	})
+	if err != nil {
+		return models.Coupon{}, err
+	}
+
+	return coupon, nil
+}
+
+// UpdateCoupon replaces every editable field of the coupon. Its use count
+// is kept, so lowering MaxUses below it uses the coupon up.
+func (s *CouponService) UpdateCoupon(ctx context.Context, couponID string, body io.ReadCloser) (models.Coupon, error) {
+	id, err := parseCouponID(couponID)
+	if err != nil {
+		return models.Coupon{}, err
+	}
+
+	var coupon models.Coupon
+	if err := json.NewDecoder(body).Decode(&coupon); err != nil {
+		return models.Coupon{}, errors.New("invalid request body: " + err.Error())
+	}
+
+	var updated models.Coupon
+	err = s.store.Transaction(ctx, func(tx *repository.Store) error {
+		updated, err = tx.Coupons.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		updated.Code = coupon.Code
+		updated.Description = coupon.Description
+		updated.Kind = coupon.Kind
+		updated.Value = coupon.Value
+		updated.MinSubtotal = coupon.MinSubtotal
+		updated.MaxUses = coupon.MaxUses
+		updated.StartsAt = coupon.StartsAt
+		updated.EndsAt = coupon.EndsAt
+		updated.Disabled = coupon.Disabled
+		updated.UpdatedByID = auth.UserID(ctx)
+		if err := validateCoupon(ctx, tx.Coupons, &updated); err != nil {
+			return err
+		}
+
+		return tx.Coupons.Update(ctx, &updated)
+	})
+	if err != nil {
+		return models.Coupon{}, err
+	}
+
+	return updated, nil
+}
+
+func (s *CouponService) DeleteCoupon(ctx context.Context, couponID string) error {
+	id, err := parseCouponID(couponID)
+	if err != nil {
+		return err
+	}
+
+	return s.coupons.Delete(ctx, id)
+}
+
+// validateCoupon normalizes the coupon's code and checks no other coupon
+// uses it and that its discount and limits make sense.
+func validateCoupon(ctx context.Context, coupons repository.CouponRepository, coupon *models.Coupon) error {
+	coupon.Code = normalizeCouponCode(coupon.Code)
+	if coupon.Code == "" {
+		return errors.New("code is required")
+	}
+
+	if coupon.Kind != models.DiscountPercentage && coupon.Kind != models.DiscountFixed {
+		return errors.New("kind must be percentage or fixed")
+	}
+	if err := validateDiscountValue(coupon.Kind, coupon.Value); err != nil {
+		return err
+	}
+	if coupon.MinSubtotal < 0 {
+		return errors.New("MinSubtotal cannot be negative")
+	}
+	if coupon.MaxUses < 0 {
+		return errors.New("MaxUses cannot be negative")
+	}
+	if err := validateWindow(coupon.StartsAt, coupon.EndsAt); err != nil {
+		return err
+	}
+
+	existing, err := coupons.FindByCode(ctx, coupon.Code)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return nil
+	case err != nil:
+		return err
+	case existing.ID != coupon.ID:
+		return fmt.Errorf("coupon %s already exists", coupon.Code)
+	}
+	return nil
+}
+
+func parseCouponID(couponID string) (uint, error) {
+	if couponID == "" {
+		return 0, errors.New("The coupon id is required")
+	}
+
+	id, err := strconv.ParseUint(couponID, 10, 64)
+	if err != nil || id == 0 {
+		return 0, errors.New("The coupon id is invalid")
+	}
+
+	return uint(id), nil
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"productmanagerapi/models"
+	"productmanagerapi/repository"
+	"productmanagerapi/types"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CustomerService struct {
+	store     *repository.Store
+	customers repository.CustomerRepository
+}
+
+func NewCustomerService(store *repository.Store) *CustomerService {
+	return &CustomerService{store: store, customers: store.Customers}
+}
+
+// CustomerHistory is what a customer bought. Only paid and fulfilled sales
+// count as purchases: LifetimeValue is their total net of refunds.
+type CustomerHistory struct {
+	Customer       models.Customer `json:"customer"`
+	Sales          []models.Sale   `json:"sales"`
+	PurchaseCount  int             `json:"purchase_count"`
+	LifetimeValue  float64         `json:"lifetime_value"`
+	LastPurchaseAt *time.Time      `json:"last_purchase_at"`
+}
+
+func (s *CustomerService) GetAllCustomers(ctx context.Context) ([]models.Customer, error) {
+	return s.customers.FindAll(ctx)
+}
+
+func (s *CustomerService) GetCustomerByID(ctx context.Context, customerID string) (models.Customer, error) {
+	id, err := parseCustomerID(customerID)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	return s.customers.FindByID(ctx, id)
+}
+
+func (s *CustomerService) CreateCustomer(ctx context.Context, body io.ReadCloser) (models.Customer, error) {
+	var customer models.Customer
+	if err := json.NewDecoder(body).Decode(&customer); err != nil {
+		return models.Customer{}, errors.New("invalid request body: " + err.Error())
+	}
+
+	customer.ID = 0
+	customer.StoreCredit = 0
+	for i := range customer.Addresses {
+		customer.Addresses[i].ID = 0
+	}
+	if err := validateCustomer(&customer); err != nil {
+		return models.Customer{}, err
+	}
+
+	if err := s.customers.Create(ctx, &customer); err != nil {
+		return models.Customer{}, err
+	}
+
+	return customer, nil
+}
+
+// UpdateCustomer replaces every editable field of the customer, addresses
+// included.
+func (s *CustomerService) UpdateCustomer(ctx context.Context, customerID string, body io.ReadCloser) (models.Customer, error) {
+	id, err := parseCustomerID(customerID)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	var customer models.Customer
+	if err := json.NewDecoder(body).Decode(&customer); err != nil {
+		return models.Customer{}, errors.New("invalid request body: " + err.Error())
+	}
+
+	var updated models.Customer
+	err = s.store.Transaction(ctx, func(tx *repository.Store) error {
+		updated, err = tx.Customers.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		updated.Name = customer.Name
+		updated.Email = customer.Email
+		updated.Phone = customer.Phone
+		updated.Notes = customer.Notes
+		updated.Addresses = customer.Addresses
+		if err := validateCustomer(&updated); err != nil {
+			return err
+		}
+
+		return tx.Customers.Update(ctx, &updated)
+	})
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	return updated, nil
+}
+
+// DeleteCustomer removes a customer. Their sales are kept and still point to
+// them.
+func (s *CustomerService) DeleteCustomer(ctx context.Context, customerID string) error {
+	id, err := parseCustomerID(customerID)
+	if err != nil {
+		return err
+	}
+
+	return s.customers.Delete(ctx, id)
+}
+
+// AdjustStoreCredit gives a customer store credit, or takes it away with a
+// negative amount. Credit cannot go below zero.
+func (s *CustomerService) AdjustStoreCredit(ctx context.Context, customerID string, body io.ReadCloser) (models.Customer, error) {
+	id, err := parseCustomerID(customerID)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	var request types.StoreCreditAdjustment
+	if err := json.NewDecoder(body).Decode(&request); err != nil {
+		return models.Customer{}, errors.New("invalid request body: " + err.Error())
+	}
+
+	amount := roundCents(request.Amount)
+	if amount == 0 {
+		return models.Customer{}, errors.New("amount is required")
+	}
+
+	ok, err := s.customers.AdjustStoreCredit(ctx, id, amount)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	if !ok {
+		return models.Customer{}, errors.New("store credit cannot go below zero")
+	}
+
+	return s.customers.FindByID(ctx, id)
+}
+
+// GetPurchaseHistory lists every sale of a customer, drafts and cancelled
+// sales included, with the totals of what they actually bought.
+func (s *CustomerService) GetPurchaseHistory(ctx context.Context, customerID string) (CustomerHistory, error) {
+	id, err := parseCustomerID(customerID)
+	if err != nil {
+		return CustomerHistory{}, err
+	}
+
+	customer, err := s.customers.FindByID(ctx, id)
+	if err != nil {
+		return CustomerHistory{}, err
+	}
+
+	sales, err := s.store.Sales.FindByCustomer(ctx, id)
+	if err != nil {
+		return CustomerHistory{}, err
+	}
+
+	history := CustomerHistory{Customer: customer, Sales: sales}
+	for _, sale := range sales {
+		if sale.Status != models.SalePaid && sale.Status != models.SaleFulfilled {
+			continue
+		}
+		history.PurchaseCount++
+		history.LifetimeValue += sale.Total - sale.RefundedTotal
+		if history.LastPurchaseAt == nil || sale.CreatedAt.After(*history.LastPurchaseAt) {
+			createdAt := sale.CreatedAt
+			history.LastPurchaseAt = &createdAt
+		}
+	}
+
+	return history, nil
+}
+
+// validateCustomer trims the customer's fields and checks them.
+func validateCustomer(customer *models.Customer) error {
+	customer.Name = strings.TrimSpace(customer.Name)
+	customer.Email = strings.TrimSpace(customer.Email)
+	customer.Phone = strings.TrimSpace(customer.Phone)
+
+	if customer.Name == "" {
+		return errors.New("customer name is required")
+	}
+	if customer.Email != "" {
+		if address, err := mail.ParseAddress(customer.Email); err != nil || address.Address != customer.Email {
+			return errors.New("customer email is invalid")
+		}
+	}
+
+	for i, address := range customer.Addresses {
+		if strings.TrimSpace(address.Line1) == "" || strings.TrimSpace(address.City) == "" {
+			return fmt.Errorf("address %d: line1 and city are required", i)
+		}
+	}
+
+	return nil
+}
+
+func parseCustomerID(customerID string) (uint, error) {
+	if strings.TrimSpace(customerID) == "" {
+		return 0, errors.New("customer ID is required")
+	}
+
+	id, err := strconv.ParseUint(strings.TrimSpace(customerID), 10, 64)
+	if err != nil || id == 0 {
+		return 0, errors.New("invalid customer ID")
+	}
+
+	return uint(id), nil
+}
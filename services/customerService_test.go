@@ -0,0 +1,71 @@
+package services
+
+import (
+	"fmt"
+	"productmanagerapi/models"
+	"testing"
+)
+
+func TestCustomerPurchaseHistory(t *testing.T) {
+	forEachStore(t, func(t *testing.T, services *Services) {
+		ctx := asAdmin()
+		product := createProduct(t, ctx, services, 10, 10)
+
+		if _, err := services.Customers.CreateCustomer(ctx, body(t, map[string]any{"Name": "Ada", "Email": "not an email"})); err == nil {
+			t.Fatal("an invalid email is refused")
+		}
+		customer, err := services.Customers.CreateCustomer(ctx, body(t, map[string]any{
+			"Name":        "Ada",
+			"Email":       "ada@example.com",
+			"StoreCredit": 100,
+			"Addresses":   []map[string]any{{"Label": "billing", "Line1": "1 Main St", "City": "Springfield"}},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if customer.StoreCredit != 0 || len(customer.Addresses) != 1 {
+			t.Fatalf("got store credit %v and %d addresses, want credit only given through adjustments and the address kept", customer.StoreCredit, len(customer.Addresses))
+		}
+		id := fmt.Sprint(customer.ID)
+
+		if _, err := services.Customers.AdjustStoreCredit(ctx, id, body(t, map[string]any{"amount": -5})); err == nil {
+			t.Fatal("store credit cannot go below zero")
+		}
+
+		if _, err := services.Sales.CreateSale(ctx, body(t, map[string]any{
+			"products":    []map[string]any{{"product_id": product.ID, "quantity": 1}},
+			"customer_id": customer.ID + 100,
+		})); err == nil {
+			t.Fatal("a sale cannot point to a missing customer")
+		}
+		if _, err := services.Sales.CreateSale(ctx, body(t, map[string]any{
+			"products":    []map[string]any{{"product_id": product.ID, "quantity": 2}},
+			"customer_id": customer.ID,
+			"status":      models.SaleFulfilled,
+			"tenders":     []map[string]any{{"method": "cash", "tendered": 20}},
+		})); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := services.Sales.CreateSale(ctx, body(t, map[string]any{
+			"products":    []map[string]any{{"product_id": product.ID, "quantity": 1}},
+			"customer_id": customer.ID,
+		})); err != nil {
+			t.Fatal(err)
+		}
+
+		history, err := services.Customers.GetPurchaseHistory(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history.Sales) != 2 || history.PurchaseCount != 1 || history.LifetimeValue != 20 || history.LastPurchaseAt == nil {
+			t.Fatalf("got %d sales, %d purchases worth %v, want the draft listed but only the paid sale counted for 20", len(history.Sales), history.PurchaseCount, history.LifetimeValue)
+		}
+
+		if err := services.Customers.DeleteCustomer(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := services.Customers.GetCustomerByID(ctx, id); err == nil {
+			t.Fatal("a deleted customer is gone")
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidFilter wraps errors about the query parameters of a listing.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// parseUserFilter reads the user ID in the query parameter name, such as
+// ?created_by=3. It returns 0 when the parameter is absent.
+func parseUserFilter(query url.Values, name string) (uint, error) {
+	value := strings.TrimSpace(query.Get(name))
+	if value == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("%w: %s must be a user ID", ErrInvalidFilter, name)
+	}
+	return uint(id), nil
+}
+
+// parsePeriod reads the period of a report from the ?from= and ?to= query
+// parameters as parseTimeRange does. The period defaults to the month of
+// now.
+func parsePeriod(query url.Values, now time.Time) (time.Time, time.Time, error) {
+	from, to, err := parseTimeRange(query)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	year, month, _ := now.UTC().Date()
+	if from.IsZero() {
+		from = time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if to.IsZero() {
+		to = time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	}
+
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: to must be after from", ErrInvalidFilter)
+	}
+	return from, to, nil
+}
+
+// parseTimeRange reads the ?from= and ?to= query parameters, each a date
+// such as 2024-01-31 or an RFC 3339 time, in UTC when no offset is given.
+// from is inclusive and to exclusive, except that a date given as to
+// includes that whole day. Either is zero when absent.
+func parseTimeRange(query url.Values) (time.Time, time.Time, error) {
+	var from, to time.Time
+	if value := strings.TrimSpace(query.Get("from")); value != "" {
+		parsed, _, err := parseTime(value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: from must be a date or an RFC 3339 time", ErrInvalidFilter)
+		}
+		from = parsed
+	}
+	if value := strings.TrimSpace(query.Get("to")); value != "" {
+		parsed, dateOnly, err := parseTime(value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: to must be a date or an RFC 3339 time", ErrInvalidFilter)
+		}
+		if dateOnly {
+			parsed = parsed.AddDate(0, 0, 1)
+		}
+		to = parsed
+	}
+
+	if !from.IsZero() && !to.IsZero() && !to.After(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: to must be after from", ErrInvalidFilter)
+	}
+	return from, to, nil
+}
+
+// parseIDFilter reads the ID in the query parameter name, such as
+// ?product_id=3. It returns 0 when the parameter is absent.
+func parseIDFilter(query url.Values, name string) (uint, error) {
+	value := strings.TrimSpace(query.Get(name))
+	if value == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("%w: %s must be an ID", ErrInvalidFilter, name)
+	}
+	return uint(id), nil
+}
+
+// parseAmountFilter reads the amount in the query parameter name, such as
+// ?min_total=10.5. It returns nil when the parameter is absent.
+func parseAmountFilter(query url.Values, name string) (*float64, error) {
+	value := strings.TrimSpace(query.Get(name))
+	if value == "" {
+		return nil, nil
+	}
+
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidFilter, name)
+	}
+	return &amount, nil
+}
+
+// parseListFilter reads the comma-separated values of the query parameter
+// name, such as ?status=paid,fulfilled, each of which must be one of
+// allowed.
+func parseListFilter(query url.Values, name string, allowed []string) ([]string, error) {
+	var values []string
+	for _, value := range strings.Split(query.Get(name), ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		if !slices.Contains(allowed, value) {
+			return nil, fmt.Errorf("%w: %s must be among %s", ErrInvalidFilter, name, strings.Join(allowed, ", "))
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// parseBoolFilter reads the true or false in the query parameter name, such
+// as ?in_stock=true. It returns false when the parameter is absent.
+func parseBoolFilter(query url.Values, name string) (bool, error) {
+	value := strings.TrimSpace(query.Get(name))
+	if value == "" {
+		return false, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s must be true or false", ErrInvalidFilter, name)
+	}
+	return parsed, nil
+}
+
+// parseTimeFilter reads the date or RFC 3339 time in the query parameter
+// name, such as ?created_since=2024-01-31. It returns zero when the
+// parameter is absent.
+func parseTimeFilter(query url.Values, name string) (time.Time, error) {
+	value := strings.TrimSpace(query.Get(name))
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	parsed, _, err := parseTime(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s must be a date or an RFC 3339 time", ErrInvalidFilter, name)
+	}
+	return parsed, nil
+}
+
+// parseTime parses a date or an RFC 3339 time and reports which it was.
+func parseTime(value string) (time.Time, bool, error) {
+	if parsed, err := time.Parse(time.DateOnly, value); err == nil {
+		return parsed, true, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	return parsed, false, err
+}
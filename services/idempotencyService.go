@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"productmanagerapi/auth"
+	"productmanagerapi/config"
+	"productmanagerapi/models"
+	"productmanagerapi/repository"
+	"time"
+)
+
+// Errors of IdempotencyService.Begin.
+var (
+	ErrInvalidIdempotencyKey    = errors.New("the Idempotency-Key header must be between 1 and 255 characters")
+	ErrIdempotencyKeyReused     = errors.New("the Idempotency-Key was already used for a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this Idempotency-Key is still being processed")
+)
+
+// idempotencyPendingTimeout is how long a key stays reserved by a request
+// that never completes, such as one cut short by a restart, before it can
+// be used again. Hold renews the reservation while the request runs, every
+// idempotencyHeartbeat, so slow requests keep their key however long they
+// take.
+const (
+	idempotencyPendingTimeout = time.Minute
+	idempotencyHeartbeat      = idempotencyPendingTimeout / 3
+)
+
+type IdempotencyService struct {
+	keys repository.IdempotencyRepository
+}
+
+func NewIdempotencyService(store *repository.Store) *IdempotencyService {
+	return &IdempotencyService{keys: store.IdempotencyKeys}
+}
+
+// Begin reserves key, scoped to the caller, for a request identified by
+// fingerprint. It returns the reserved record and false when the request
+// should run, in which case Hold should keep the key while it does and
+// Complete or Abandon must follow. When the
+// request already ran with this key it returns the record holding its
+// response and true. A key reused for a different request fails with
+// ErrIdempotencyKeyReused, and one whose request is still running with
+// ErrIdempotencyKeyInProgress.
+func (s *IdempotencyService) Begin(ctx context.Context, key string, fingerprint string) (models.IdempotencyKey, bool, error) {
+	if key == "" || len(key) > 255 {
+		return models.IdempotencyKey{}, false, ErrInvalidIdempotencyKey
+	}
+
+	userID := auth.UserID(ctx)
+	// A record found taken may expire before it is read; the second
+	// attempt reserves the key then.
+	for attempt := 0; attempt < 2; attempt++ {
+		now := time.Now()
+		record := models.IdempotencyKey{
+			UserID:      userID,
+			Key:         key,
+			Fingerprint: fingerprint,
+			ExpiresAt:   pendingExpiry(now),
+		}
+		reserved, err := s.keys.Reserve(ctx, &record, now)
+		if err != nil {
+			return models.IdempotencyKey{}, false, err
+		}
+		if reserved {
+			return record, false, nil
+		}
+
+		existing, err := s.keys.Find(ctx, userID, key)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return models.IdempotencyKey{}, false, err
+		}
+
+		switch {
+		case existing.Fingerprint != fingerprint:
+			return models.IdempotencyKey{}, false, ErrIdempotencyKeyReused
+		case existing.Status == 0:
+			return models.IdempotencyKey{}, false, ErrIdempotencyKeyInProgress
+		}
+		return existing, true, nil
+	}
+	return models.IdempotencyKey{}, false, ErrIdempotencyKeyInProgress
+}
+
+// Hold keeps the key reserved by Begin for record until the returned
+// release is called, which must happen before Complete or Abandon. The
+// reservation is renewed in the background, independently of ctx being
+// cancelled, since the handler may go on after the client left.
+func (s *IdempotencyService) Hold(ctx context.Context, record models.IdempotencyKey) (release func()) {
+	ctx = context.WithoutCancel(ctx)
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(idempotencyHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				record.ExpiresAt = pendingExpiry(now)
+				if err := s.keys.Update(ctx, &record); err != nil {
+					fmt.Println("Error renewing idempotency key:", err)
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-stopped
+	}
+}
+
+// pendingExpiry is when a key reserved or renewed at now frees up if its
+// request stops renewing it.
+func pendingExpiry(now time.Time) time.Time {
+	return now.Add(min(idempotencyPendingTimeout, config.App.API.IdempotencyWindow))
+}
+
+// Complete stores the response of the request that reserved record with
+// Begin, as set on record by the caller, to be replayed for the idempotency
+// window.
+func (s *IdempotencyService) Complete(ctx context.Context, record models.IdempotencyKey) error {
+	record.ExpiresAt = time.Now().Add(config.App.API.IdempotencyWindow)
+	return s.keys.Update(ctx, &record)
+}
+
+// Abandon frees a key reserved with Begin without storing a response, so
+// the request can be retried with it.
+func (s *IdempotencyService) Abandon(ctx context.Context, record models.IdempotencyKey) error {
+	return s.keys.Delete(ctx, record.ID)
+}
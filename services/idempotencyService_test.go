@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"productmanagerapi/auth"
+	"testing"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	forEachStore(t, func(t *testing.T, services *Services) {
+		ctx := asAdmin()
+		keys := services.Idempotency
+
+		if _, _, err := keys.Begin(ctx, "", "POST /api/v1/sales"); !errors.Is(err, ErrInvalidIdempotencyKey) {
+			t.Fatalf("got %v, want an empty key refused", err)
+		}
+
+		record, replay, err := keys.Begin(ctx, "key-1", "POST /api/v1/sales")
+		if err != nil || replay {
+			t.Fatalf("got replay %v and %v, want the key reserved", replay, err)
+		}
+		if _, _, err := keys.Begin(ctx, "key-1", "POST /api/v1/sales"); !errors.Is(err, ErrIdempotencyKeyInProgress) {
+			t.Fatalf("got %v, want the key taken while its request runs", err)
+		}
+		if _, _, err := keys.Begin(ctx, "key-1", "POST /api/v1/customers"); !errors.Is(err, ErrIdempotencyKeyReused) {
+			t.Fatalf("got %v, want the key refused for another request", err)
+		}
+
+		other := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 2, Username: "cashier", Role: auth.RoleCashier})
+		if _, replay, err := keys.Begin(other, "key-1", "POST /api/v1/customers"); err != nil || replay {
+			t.Fatalf("got replay %v and %v, want keys scoped to their user", replay, err)
+		}
+
+		record.Status = 201
+		record.ContentType = "application/json"
+		record.Location = "/api/v1/sales/1"
+		record.Body = []byte(`{"data":{"ID":1}}`)
+		if err := keys.Complete(ctx, record); err != nil {
+			t.Fatal(err)
+		}
+		stored, replay, err := keys.Begin(ctx, "key-1", "POST /api/v1/sales")
+		if err != nil || !replay {
+			t.Fatalf("got replay %v and %v, want the response replayed", replay, err)
+		}
+		if stored.Status != 201 || stored.Location != "/api/v1/sales/1" || string(stored.Body) != `{"data":{"ID":1}}` {
+			t.Fatalf("got status %d location %q body %s, want the stored response", stored.Status, stored.Location, stored.Body)
+		}
+
+		abandoned, _, err := keys.Begin(ctx, "key-2", "POST /api/v1/sales")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := keys.Abandon(ctx, abandoned); err != nil {
+			t.Fatal(err)
+		}
+		if _, replay, err := keys.Begin(ctx, "key-2", "POST /api/v1/sales"); err != nil || replay {
+			t.Fatalf("got replay %v and %v, want an abandoned key free again", replay, err)
+		}
+	})
+}
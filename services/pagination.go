@@ -0,0 +1,189 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Page describes the part of a listing a response holds. Total counts what
+// matches the filters over every page. NextCursor, when set, is passed as
+// ?cursor= to get the following page. Offset is set instead on listings
+// paged with ?offset=.
+type Page struct {
+	Limit      int    `json:"limit"`
+	Offset     *int   `json:"offset,omitempty"`
+	Total      int64  `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Page sizes of listings.
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// parseLimit reads the page size in ?limit=.
+func parseLimit(query url.Values) (int, error) {
+	value := strings.TrimSpace(query.Get("limit"))
+	if value == "" {
+		return defaultPageLimit, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		return 0, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidFilter, maxPageLimit)
+	}
+	return limit, nil
+}
+
+// parseSort reads ?sort=, one of allowed, descending when prefixed with a
+// minus sign as in ?sort=-total. It returns fallback when absent.
+func parseSort(query url.Values, allowed []string, fallback string) (string, bool, error) {
+	value := strings.TrimSpace(query.Get("sort"))
+	if value == "" {
+		value = fallback
+	}
+
+	field, desc := strings.CutPrefix(value, "-")
+	if !slices.Contains(allowed, field) {
+		return "", false, fmt.Errorf("%w: sort must be one of %s, prefixed with - for descending order", ErrInvalidFilter, strings.Join(allowed, ", "))
+	}
+	return field, desc, nil
+}
+
+// sortField is a field of ?sort=, descending when Desc is set.
+type sortField struct {
+	Name string
+	Desc bool
+}
+
+// parseSorts reads ?sort= as a comma-separated list of fields among
+// allowed, each descending when prefixed with a minus sign as in
+// ?sort=category_id,-price. It returns the fields of fallback when absent.
+func parseSorts(query url.Values, allowed []string, fallback string) ([]sortField, error) {
+	value := strings.TrimSpace(query.Get("sort"))
+	if value == "" {
+		value = fallback
+	}
+
+	var fields []sortField
+	for _, item := range strings.Split(value, ",") {
+		name, desc := strings.CutPrefix(strings.TrimSpace(item), "-")
+		if !slices.Contains(allowed, name) {
+			return nil, fmt.Errorf("%w: sort must list fields among %s, each prefixed with - for descending order", ErrInvalidFilter, strings.Join(allowed, ", "))
+		}
+		if slices.ContainsFunc(fields, func(field sortField) bool { return field.Name == name }) {
+			return nil, fmt.Errorf("%w: sort lists %s twice", ErrInvalidFilter, name)
+		}
+		fields = append(fields, sortField{Name: name, Desc: desc})
+	}
+	return fields, nil
+}
+
+// formatSorts writes fields back as a ?sort= value.
+func formatSorts(fields []sortField) string {
+	items := make([]string, len(fields))
+	for i, field := range fields {
+		items[i] = field.Name
+		if field.Desc {
+			items[i] = "-" + field.Name
+		}
+	}
+	return strings.Join(items, ",")
+}
+
+// parseOffset reads ?offset=, the number of records to skip, and reports
+// whether it was given.
+func parseOffset(query url.Values) (int, bool, error) {
+	value := strings.TrimSpace(query.Get("offset"))
+	if value == "" {
+		return 0, false, nil
+	}
+
+	offset, err := strconv.Atoi(value)
+	if err != nil || offset < 0 {
+		return 0, false, fmt.Errorf("%w: offset must be a number of records, 0 or more", ErrInvalidFilter)
+	}
+	return offset, true, nil
+}
+
+// parseFields reads ?fields=, a comma-separated list of the JSON fields of
+// record, matched without regard to case. It returns nil when absent.
+func parseFields(query url.Values, record any) ([]string, error) {
+	value := strings.TrimSpace(query.Get("fields"))
+	if value == "" {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &object); err != nil {
+		return nil, err
+	}
+	known := make([]string, 0, len(object))
+	for name := range object {
+		known = append(known, name)
+	}
+	slices.Sort(known)
+
+	var fields []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		i := slices.IndexFunc(known, func(name string) bool { return strings.EqualFold(name, item) })
+		if i < 0 {
+			return nil, fmt.Errorf("%w: fields must be among %s", ErrInvalidFilter, strings.Join(known, ", "))
+		}
+		if !slices.Contains(fields, known[i]) {
+			fields = append(fields, known[i])
+		}
+	}
+	return fields, nil
+}
+
+// selectFields keeps only fields, as read by parseFields, of each of
+// records.
+func selectFields[T any](records []T, fields []string) ([]map[string]json.RawMessage, error) {
+	selected := make([]map[string]json.RawMessage, 0, len(records))
+	for _, record := range records {
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(encoded, &object); err != nil {
+			return nil, err
+		}
+		kept := make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			kept[field] = object[field]
+		}
+		selected = append(selected, kept)
+	}
+	return selected, nil
+}
+
+// encodeCursor turns the position of a page into an opaque ?cursor= value.
+func encodeCursor(position any) string {
+	encoded, _ := json.Marshal(position)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// decodeCursor reads a value made by encodeCursor into position.
+func decodeCursor(value string, position any) error {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err == nil {
+		err = json.Unmarshal(decoded, position)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: cursor is invalid", ErrInvalidFilter)
+	}
+	return nil
+}
@@ -0,0 +1,330 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"productmanagerapi/auth"
+	"productmanagerapi/models"
+	"productmanagerapi/payments"
+	"productmanagerapi/repository"
+	"productmanagerapi/types"
+	"time"
+)
+
+type PaymentService struct {
+	store    *repository.Store
+	payments repository.PaymentRepository
+	provider payments.Provider
+}
+
+func NewPaymentService(store *repository.Store, provider payments.Provider) *PaymentService {
+	return &PaymentService{store: store, payments: store.Payments, provider: provider}
+}
+
+// PaymentResult is the outcome of POST /sales/{id}/payments: the payments
+// recorded, the change to hand back in cash and what is still owed.
+type PaymentResult struct {
+	Sale     models.Sale      `json:"sale"`
+	Payments []models.Payment `json:"payments"`
+	Change   float64          `json:"change"`
+	Balance  float64          `json:"balance"`
+}
+
+// PaymentSummary lists the payments of a sale with its balance.
+type PaymentSummary struct {
+	Payments  []models.Payment `json:"payments"`
+	Total     float64          `json:"total"`
+	PaidTotal float64          `json:"paid_total"`
+	Balance   float64          `json:"balance"`
+}
+
+var tenderMethods = map[string]bool{
+	models.TenderCash:        true,
+	models.TenderCard:        true,
+	models.TenderMobileMoney: true,
+	models.TenderStoreCredit: true,
+}
+
+// CreatePayment pays off part or all of a sale with one or more tenders,
+// see pay. A confirmed sale that ends up fully paid moves to paid.
+func (s *PaymentService) CreatePayment(ctx context.Context, saleID string, body io.ReadCloser) (PaymentResult, error) {
+	id, err := parseSaleID(saleID)
+	if err != nil {
+		return PaymentResult{}, err
+	}
+
+	var request types.PaymentRequest
+	if err := json.NewDecoder(body).Decode(&request); err != nil {
+		return PaymentResult{}, invalidSale("invalid request body : %v", err)
+	}
+
+	if len(request.Tenders) == 0 {
+		return PaymentResult{}, invalidSale("at least one tender is required")
+	}
+	if err := validateTenders(request.Tenders); err != nil {
+		return PaymentResult{}, err
+	}
+
+	var result PaymentResult
+	var charged []string
+	err = s.store.Transaction(ctx, func(tx *repository.Store) error {
+		sale, err := tx.Sales.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		result, err = s.pay(ctx, tx, &sale, request.Tenders, &charged)
+		if err != nil {
+			return err
+		}
+
+		result.Sale, err = tx.Sales.FindByID(ctx, sale.ID)
+		return err
+	})
+	if err != nil {
+		s.refundCharges(ctx, charged)
+		return PaymentResult{}, err
+	}
+
+	return result, nil
+}
+
+// validateTenders checks the tenders of a request before anything is
+// charged.
+func validateTenders(tenders []types.Tender) error {
+	for i, tender := range tenders {
+		switch {
+		case !tenderMethods[tender.Method]:
+			return invalidSale("tender %d: method must be cash, card, mobile_money or store_credit", i)
+		case tender.Amount < 0 || tender.Tendered < 0:
+			return invalidSale("tender %d: amounts cannot be negative", i)
+		case tender.Tendered > 0 && tender.Method != models.TenderCash:
+			return invalidSale("tender %d: only cash takes a tendered amount", i)
+		case tender.Token == "" && (tender.Method == models.TenderCard || tender.Method == models.TenderMobileMoney):
+			return invalidSale("tender %d: a token is required for %s", i, tender.Method)
+		}
+	}
+	return nil
+}
+
+// pay records tenders against sale, locked by the transaction tx. Cash and
+// store credit are settled here; card and mobile money tenders are charged
+// through the payment provider once every other tender is accepted, and
+// their references appended to charged so that the caller can refund them
+// if the transaction fails. A confirmed sale left with nothing to pay moves
+// to paid and gets its invoice number. The sale is saved; the result's Sale
+// is left for the caller to load.
+func (s *PaymentService) pay(ctx context.Context, tx *repository.Store, sale *models.Sale, tenders []types.Tender, charged *[]string) (PaymentResult, error) {
+	if sale.Status == models.SaleDraft || sale.Status == models.SaleCancelled {
+		return PaymentResult{}, &SaleStatusError{Status: sale.Status, Action: "take a payment for"}
+	}
+
+	remaining := roundCents(sale.Total - sale.PaidTotal)
+	if remaining <= 0 {
+		return PaymentResult{}, invalidSale("sale %d has nothing left to pay", sale.ID)
+	}
+
+	principal, _ := auth.PrincipalFrom(ctx)
+
+	var result PaymentResult
+	for i, tender := range tenders {
+		payment := models.Payment{
+			SaleID:       sale.ID,
+			Method:       tender.Method,
+			Amount:       roundCents(tender.Amount),
+			ReceivedByID: principal.UserID,
+		}
+
+		if payment.Amount == 0 {
+			payment.Amount = remaining
+			if tender.Tendered > 0 {
+				payment.Amount = math.Min(roundCents(tender.Tendered), remaining)
+			}
+		}
+		if payment.Amount <= 0 {
+			return PaymentResult{}, invalidSale("tender %d: sale %d has nothing left to pay", i, sale.ID)
+		}
+		if payment.Amount > remaining {
+			return PaymentResult{}, invalidSale("tender %d: %.2f exceeds the balance of %.2f", i, payment.Amount, remaining)
+		}
+
+		if tender.Method == models.TenderCash {
+			payment.Tendered = roundCents(tender.Tendered)
+			if payment.Tendered == 0 {
+				payment.Tendered = payment.Amount
+			}
+			if payment.Tendered < payment.Amount {
+				return PaymentResult{}, invalidSale("tender %d: %.2f tendered does not cover %.2f", i, payment.Tendered, payment.Amount)
+			}
+			payment.Change = roundCents(payment.Tendered - payment.Amount)
+			result.Change += payment.Change
+		}
+
+		if tender.Method == models.TenderStoreCredit {
+			if err := spendStoreCredit(ctx, tx.Customers, *sale, payment.Amount); err != nil {
+				return PaymentResult{}, fmt.Errorf("tender %d: %w", i, err)
+			}
+		}
+
+		remaining = roundCents(remaining - payment.Amount)
+		result.Payments = append(result.Payments, payment)
+	}
+
+	// External charges come last, so a tender refused above costs the
+	// customer nothing.
+	for i, tender := range tenders {
+		if tender.Method != models.TenderCard && tender.Method != models.TenderMobileMoney {
+			continue
+		}
+		reference, err := s.provider.Charge(ctx, payments.Charge{
+			SaleID: sale.ID,
+			Method: tender.Method,
+			Amount: result.Payments[i].Amount,
+			Token:  tender.Token,
+		})
+		if err != nil {
+			return PaymentResult{}, fmt.Errorf("tender %d: %w", i, err)
+		}
+		*charged = append(*charged, reference)
+		result.Payments[i].Provider = s.provider.Name()
+		result.Payments[i].Reference = reference
+	}
+
+	if err := tx.Payments.Create(ctx, result.Payments); err != nil {
+		return PaymentResult{}, err
+	}
+
+	for _, payment := range result.Payments {
+		sale.PaidTotal += payment.Amount
+	}
+	sale.PaidTotal = roundCents(sale.PaidTotal)
+	sale.UpdatedByID = principal.UserID
+	if sale.Status == models.SaleConfirmed && remaining == 0 {
+		stampStatus(sale, models.SalePaid, time.Now())
+		if err := issueInvoice(ctx, tx.Sequences, sale); err != nil {
+			return PaymentResult{}, err
+		}
+	}
+	if err := tx.Sales.Update(ctx, sale); err != nil {
+		return PaymentResult{}, err
+	}
+
+	result.Balance = remaining
+	result.Change = roundCents(result.Change)
+	return result, nil
+}
+
+// refundCharges refunds the provider charges of a payment that failed after
+// they were made.
+func (s *PaymentService) refundCharges(ctx context.Context, charged []string) {
+	for _, reference := range charged {
+		if err := s.provider.Refund(ctx, reference); err != nil {
+			fmt.Printf("Could not refund charge %s after a failed payment: %v\n", reference, err)
+		}
+	}
+}
+
+// refundPayments gives back the payments of sale, locked by the transaction
+// tx, that were not refunded yet: store credit goes back to the customer,
+// card and mobile money charges are refunded through the payment provider
+// and cash is handed back at the till. The payments are marked refunded and
+// their sum added to the sale's RefundedTotal; the caller saves the sale.
+// The provider is called last, so a refund it refuses fails the transaction
+// before anything else is given back.
+func (s *PaymentService) refundPayments(ctx context.Context, tx *repository.Store, sale *models.Sale) error {
+	payments, err := tx.Payments.FindBySale(ctx, sale.ID)
+	if err != nil {
+		return err
+	}
+
+	var refunded []uint
+	var charges []string
+	var total float64
+	for _, payment := range payments {
+		if payment.RefundedAt != nil {
+			continue
+		}
+		switch payment.Method {
+		case models.TenderStoreCredit:
+			if sale.CustomerID == nil {
+				return fmt.Errorf("payment %d was made in store credit but sale %d has no customer to give it back to", payment.ID, sale.ID)
+			}
+			if _, err := tx.Customers.AdjustStoreCredit(ctx, *sale.CustomerID, payment.Amount); err != nil {
+				return err
+			}
+		case models.TenderCard, models.TenderMobileMoney:
+			charges = append(charges, payment.Reference)
+		}
+		refunded = append(refunded, payment.ID)
+		total += payment.Amount
+	}
+
+	if err := tx.Payments.MarkRefunded(ctx, refunded, time.Now()); err != nil {
+		return err
+	}
+	for i, reference := range charges {
+		if err := s.provider.Refund(ctx, reference); err != nil {
+			if i > 0 {
+				fmt.Printf("Charges %v of sale %d were refunded before the refund of %s failed\n", charges[:i], sale.ID, reference)
+			}
+			return fmt.Errorf("could not refund charge %s: %w", reference, err)
+		}
+	}
+
+	sale.RefundedTotal = roundCents(sale.RefundedTotal + total)
+	return nil
+}
+
+// GetSalePayments lists the payments of a sale and what is left to pay.
+func (s *PaymentService) GetSalePayments(ctx context.Context, saleID string) (PaymentSummary, error) {
+	id, err := parseSaleID(saleID)
+	if err != nil {
+		return PaymentSummary{}, err
+	}
+
+	sale, err := s.store.Sales.FindByID(ctx, id)
+	if err != nil {
+		return PaymentSummary{}, err
+	}
+
+	payments, err := s.payments.FindBySale(ctx, id)
+	if err != nil {
+		return PaymentSummary{}, err
+	}
+
+	return PaymentSummary{
+		Payments:  payments,
+		Total:     sale.Total,
+		PaidTotal: sale.PaidTotal,
+		Balance:   roundCents(sale.Total - sale.PaidTotal),
+	}, nil
+}
+
+// spendStoreCredit takes amount from the store credit of the sale's
+// customer.
+func spendStoreCredit(ctx context.Context, customers repository.CustomerRepository, sale models.Sale, amount float64) error {
+	if sale.CustomerID == nil {
+		return invalidSale("store credit needs a sale with a customer")
+	}
+
+	ok, err := customers.AdjustStoreCredit(ctx, *sale.CustomerID, -amount)
+	if errors.Is(err, repository.ErrNotFound) {
+		return invalidSale("customer %d does not exist", *sale.CustomerID)
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return invalidSale("customer %d has less than %.2f of store credit", *sale.CustomerID, amount)
+	}
+	return nil
+}
+
+// roundCents rounds an amount of money to the cent.
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
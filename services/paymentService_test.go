@@ -0,0 +1,102 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"productmanagerapi/models"
+	"productmanagerapi/payments"
+	"productmanagerapi/repository"
+	"testing"
+)
+
+func TestSaleTotalsAndPayments(t *testing.T) {
+	forEachStore(t, func(t *testing.T, services *Services) {
+		ctx := asAdmin()
+		product := createProduct(t, ctx, services, 10, 10)
+		if _, err := services.TaxRates.CreateTaxRate(ctx, body(t, map[string]any{"Name": "VAT", "Rate": 20, "ProductID": product.ID})); err != nil {
+			t.Fatal(err)
+		}
+
+		sale, err := services.Sales.CreateSale(ctx, body(t, map[string]any{
+			"products": []map[string]any{{"product_id": product.ID, "quantity": 2}},
+			"status":   models.SaleConfirmed,
+			"discount": map[string]any{"type": models.DiscountPercentage, "value": 10, "reason": "regular customer"},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sale.Subtotal != 20 || sale.DiscountTotal != 2 || sale.TaxTotal != 3.6 || sale.Total != 21.6 {
+			t.Fatalf("got subtotal %v discount %v tax %v total %v, want 20, 2, 3.6 and 21.6", sale.Subtotal, sale.DiscountTotal, sale.TaxTotal, sale.Total)
+		}
+
+		id := fmt.Sprint(sale.ID)
+		_, err = services.Payments.CreatePayment(ctx, id, body(t, map[string]any{
+			"tenders": []map[string]any{{"method": "cash", "amount": 5}, {"method": "card", "amount": 10, "token": "decline_card"}},
+		}))
+		if !errors.Is(err, payments.ErrDeclined) {
+			t.Fatalf("got %v, want the card declined", err)
+		}
+		summary, err := services.Payments.GetSalePayments(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(summary.Payments) != 0 || summary.Balance != 21.6 {
+			t.Fatalf("a declined payment records nothing, got %d payments and balance %v", len(summary.Payments), summary.Balance)
+		}
+
+		result, err := services.Payments.CreatePayment(ctx, id, body(t, map[string]any{
+			"tenders": []map[string]any{{"method": "card", "amount": 10, "token": "tok_visa"}},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Balance != 11.6 || result.Sale.Status != models.SaleConfirmed {
+			t.Fatalf("got balance %v status %s, want 11.6 left on a confirmed sale", result.Balance, result.Sale.Status)
+		}
+
+		result, err = services.Payments.CreatePayment(ctx, id, body(t, map[string]any{
+			"tenders": []map[string]any{{"method": "cash", "tendered": 20}},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Balance != 0 || result.Change != 8.4 || result.Sale.PaidTotal != 21.6 {
+			t.Fatalf("got balance %v change %v paid %v, want 0, 8.4 and 21.6", result.Balance, result.Change, result.Sale.PaidTotal)
+		}
+		if result.Sale.Status != models.SalePaid || result.Sale.InvoiceNumber == nil || *result.Sale.InvoiceNumber != 1 {
+			t.Fatalf("got status %s invoice %v, want paid with invoice 1", result.Sale.Status, result.Sale.InvoiceNumber)
+		}
+	})
+}
+
+func TestNoProviderRefusesCards(t *testing.T) {
+	services := New(repository.NewMemoryStore(), payments.NewNoneProvider())
+	ctx := asAdmin()
+	product := createProduct(t, ctx, services, 10, 10)
+
+	sale, err := services.Sales.CreateSale(ctx, body(t, map[string]any{
+		"products": []map[string]any{{"product_id": product.ID, "quantity": 1}},
+		"status":   models.SaleConfirmed,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := fmt.Sprint(sale.ID)
+
+	_, err = services.Payments.CreatePayment(ctx, id, body(t, map[string]any{
+		"tenders": []map[string]any{{"method": "cash", "amount": 4}, {"method": "card", "token": "tok_visa"}},
+	}))
+	if !errors.Is(err, payments.ErrUnavailable) {
+		t.Fatalf("got %v, want cards refused without a provider", err)
+	}
+
+	result, err := services.Payments.CreatePayment(ctx, id, body(t, map[string]any{
+		"tenders": []map[string]any{{"method": "cash", "tendered": 10}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Balance != 0 || len(result.Payments) != 1 {
+		t.Fatalf("got balance %v with %d payments, want the sale paid in cash alone", result.Balance, len(result.Payments))
+	}
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"productmanagerapi/auth"
+	"productmanagerapi/models"
+	"productmanagerapi/repository"
+	"productmanagerapi/types"
+	"strings"
+	"time"
+)
+
+// ErrDiscountNotAllowed is returned when a caller who may not give discounts
+// puts a manual discount on a sale.
+var ErrDiscountNotAllowed = errors.New("giving a discount requires the " + string(auth.SalesDiscount) + " permission")
+
+// priceSale works out the amount of every discount and tax of the sale and
+// the totals that follow from them. Line discounts apply in turn to what is
+// left of their line, then sale-wide discounts apply in turn to what is left
+// of the lines together. No discount takes more than what is left, so totals
+// never go negative. Lines are then taxed, see taxLine, on their share of
+// what is left.
+func priceSale(sale *models.Sale) {
+	byLine := map[uint][]int{}
+	var orderDiscounts []int
+	for i, discount := range sale.Discounts {
+		if discount.SaleProductID == nil {
+			orderDiscounts = append(orderDiscounts, i)
+			continue
+		}
+		byLine[*discount.SaleProductID] = append(byLine[*discount.SaleProductID], i)
+	}
+
+	sale.Subtotal = 0
+	sale.DiscountTotal = 0
+	var net float64
+	for i := range sale.Products {
+		line := &sale.Products[i]
+		gross := roundCents(float64(line.Quantity) * line.UnitPrice)
+		left := gross
+		for _, d := range byLine[line.ID] {
+			discount := &sale.Discounts[d]
+			discount.Amount = min(discountAmount(*discount, left, line.Quantity, line.UnitPrice), left)
+			left = roundCents(left - discount.Amount)
+		}
+		line.Discount = roundCents(gross - left)
+		line.Total = left
+
+		sale.Subtotal += gross
+		sale.DiscountTotal += line.Discount
+		net += left
+	}
+
+	net = roundCents(net)
+	left := net
+	for _, d := range orderDiscounts {
+		discount := &sale.Discounts[d]
+		discount.Amount = min(discountAmount(*discount, left, 0, 0), left)
+		left = roundCents(left - discount.Amount)
+		sale.DiscountTotal += discount.Amount
+	}
+
+	// Sale-wide discounts are shared between the lines in proportion to
+	// their totals, the last line taking the rounding difference.
+	var shared, exclusive float64
+	sale.TaxTotal = 0
+	for i := range sale.Products {
+		line := &sale.Products[i]
+		amount := 0.0
+		switch {
+		case i == len(sale.Products)-1:
+			amount = roundCents(left - shared)
+		case net > 0:
+			amount = roundCents(line.Total * left / net)
+		}
+		shared += amount
+
+		exclusive += taxLine(line, amount)
+		sale.TaxTotal += line.Tax
+	}
+	sale.Taxes = saleTaxes(sale.Products)
+
+	sale.Subtotal = roundCents(sale.Subtotal)
+	sale.DiscountTotal = roundCents(sale.DiscountTotal)
+	sale.TaxTotal = roundCents(sale.TaxTotal)
+	sale.Total = roundCents(left + exclusive)
+}
+
+// discountAmount is what discount takes off base, the part of a line or sale
+// still to pay. quantity and unitPrice describe the line and are only used
+// by per-unit and buy_x_get_y discounts.
+func discountAmount(discount models.SaleDiscount, base float64, quantity int, unitPrice float64) float64 {
+	switch discount.Kind {
+	case models.DiscountPercentage:
+		return roundCents(base * discount.Value / 100)
+	case models.DiscountFixed:
+		return roundCents(discount.Value)
+	case models.DiscountFixedPerUnit:
+		return roundCents(discount.Value * float64(quantity))
+	case models.DiscountBuyXGetY:
+		group := discount.BuyQuantity + discount.FreeQuantity
+		if group <= 0 {
+			return 0
+		}
+		free := quantity / group * discount.FreeQuantity
+		return roundCents(float64(free) * unitPrice)
+	}
+	return 0
+}
+
+// netUnitPrice is what one item of line finally cost: what it was taxed on,
+// after every discount, plus its taxes.
+func netUnitPrice(line models.SaleProduct) float64 {
+	if line.Quantity == 0 {
+		return 0
+	}
+	return roundCents((line.TaxableAmount + line.Tax) / float64(line.Quantity))
+}
+
+// manualDiscount turns a discount from a request into a sale discount,
+// checking the caller may give it. lineID is the line it applies to, or nil
+// for the whole sale.
+func manualDiscount(ctx context.Context, request types.Discount, lineID *uint) (models.SaleDiscount, error) {
+	principal, _ := auth.PrincipalFrom(ctx)
+	if !principal.Role.Can(auth.SalesDiscount) {
+		return models.SaleDiscount{}, ErrDiscountNotAllowed
+	}
+
+	if request.Type != models.DiscountPercentage && request.Type != models.DiscountFixed {
+		return models.SaleDiscount{}, invalidSale("discount type must be percentage or fixed")
+	}
+	if err := validateDiscountValue(request.Type, request.Value); err != nil {
+		return models.SaleDiscount{}, err
+	}
+
+	reason := strings.TrimSpace(request.Reason)
+	if reason == "" {
+		return models.SaleDiscount{}, invalidSale("a reason is required for a discount")
+	}
+
+	return models.SaleDiscount{
+		SaleProductID: lineID,
+		Source:        models.DiscountSourceManual,
+		Description:   reason,
+		Kind:          request.Type,
+		Value:         request.Value,
+		AppliedByID:   principal.UserID,
+	}, nil
+}
+
+// validateDiscountValue checks value makes sense for a discount of kind:
+// percentages go up to 100 and every value is positive.
+func validateDiscountValue(kind string, value float64) error {
+	if value <= 0 {
+		return invalidSale("discount value must be positive")
+	}
+	if kind == models.DiscountPercentage && value > 100 {
+		return invalidSale("a percentage discount cannot exceed 100")
+	}
+	return nil
+}
+
+// promotionDiscount picks the promotion among promotions that takes the
+// most off line, if any applies to it. Lines sold at an overridden price get
+// no promotion: the override already is the price agreed.
+func promotionDiscount(line models.SaleProduct, promotions []models.Promotion) (models.SaleDiscount, bool) {
+	if line.OverriddenByID != nil {
+		return models.SaleDiscount{}, false
+	}
+
+	gross := roundCents(float64(line.Quantity) * line.UnitPrice)
+	var best models.SaleDiscount
+	for _, promotion := range promotions {
+		matches := (promotion.ProductID != nil && *promotion.ProductID == line.ProductID) ||
+			(promotion.CategoryID != nil && *promotion.CategoryID == line.CategoryID)
+		if !matches {
+			continue
+		}
+
+		lineID := line.ID
+		promotionID := promotion.ID
+		discount := models.SaleDiscount{
+			SaleID:        line.SaleID,
+			SaleProductID: &lineID,
+			Source:        models.DiscountSourcePromotion,
+			PromotionID:   &promotionID,
+			Description:   promotion.Name,
+			Kind:          promotion.Kind,
+			Value:         promotion.Value,
+			BuyQuantity:   promotion.BuyQuantity,
+			FreeQuantity:  promotion.FreeQuantity,
+		}
+		discount.Amount = min(discountAmount(discount, gross, line.Quantity, line.UnitPrice), gross)
+		if discount.Amount > best.Amount {
+			best = discount
+		}
+	}
+	return best, best.Amount > 0
+}
+
+// redeemCoupon checks the coupon code can be used on a sale whose lines come
+// to net after their discounts, counts one use of it and returns the
+// discount it gives.
+func redeemCoupon(ctx context.Context, coupons repository.CouponRepository, code string, net float64, at time.Time) (models.SaleDiscount, error) {
+	code = normalizeCouponCode(code)
+	coupon, err := coupons.FindByCode(ctx, code)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.SaleDiscount{}, invalidSale("coupon %s does not exist", code)
+	}
+	if err != nil {
+		return models.SaleDiscount{}, err
+	}
+
+	switch {
+	case coupon.Disabled:
+		return models.SaleDiscount{}, invalidSale("coupon %s is disabled", code)
+	case coupon.StartsAt != nil && coupon.StartsAt.After(at):
+		return models.SaleDiscount{}, invalidSale("coupon %s is not valid before %s", code, coupon.StartsAt.Format(time.RFC3339))
+	case coupon.EndsAt != nil && !coupon.EndsAt.After(at):
+		return models.SaleDiscount{}, invalidSale("coupon %s expired on %s", code, coupon.EndsAt.Format(time.RFC3339))
+	case net < coupon.MinSubtotal:
+		return models.SaleDiscount{}, invalidSale("coupon %s needs a sale of at least %.2f", code, coupon.MinSubtotal)
+	}
+
+	ok, err := coupons.Redeem(ctx, coupon.ID)
+	if err != nil {
+		return models.SaleDiscount{}, err
+	}
+	if !ok {
+		return models.SaleDiscount{}, invalidSale("coupon %s has been used up", code)
+	}
+
+	couponID := coupon.ID
+	description := coupon.Description
+	if description == "" {
+		description = coupon.Code
+	}
+	return models.SaleDiscount{
+		Source:      models.DiscountSourceCoupon,
+		CouponID:    &couponID,
+		Description: description,
+		Kind:        coupon.Kind,
+		Value:       coupon.Value,
+	}, nil
+}
+
+// releaseCoupon gives back the use of the coupon that discount redeemed, if
+// it came from one, when the discount goes away with its sale or is dropped
+// by an amendment.
+func releaseCoupon(ctx context.Context, coupons repository.CouponRepository, discount models.SaleDiscount) error {
+	if discount.Source != models.DiscountSourceCoupon || discount.CouponID == nil {
+		return nil
+	}
+	return coupons.Release(ctx, *discount.CouponID)
+}
+
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// saveSalePricing stores what priceSale worked out: the discounts, the
+// lines, the taxes and the sale's totals.
+func saveSalePricing(ctx context.Context, sales repository.SaleRepository, sale *models.Sale) error {
+	for i := range sale.Discounts {
+		sale.Discounts[i].SaleID = sale.ID
+		if err := sales.SaveDiscount(ctx, &sale.Discounts[i]); err != nil {
+			return err
+		}
+	}
+	for i := range sale.Products {
+		if err := sales.SaveLine(ctx, &sale.Products[i]); err != nil {
+			return err
+		}
+	}
+	if err := sales.SaveTaxes(ctx, sale); err != nil {
+		return err
+	}
+	return sales.Update(ctx, sale)
+}
@@ -1,112 +1,365 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
-	"productmanagerapi/config"
+	"net/url"
+	"productmanagerapi/auth"
 	"productmanagerapi/models"
+	"productmanagerapi/repository"
+	"productmanagerapi/types"
+	"strconv"
+	"strings"
+	"time"
 )
 
-var GetAllProducts = func() ([]models.Product, error) {
-	listProducts := []models.Product{}
-	products := config.Db.Preload("Category").Find(&listProducts)
+type ProductService struct {
+	store      *repository.Store
+	products   repository.ProductRepository
+	categories repository.CategoryRepository
+}
+
+func NewProductService(store *repository.Store) *ProductService {
+	return &ProductService{store: store, products: store.Products, categories: store.Categories}
+}
+
+// productCursor is where a page of products ends: the last product's
+// position in the Sort of the page, as given in ?sort=.
+type productCursor struct {
+	Sort       string    `json:"sort"`
+	ID         uint      `json:"id"`
+	Name       string    `json:"name"`
+	Price      float64   `json:"price"`
+	Stock      int       `json:"stock"`
+	CategoryID uint      `json:"category_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
 
-	if products.Error != nil {
-		return nil, products.Error
+// GetAllProducts lists a page of the products matching the filters of
+// query:
+//
+//   - ?category_id=, ?created_by=
+//   - ?min_price= and ?max_price=, inclusive
+//   - ?in_stock=true, for products with stock left once reservations are
+//     taken off
+//   - ?name=, for names containing it, ignoring case
+//   - ?created_since=, a date or an RFC 3339 time
+//
+// ?sort= lists the fields to order on, see parseSorts, by ID by default.
+// Pages follow each other by ?cursor=, or by ?offset= when it is given.
+// With ?fields=, products only have those of their JSON fields.
+func (s *ProductService) GetAllProducts(ctx context.Context, query url.Values) (any, Page, error) {
+	var filter repository.ProductFilter
+	var err error
+
+	if filter.CreatedByID, err = parseUserFilter(query, "created_by"); err != nil {
+		return nil, Page{}, err
+	}
+	if filter.CategoryID, err = parseIDFilter(query, "category_id"); err != nil {
+		return nil, Page{}, err
+	}
+	if filter.MinPrice, err = parseAmountFilter(query, "min_price"); err != nil {
+		return nil, Page{}, err
+	}
+	if filter.MaxPrice, err = parseAmountFilter(query, "max_price"); err != nil {
+		return nil, Page{}, err
+	}
+	if filter.InStock, err = parseBoolFilter(query, "in_stock"); err != nil {
+		return nil, Page{}, err
+	}
+	filter.NameContains = strings.TrimSpace(query.Get("name"))
+	if filter.CreatedFrom, err = parseTimeFilter(query, "created_since"); err != nil {
+		return nil, Page{}, err
 	}
 
-	if len(listProducts) == 0 {
-		return []models.Product{}, nil
+	fields, err := parseFields(query, models.Product{})
+	if err != nil {
+		return nil, Page{}, err
+	}
+	sorts, err := parseSorts(query, []string{
+		repository.ProductSortID, repository.ProductSortName, repository.ProductSortPrice,
+		repository.ProductSortStock, repository.ProductSortCategoryID, repository.ProductSortCreatedAt,
+	}, repository.ProductSortID)
+	if err != nil {
+		return nil, Page{}, err
 	}
+	sortKey := formatSorts(sorts)
 
-	return listProducts, nil
-}
+	var page repository.ProductPage
+	for _, sort := range sorts {
+		page.Sorts = append(page.Sorts, repository.ProductSort{Field: sort.Name, Desc: sort.Desc})
+	}
+	if page.Limit, err = parseLimit(query); err != nil {
+		return nil, Page{}, err
+	}
+	offset, paged, err := parseOffset(query)
+	if err != nil {
+		return nil, Page{}, err
+	}
+	if value := strings.TrimSpace(query.Get("cursor")); value != "" {
+		if paged {
+			return nil, Page{}, fmt.Errorf("%w: cursor and offset cannot be used together", ErrInvalidFilter)
+		}
+		var cursor productCursor
+		if err := decodeCursor(value, &cursor); err != nil {
+			return nil, Page{}, err
+		}
+		if cursor.Sort != sortKey {
+			return nil, Page{}, fmt.Errorf("%w: cursor was made for sort=%s", ErrInvalidFilter, cursor.Sort)
+		}
+		page.After = &models.Product{Name: cursor.Name, Price: cursor.Price, Stock: cursor.Stock, CategoryID: cursor.CategoryID}
+		page.After.ID = cursor.ID
+		page.After.CreatedAt = cursor.CreatedAt
+	}
+	page.Offset = offset
 
-var GetProductByID = func(productID string) (models.Product, error) {
-	if productID == "" {
-		return models.Product{}, errors.New("product ID is required")
+	// One product more than the page tells whether another page follows.
+	limit := page.Limit
+	page.Limit++
+	products, total, err := s.products.FindPage(ctx, filter, page)
+	if err != nil {
+		return nil, Page{}, err
 	}
 
-	var product models.Product
-	result := config.Db.Preload("Category").First(&product, "id = ?", productID)
+	result := Page{Limit: limit, Total: total}
+	if paged {
+		result.Offset = &offset
+	}
+	if len(products) > limit {
+		products = products[:limit]
+		if !paged {
+			last := products[limit-1]
+			result.NextCursor = encodeCursor(productCursor{
+				Sort:       sortKey,
+				ID:         last.ID,
+				Name:       last.Name,
+				Price:      last.Price,
+				Stock:      last.Stock,
+				CategoryID: last.CategoryID,
+				CreatedAt:  last.CreatedAt,
+			})
+		}
+	}
 
-	if result.Error != nil {
-		return models.Product{}, result.Error
+	if fields == nil {
+		return products, result, nil
+	}
+	selected, err := selectFields(products, fields)
+	if err != nil {
+		return nil, Page{}, err
 	}
+	return selected, result, nil
+}
 
-	return product, nil
+func (s *ProductService) GetProductByID(ctx context.Context, productID string) (models.Product, error) {
+	id, err := parseProductID(productID)
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	return s.products.FindByID(ctx, id)
 }
 
-var CreateProduct = func(body io.ReadCloser) (models.Product, error) {
+func (s *ProductService) CreateProduct(ctx context.Context, body io.ReadCloser) (models.Product, error) {
 	var product models.Product
 	if err := json.NewDecoder(body).Decode(&product); err != nil {
 		return models.Product{}, errors.New("invalid request body: " + err.Error())
 	}
 
-	if product.Name == "" {
-		return models.Product{}, errors.New("product name is required")
+	if err := validateProduct(product); err != nil {
+		return models.Product{}, err
 	}
+	product.Reserved = 0
+	product.CreatedByID = auth.UserID(ctx)
+	product.UpdatedByID = product.CreatedByID
 
-	if product.Price <= 0 {
-		return models.Product{}, errors.New("product price must be greater than zero")
+	if err := assignCategory(ctx, s.categories, &product, product.CategoryID); err != nil {
+		return models.Product{}, err
 	}
 
-	if product.Stock < 0 {
-		return models.Product{}, errors.New("product stock cannot be negative")
+	if err := s.products.Create(ctx, &product); err != nil {
+		return models.Product{}, err
+	}
+
+	return product, nil
+}
+
+func (s *ProductService) UpdateProduct(ctx context.Context, productID string, body io.ReadCloser) (models.Product, error) {
+	id, err := parseProductID(productID)
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	var changes models.Product
+	if err := json.NewDecoder(body).Decode(&changes); err != nil {
+		return models.Product{}, errors.New("invalid request body: " + err.Error())
+	}
+
+	if err := validateProduct(changes); err != nil {
+		return models.Product{}, err
 	}
 
-	result := config.Db.Create(&product)
-	if result.Error != nil {
-		return models.Product{}, result.Error
+	var product models.Product
+	err = s.store.Transaction(ctx, func(tx *repository.Store) error {
+		product, err = tx.Products.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		// Only fields present in the body are changed; zero values keep the
+		// stored value.
+		product.Name = changes.Name
+		product.Price = changes.Price
+		if changes.Description != "" {
+			product.Description = changes.Description
+		}
+		if changes.Stock != 0 {
+			product.Stock = changes.Stock
+		}
+		if changes.CategoryID != 0 && changes.CategoryID != product.CategoryID {
+			if err := assignCategory(ctx, tx.Categories, &product, changes.CategoryID); err != nil {
+				return err
+			}
+		}
+
+		return s.saveProduct(ctx, tx, &product)
+	})
+	if err != nil {
+		return models.Product{}, err
 	}
 
 	return product, nil
 }
 
-var UpdateProduct = func(productID string, body io.ReadCloser) (models.Product, error) {
-	if productID == "" {
-		return models.Product{}, errors.New("product ID is required")
+// PatchProduct changes only the fields present in the body.
+func (s *ProductService) PatchProduct(ctx context.Context, productID string, body io.ReadCloser) (models.Product, error) {
+	id, err := parseProductID(productID)
+	if err != nil {
+		return models.Product{}, err
 	}
 
-	var product models.Product
-	if err := json.NewDecoder(body).Decode(&product); err != nil {
+	var patch types.ProductPatch
+	if err := json.NewDecoder(body).Decode(&patch); err != nil {
 		return models.Product{}, errors.New("invalid request body: " + err.Error())
 	}
 
-	if product.Name == "" {
-		return models.Product{}, errors.New("product name is required")
+	var product models.Product
+	err = s.store.Transaction(ctx, func(tx *repository.Store) error {
+		product, err = tx.Products.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if patch.Name != nil {
+			product.Name = *patch.Name
+		}
+		if patch.Description != nil {
+			product.Description = *patch.Description
+		}
+		if patch.Price != nil {
+			product.Price = *patch.Price
+		}
+		if patch.Stock != nil {
+			product.Stock = *patch.Stock
+		}
+		if patch.CategoryID != nil && *patch.CategoryID != product.CategoryID {
+			if err := assignCategory(ctx, tx.Categories, &product, *patch.CategoryID); err != nil {
+				return err
+			}
+		}
+
+		if err := validateProduct(product); err != nil {
+			return err
+		}
+		return s.saveProduct(ctx, tx, &product)
+	})
+	if err != nil {
+		return models.Product{}, err
 	}
 
-	if product.Price <= 0 {
-		return models.Product{}, errors.New("product price must be greater than zero")
+	return product, nil
+}
+
+// saveProduct saves the changes to product, locked by the transaction tx,
+// and reloads it with its category. The stock cannot go below what confirmed
+// and paid sales reserved.
+func (s *ProductService) saveProduct(ctx context.Context, tx *repository.Store, product *models.Product) error {
+	if product.Stock < product.Reserved {
+		return fmt.Errorf("product stock cannot go below the %d items reserved by confirmed and paid sales", product.Reserved)
 	}
 
-	if product.Stock < 0 {
-		return models.Product{}, errors.New("product stock cannot be negative")
+	product.UpdatedByID = auth.UserID(ctx)
+	if err := tx.Products.Update(ctx, product); err != nil {
+		return err
 	}
 
-	result := config.Db.Model(&models.Product{}).Where("id = ?", productID).Updates(product)
-	if result.Error != nil {
-		return models.Product{}, result.Error
+	reloaded, err := tx.Products.FindByID(ctx, product.ID)
+	if err != nil {
+		return err
 	}
+	*product = reloaded
+	return nil
+}
 
-	return product, nil
+func (s *ProductService) DeleteProduct(ctx context.Context, productID string) error {
+	id, err := parseProductID(productID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.products.Delete(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errors.New("no product found with the given ID")
+		}
+		return err
+	}
+
+	return nil
 }
 
-var DeleteProduct = func(productID string) error {
-	if productID == "" {
-		return errors.New("product ID is required")
+// assignCategory points product at an existing category.
+func assignCategory(ctx context.Context, categories repository.CategoryRepository, product *models.Product, categoryID uint) error {
+	category, err := categories.FindByID(ctx, categoryID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errors.New("product category does not exist")
+		}
+		return err
 	}
 
-	result := config.Db.Delete(&models.Product{}, "id = ?", productID)
-	if result.Error != nil {
-		return result.Error
+	product.CategoryID = category.ID
+	product.Category = category
+	return nil
+}
+
+func validateProduct(product models.Product) error {
+	if product.Name == "" {
+		return errors.New("product name is required")
 	}
 
-	if result.RowsAffected == 0 {
-		return errors.New("no product found with the given ID")
+	if product.Price <= 0 {
+		return errors.New("product price must be greater than zero")
+	}
+
+	if product.Stock < 0 {
+		return errors.New("product stock cannot be negative")
 	}
 
 	return nil
 }
+
+func parseProductID(productID string) (uint, error) {
+	if productID == "" {
+		return 0, errors.New("product ID is required")
+	}
+
+	id, err := strconv.ParseUint(productID, 10, 64)
+	if err != nil || id == 0 {
+		return 0, errors.New("invalid product ID")
+	}
+
+	return uint(id), nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"productmanagerapi/models"
+	"strings"
+	"testing"
+)
+
+func TestUpdateProductKeepsReservedStock(t *testing.T) {
+	forEachStore(t, func(t *testing.T, services *Services) {
+		ctx := asAdmin()
+		product := createProduct(t, ctx, services, 10, 5)
+		if _, err := services.Sales.CreateSale(ctx, body(t, map[string]any{
+			"products": []map[string]any{{"product_id": product.ID, "quantity": 3}},
+			"status":   models.SaleConfirmed,
+		})); err != nil {
+			t.Fatal(err)
+		}
+		id := fmt.Sprint(product.ID)
+
+		if _, err := services.Products.PatchProduct(ctx, id, body(t, map[string]any{"Stock": 2})); err == nil {
+			t.Fatal("patching the stock below the 3 reserved items must fail")
+		}
+		if _, err := services.Products.UpdateProduct(ctx, id, body(t, map[string]any{"Name": "Hammer", "Price": 10, "Stock": 2})); err == nil {
+			t.Fatal("updating the stock below the 3 reserved items must fail")
+		}
+		if stock, reserved := stockOf(t, ctx, services, product.ID); stock != 5 || reserved != 3 {
+			t.Fatalf("a refused update leaves the stock alone, got stock %d reserved %d", stock, reserved)
+		}
+
+		updated, err := services.Products.PatchProduct(ctx, id, body(t, map[string]any{"Stock": 3}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Stock != 3 || updated.Reserved != 3 || updated.Category.Name != "Tools" {
+			t.Fatalf("got stock %d reserved %d category %q, want 3, 3 and the product's category", updated.Stock, updated.Reserved, updated.Category.Name)
+		}
+	})
+}
+
+func TestGetAllProductsPagesAndFilters(t *testing.T) {
+	forEachStore(t, func(t *testing.T, services *Services) {
+		ctx := asAdmin()
+		category, err := services.Categories.CreateCategory(ctx, body(t, map[string]any{"Name": "Tools", "Description": "Hand tools"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, product := range []struct {
+			name  string
+			price float64
+			stock int
+		}{{"Claw hammer", 15, 4}, {"Saw", 30, 0}, {"Sledge hammer", 40, 2}, {"Chisel", 8, 9}} {
+			if _, err := services.Products.CreateProduct(ctx, body(t, map[string]any{
+				"Name": product.name, "Description": "d", "Price": product.price, "Stock": product.stock, "CategoryID": category.ID,
+			})); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		names := func(listed any) string {
+			var names []string
+			for _, product := range listed.([]models.Product) {
+				names = append(names, product.Name)
+			}
+			return strings.Join(names, ", ")
+		}
+
+		listed, page, err := services.Products.GetAllProducts(ctx, url.Values{"sort": {"-price"}, "limit": {"3"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names(listed) != "Sledge hammer, Saw, Claw hammer" || page.Total != 4 || page.NextCursor == "" {
+			t.Fatalf("got %s of %d, want the three dearest and a cursor", names(listed), page.Total)
+		}
+		listed, page, err = services.Products.GetAllProducts(ctx, url.Values{"sort": {"-price"}, "limit": {"3"}, "cursor": {page.NextCursor}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names(listed) != "Chisel" || page.NextCursor != "" {
+			t.Fatalf("got %s, want the cheapest product on the last page", names(listed))
+		}
+
+		listed, page, err = services.Products.GetAllProducts(ctx, url.Values{"sort": {"name"}, "limit": {"1"}, "offset": {"2"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names(listed) != "Saw" || page.Offset == nil || *page.Offset != 2 {
+			t.Fatalf("got %s at offset %v, want the third by name", names(listed), page.Offset)
+		}
+		if _, _, err := services.Products.GetAllProducts(ctx, url.Values{"offset": {"1"}, "cursor": {"abc"}}); !errors.Is(err, ErrInvalidFilter) {
+			t.Fatalf("got %v, want cursor and offset refused together", err)
+		}
+
+		listed, _, err = services.Products.GetAllProducts(ctx, url.Values{"name": {"HAMMER"}, "in_stock": {"true"}, "max_price": {"20"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names(listed) != "Claw hammer" {
+			t.Fatalf("got %s, want the claw hammer alone", names(listed))
+		}
+
+		listed, _, err = services.Products.GetAllProducts(ctx, url.Values{"fields": {"name,price"}, "limit": {"1"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		selected, ok := listed.([]map[string]json.RawMessage)
+		if !ok || len(selected) != 1 || len(selected[0]) != 2 || string(selected[0]["Name"]) != `"Claw hammer"` {
+			t.Fatalf("got %v, want only the name and price of the first product", listed)
+		}
+	})
+}
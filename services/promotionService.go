@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"productmanagerapi/auth"
+	"productmanagerapi/models"
+	"productmanagerapi/repository"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type PromotionService struct {
+	store      *repository.Store
+	promotions repository.PromotionRepository
+}
+
+func NewPromotionService(store *repository.Store) *PromotionService {
+	return &PromotionService{store: store, promotions: store.Promotions}
+}
+
+func (s *PromotionService) GetAllPromotions(ctx context.Context) ([]models.Promotion, error) {
+	return s.promotions.FindAll(ctx)
+}
+
+func (s *PromotionService) GetPromotionByID(ctx context.Context, promotionID string) (models.Promotion, error) {
+	id, err := parsePromotionID(promotionID)
+	if err != nil {
+		return models.Promotion{}, err
+	}
+
+	return s.promotions.FindByID(ctx, id)
+}
+
+func (s *PromotionService) CreatePromotion(ctx context.Context, body io.ReadCloser) (models.Promotion, error) {
+	var promotion models.Promotion
+	if err := json.NewDecoder(body).Decode(&promotion); err != nil {
+		return models.Promotion{}, errors.New("invalid request body: " + err.Error())
+	}
+
+	promotion.ID = 0
+	promotion.CreatedByID = auth.UserID(ctx)
+	promotion.UpdatedByID = promotion.CreatedByID
+	if err := s.validatePromotion(ctx, &promotion); err != nil {
+		return models.Promotion{}, err
+	}
+
+	if err := s.promotions.Create(ctx, &promotion); err != nil {
+		return models.Promotion{}, err
+	}
+
+	return promotion, nil
+}
+
+// UpdatePromotion replaces every editable field of the promotion. Sales
+// already discounted by it keep their discount.
+func (s *PromotionService) UpdatePromotion(ctx context.Context, promotionID string, body io.ReadCloser) (models.Promotion, error) {
+	id, err := parsePromotionID(promotionID)
+	if err != nil {
+		return models.Promotion{}, err
+	}
+
+	var promotion models.Promotion
+	if err := json.NewDecoder(body).Decode(&promotion); err != nil {
+		return models.Promotion{}, errors.New("invalid request body: " + err.Error())
+	}
+
+	var updated models.Promotion
+	err = s.store.Transaction(ctx, func(tx *repository.Store) error {
+		updated, err = tx.Promotions.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		updated.Name = promotion.Name
+		updated.Kind = promotion.Kind
+		updated.Value = promotion.Value
+		updated.BuyQuantity = promotion.BuyQuantity
+		updated.FreeQuantity = promotion.FreeQuantity
+		updated.ProductID = promotion.ProductID
+		updated.CategoryID = promotion.CategoryID
+		updated.StartsAt = promotion.StartsAt
+		updated.EndsAt = promotion.EndsAt
+		updated.Disabled = promotion.Disabled
+		updated.UpdatedByID = auth.UserID(ctx)
+		if err := s.validatePromotion(ctx, &updated); err != nil {
+			return err
+		}
+
+		return tx.Promotions.Update(ctx, &updated)
+	})
+	if err != nil {
+		return models.Promotion{}, err
+	}
+
+	return updated, nil
+}
+
+func (s *PromotionService) DeletePromotion(ctx context.Context, promotionID string) error {
+	id, err := parsePromotionID(promotionID)
+	if err != nil {
+		return err
+	}
+
+	return s.promotions.Delete(ctx, id)
+}
+
+// validatePromotion checks the promotion targets exactly one existing
+// product or category with a discount that makes sense.
+func (s *PromotionService) validatePromotion(ctx context.Context, promotion *models.Promotion) error {
+	promotion.Name = strings.TrimSpace(promotion.Name)
+	if promotion.Name == "" {
+		return errors.New("name is required")
+	}
+
+	switch promotion.Kind {
+	case models.DiscountPercentage, models.DiscountFixedPerUnit:
+		if err := validateDiscountValue(promotion.Kind, promotion.Value); err != nil {
+			return err
+		}
+		promotion.BuyQuantity = 0
+		promotion.FreeQuantity = 0
+	case models.DiscountBuyXGetY:
+		if promotion.BuyQuantity <= 0 || promotion.FreeQuantity <= 0 {
+			return errors.New("a buy_x_get_y promotion needs a positive BuyQuantity and FreeQuantity")
+		}
+		promotion.Value = 0
+	default:
+		return errors.New("kind must be percentage, fixed_per_unit or buy_x_get_y")
+	}
+
+	if err := validateWindow(promotion.StartsAt, promotion.EndsAt); err != nil {
+		return err
+	}
+
+	switch {
+	case (promotion.ProductID == nil) == (promotion.CategoryID == nil):
+		return errors.New("a promotion applies to either a ProductID or a CategoryID")
+	case promotion.ProductID != nil:
+		if _, err := s.store.Products.FindByID(ctx, *promotion.ProductID); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return fmt.Errorf("product %d does not exist", *promotion.ProductID)
+			}
+			return err
+		}
+	default:
+		if _, err := s.store.Categories.FindByID(ctx, *promotion.CategoryID); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return fmt.Errorf("category %d does not exist", *promotion.CategoryID)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// validateWindow checks a validity window ends after it starts.
+func validateWindow(startsAt, endsAt *time.Time) error {
+	if startsAt != nil && endsAt != nil && !endsAt.After(*startsAt) {
+		return errors.New("EndsAt must be after StartsAt")
+	}
+	return nil
+}
+
+func parsePromotionID(promotionID string) (uint, error) {
+	if promotionID == "" {
+		return 0, errors.New("The promotion id is required")
+	}
+
+	id, err := strconv.ParseUint(promotionID, 10, 64)
+	if err != nil || id == 0 {
+		return 0, errors.New("The promotion id is invalid")
+	}
+
+	return uint(id), nil
+}
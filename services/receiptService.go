@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"productmanagerapi/auth"
+	"productmanagerapi/config"
+	"productmanagerapi/models"
+	"productmanagerapi/receipts"
+	"productmanagerapi/repository"
+	"strconv"
+	"strings"
+)
+
+type ReceiptService struct {
+	store *repository.Store
+}
+
+func NewReceiptService(store *repository.Store) *ReceiptService {
+	return &ReceiptService{store: store}
+}
+
+// GetReceipt renders the receipt of a sale, an invoice once it is paid, with
+// the store details of the configuration. The ?format= query parameter
+// picks html, the default, text for 80 mm thermal printers or pdf, and
+// ?reprint=true marks the receipt as a copy. Any sale can be printed again
+// at any time: receipts are rendered from the sale as it stands. The
+// customer's name and address are only printed for callers allowed to read
+// customers.
+func (s *ReceiptService) GetReceipt(ctx context.Context, saleID string, query url.Values) (receipts.Document, error) {
+	id, err := parseSaleID(saleID)
+	if err != nil {
+		return receipts.Document{}, err
+	}
+
+	format := strings.ToLower(strings.TrimSpace(query.Get("format")))
+	if format == "" {
+		format = receipts.FormatHTML
+	}
+	reprint := false
+	if value := strings.TrimSpace(query.Get("reprint")); value != "" {
+		reprint, err = strconv.ParseBool(value)
+		if err != nil {
+			return receipts.Document{}, errors.New("reprint must be true or false")
+		}
+	}
+
+	sale, err := s.store.Sales.FindByID(ctx, id)
+	if err != nil {
+		return receipts.Document{}, err
+	}
+
+	receipt := receipts.Receipt{Store: config.App.Store, Sale: sale, Reprint: reprint}
+	principal, _ := auth.PrincipalFrom(ctx)
+	if sale.CustomerID != nil && principal.Role.Can(auth.CustomersRead) {
+		customer, err := s.store.Customers.FindByID(ctx, *sale.CustomerID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return receipts.Document{}, err
+		}
+		if err == nil {
+			receipt.Customer = &customer
+		}
+	}
+	if sale.CreatedByID != 0 {
+		cashier, err := s.store.Users.FindByID(ctx, sale.CreatedByID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return receipts.Document{}, err
+		}
+		receipt.Cashier = cashier.Username
+	}
+
+	return receipts.Render(receipt, format)
+}
+
+// issueInvoice gives a sale being paid the next invoice number. It runs in
+// the transaction that saves the sale paid, so a number is only used when
+// that transaction commits and invoice numbers have no gaps.
+func issueInvoice(ctx context.Context, sequences repository.SequenceRepository, sale *models.Sale) error {
+	if sale.InvoiceNumber != nil {
+		return nil
+	}
+
+	number, err := sequences.Next(ctx, models.SequenceInvoice)
+	if err != nil {
+		return fmt.Errorf("numbering the invoice: %w", err)
+	}
+	sale.InvoiceNumber = &number
+	return nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"productmanagerapi/auth"
+	"productmanagerapi/models"
+	"productmanagerapi/payments"
+	"strings"
+	"testing"
+)
+
+func TestInvoiceNumbersHaveNoGaps(t *testing.T) {
+	forEachStore(t, func(t *testing.T, services *Services) {
+		ctx := asAdmin()
+		product := createProduct(t, ctx, services, 10, 10)
+
+		var ids []string
+		for range 2 {
+			sale, err := services.Sales.CreateSale(ctx, body(t, map[string]any{
+				"products": []map[string]any{{"product_id": product.ID, "quantity": 1}},
+				"status":   models.SaleConfirmed,
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sale.InvoiceNumber != nil {
+				t.Fatalf("got invoice %d, want confirmed sales left without an invoice number", *sale.InvoiceNumber)
+			}
+			ids = append(ids, fmt.Sprint(sale.ID))
+		}
+
+		_, err := services.Payments.CreatePayment(ctx, ids[0], body(t, map[string]any{
+			"tenders": []map[string]any{{"method": "card", "amount": 10, "token": "decline_card"}},
+		}))
+		if !errors.Is(err, payments.ErrDeclined) {
+			t.Fatalf("got %v, want the card declined", err)
+		}
+
+		for i, id := range []string{ids[1], ids[0]} {
+			result, err := services.Payments.CreatePayment(ctx, id, body(t, map[string]any{
+				"tenders": []map[string]any{{"method": "cash", "amount": 10}},
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Sale.InvoiceNumber == nil || *result.Sale.InvoiceNumber != uint(i+1) {
+				t.Fatalf("sale %s got invoice %v, want %d: a declined payment uses no number", id, result.Sale.InvoiceNumber, i+1)
+			}
+		}
+
+		document, err := services.Receipts.GetReceipt(ctx, ids[0], url.Values{"format": {"text"}, "reprint": {"true"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text := string(document.Body); !strings.Contains(text, "000002") || !strings.Contains(text, "*** COPY ***") {
+			t.Fatalf("got receipt\n%s\nwant invoice 2 marked as a copy", text)
+		}
+	})
+}
+
+func TestReceiptsHideCustomersFromViewers(t *testing.T) {
+	forEachStore(t, func(t *testing.T, services *Services) {
+		ctx := asAdmin()
+		product := createProduct(t, ctx, services, 10, 10)
+		customer, err := services.Customers.CreateCustomer(ctx, body(t, map[string]any{"Name": "Ada Lovelace", "Email": "ada@example.com"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sale, err := services.Sales.CreateSale(ctx, body(t, map[string]any{
+			"products":    []map[string]any{{"product_id": product.ID, "quantity": 1}},
+			"customer_id": customer.ID,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		viewer := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 2, Username: "viewer", Role: auth.RoleViewer})
+		for _, test := range []struct {
+			ctx  context.Context
+			want bool
+		}{{ctx, true}, {viewer, false}} {
+			document, err := services.Receipts.GetReceipt(test.ctx, fmt.Sprint(sale.ID), url.Values{"format": {"text"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Contains(string(document.Body), "Ada Lovelace"); got != test.want {
+				t.Fatalf("customer printed: got %v, want %v\n%s", got, test.want, document.Body)
+			}
+		}
+	})
+}
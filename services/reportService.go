@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"productmanagerapi/models"
+	"productmanagerapi/repository"
+	"strconv"
+	"time"
+)
+
+type ReportService struct {
+	store *repository.Store
+}
+
+func NewReportService(store *repository.Store) *ReportService {
+	return &ReportService{store: store}
+}
+
+// TaxReport sums the taxes of a period. Collected counts the sales paid in
+// the period, except those cancelled since; Refunded the tax given back by
+// the returns made in the period, whenever their sale was paid. Taxable is
+// what was taxed, net of the returns.
+type TaxReport struct {
+	From      time.Time       `json:"from"`
+	To        time.Time       `json:"to"`
+	SaleCount int             `json:"sale_count"`
+	Taxable   float64         `json:"taxable"`
+	Collected float64         `json:"collected"`
+	Refunded  float64         `json:"refunded"`
+	Net       float64         `json:"net"`
+	Rates     []TaxReportRate `json:"rates"`
+}
+
+// TaxReportRate is the part of a TaxReport due to one rate. A rate whose
+// percentage changed during the period appears once per percentage.
+type TaxReportRate struct {
+	TaxRateID uint    `json:"tax_rate_id"`
+	Name      string  `json:"name"`
+	Rate      float64 `json:"rate"`
+	Inclusive bool    `json:"inclusive"`
+	Taxable   float64 `json:"taxable"`
+	Collected float64 `json:"collected"`
+	Refunded  float64 `json:"refunded"`
+	Net       float64 `json:"net"`
+}
+
+// TaxSummary reports the taxes of the period given by ?from= and ?to=; see
+// parsePeriod.
+func (s *ReportService) TaxSummary(ctx context.Context, query url.Values) (TaxReport, error) {
+	from, to, err := parsePeriod(query, time.Now())
+	if err != nil {
+		return TaxReport{}, err
+	}
+
+	report := TaxReport{From: from, To: to, Rates: []TaxReportRate{}}
+
+	type key struct {
+		id   uint
+		rate float64
+	}
+	index := map[key]int{}
+	rateOf := func(id uint, name string, rate float64, inclusive bool) *TaxReportRate {
+		k := key{id, rate}
+		i, ok := index[k]
+		if !ok {
+			i = len(report.Rates)
+			index[k] = i
+			report.Rates = append(report.Rates, TaxReportRate{TaxRateID: id, Name: name, Rate: rate, Inclusive: inclusive})
+		}
+		return &report.Rates[i]
+	}
+
+	sales, err := s.store.Sales.FindAll(ctx, repository.SaleFilter{PaidFrom: from, PaidBefore: to})
+	if err != nil {
+		return TaxReport{}, err
+	}
+	for _, sale := range sales {
+		if sale.Status == models.SaleCancelled {
+			continue
+		}
+		report.SaleCount++
+		for _, tax := range sale.Taxes {
+			rate := rateOf(tax.TaxRateID, tax.Name, tax.Rate, tax.Inclusive)
+			rate.Taxable += tax.Taxable
+			rate.Collected += tax.Amount
+		}
+	}
+
+	returns, err := s.store.Returns.FindCreatedBetween(ctx, from, to)
+	if err != nil {
+		return TaxReport{}, err
+	}
+	returnedSales := map[uint]models.Sale{}
+	for _, saleReturn := range returns {
+		sale, ok := returnedSales[saleReturn.SaleID]
+		if !ok {
+			sale, err = s.store.Sales.FindByID(ctx, saleReturn.SaleID)
+			if err != nil {
+				return TaxReport{}, err
+			}
+			returnedSales[sale.ID] = sale
+		}
+
+		lines := map[uint]models.SaleProduct{}
+		for _, line := range sale.Products {
+			lines[line.ID] = line
+		}
+		for _, returned := range saleReturn.Lines {
+			line, ok := lines[returned.SaleProductID]
+			if !ok || line.Quantity == 0 {
+				continue
+			}
+			share := float64(returned.Quantity) / float64(line.Quantity)
+			for _, tax := range line.Taxes {
+				rate := rateOf(tax.TaxRateID, tax.Name, tax.Rate, tax.Inclusive)
+				rate.Taxable -= tax.Taxable * share
+				rate.Refunded += tax.Amount * share
+			}
+		}
+	}
+
+	for i := range report.Rates {
+		rate := &report.Rates[i]
+		rate.Taxable = roundCents(rate.Taxable)
+		rate.Collected = roundCents(rate.Collected)
+		rate.Refunded = roundCents(rate.Refunded)
+		rate.Net = roundCents(rate.Collected - rate.Refunded)
+
+		report.Taxable += rate.Taxable
+		report.Collected += rate.Collected
+		report.Refunded += rate.Refunded
+	}
+	report.Taxable = roundCents(report.Taxable)
+	report.Collected = roundCents(report.Collected)
+	report.Refunded = roundCents(report.Refunded)
+	report.Net = roundCents(report.Collected - report.Refunded)
+	return report, nil
+}
+
+func (r TaxReport) Filename() string {
+	return reportFilename("taxes", r.From, r.To)
+}
+
+func (r TaxReport) Rows() [][]string {
+	rows := [][]string{{"tax_rate_id", "name", "rate", "inclusive", "taxable", "collected", "refunded", "net"}}
+	for _, rate := range r.Rates {
+		rows = append(rows, []string{csvID(rate.TaxRateID), rate.Name, strconv.FormatFloat(rate.Rate, 'f', -1, 64), strconv.FormatBool(rate.Inclusive), csvAmount(rate.Taxable), csvAmount(rate.Collected), csvAmount(rate.Refunded), csvAmount(rate.Net)})
+	}
+	return append(rows, []string{"", "Total", "", "", csvAmount(r.Taxable), csvAmount(r.Collected), csvAmount(r.Refunded), csvAmount(r.Net)})
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"productmanagerapi/auth"
+	"productmanagerapi/models"
+	"productmanagerapi/repository"
+	"productmanagerapi/types"
+	"strconv"
+	"strings"
+)
+
+type ReturnService struct {
+	store   *repository.Store
+	returns repository.ReturnRepository
+}
+
+func NewReturnService(store *repository.Store) *ReturnService {
+	return &ReturnService{store: store, returns: store.Returns}
+}
+
+// CreateReturn takes back items of a sale and refunds them at the price they
+// were sold for, net of every discount of the sale; see lineRefund.
+// Restocked items go back to inventory; damaged ones do not. The refund is
+// added to the sale's RefundedTotal in the same transaction. Only fulfilled
+// sales take returns; others are cancelled instead.
+func (s *ReturnService) CreateReturn(ctx context.Context, saleID string, body io.ReadCloser) (models.SaleReturn, error) {
+	id, err := parseSaleID(saleID)
+	if err != nil {
+		return models.SaleReturn{}, err
+	}
+
+	var request types.ReturnRequest
+	if err := json.NewDecoder(body).Decode(&request); err != nil {
+		return models.SaleReturn{}, errors.New("invalid request body : " + err.Error())
+	}
+
+	if len(request.Lines) == 0 {
+		return models.SaleReturn{}, errors.New("at least one line to return is required")
+	}
+	for i, line := range request.Lines {
+		if line.LineID == 0 {
+			return models.SaleReturn{}, fmt.Errorf("line %d: line_id is required", i)
+		}
+		if line.Quantity <= 0 {
+			return models.SaleReturn{}, fmt.Errorf("line %d: quantity must be positive", i)
+		}
+	}
+
+	principal, _ := auth.PrincipalFrom(ctx)
+
+	saleReturn := models.SaleReturn{
+		SaleID:      id,
+		Reason:      strings.TrimSpace(request.Reason),
+		CreatedByID: principal.UserID,
+	}
+
+	err = s.store.Transaction(ctx, func(tx *repository.Store) error {
+		sale, err := tx.Sales.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if sale.Status != models.SaleFulfilled {
+			return &SaleStatusError{Status: sale.Status, Action: "return items of"}
+		}
+
+		lines := map[uint]models.SaleProduct{}
+		for _, line := range sale.Products {
+			lines[line.ID] = line
+		}
+		returned, refunded := returnedQuantities(sale)
+
+		var restock []types.ProductSale
+		for i, requested := range request.Lines {
+			line, ok := lines[requested.LineID]
+			if !ok {
+				return fmt.Errorf("line %d: sale %d has no line %d", i, sale.ID, requested.LineID)
+			}
+
+			returnable := line.Quantity - returned[line.ID]
+			if requested.Quantity > returnable {
+				return fmt.Errorf("line %d: only %d of line %d can still be returned", i, returnable, line.ID)
+			}
+
+			restocked := requested.Restock == nil || *requested.Restock
+			refund := lineRefund(line, requested.Quantity, returned[line.ID], refunded[line.ID])
+			returned[line.ID] += requested.Quantity
+			refunded[line.ID] += refund
+			saleReturn.Lines = append(saleReturn.Lines, models.SaleReturnLine{
+				SaleProductID: line.ID,
+				ProductID:     line.ProductID,
+				Quantity:      requested.Quantity,
+				UnitPrice:     netUnitPrice(line),
+				Refund:        refund,
+				Restocked:     restocked,
+			})
+			saleReturn.RefundTotal = roundCents(saleReturn.RefundTotal + refund)
+
+			if restocked {
+				restock = append(restock, types.ProductSale{ProductID: int(line.ProductID)})
+			}
+		}
+
+		// Products deleted since the sale cannot take stock back; their
+		// items are refunded all the same.
+		products, err := lockProducts(ctx, tx.Products, restock)
+		if err != nil {
+			return err
+		}
+		for _, line := range saleReturn.Lines {
+			if _, found := products[line.ProductID]; !line.Restocked || !found {
+				continue
+			}
+			if err := tx.Products.AdjustStock(ctx, line.ProductID, line.Quantity); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Returns.Create(ctx, &saleReturn); err != nil {
+			return err
+		}
+
+		sale.RefundedTotal = roundCents(sale.RefundedTotal + saleReturn.RefundTotal)
+		sale.UpdatedByID = principal.UserID
+		return tx.Sales.Update(ctx, &sale)
+	})
+	if err != nil {
+		return models.SaleReturn{}, err
+	}
+
+	return saleReturn, nil
+}
+
+func (s *ReturnService) GetSaleReturns(ctx context.Context, saleID string) ([]models.SaleReturn, error) {
+	id, err := parseSaleID(saleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.store.Sales.FindByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.returns.FindBySale(ctx, id)
+}
+
+func (s *ReturnService) GetReturnByID(ctx context.Context, returnID string) (models.SaleReturn, error) {
+	id, err := strconv.ParseUint(returnID, 10, 64)
+	if err != nil || id == 0 {
+		return models.SaleReturn{}, errors.New("The return id is invalid")
+	}
+
+	return s.returns.FindByID(ctx, uint(id))
+}
+
+// returnedQuantities sums, per sale line, the quantities already returned
+// and what they refunded.
+func returnedQuantities(sale models.Sale) (map[uint]int, map[uint]float64) {
+	returned := map[uint]int{}
+	refunded := map[uint]float64{}
+	for _, saleReturn := range sale.Returns {
+		for _, line := range saleReturn.Lines {
+			returned[line.SaleProductID] += line.Quantity
+			refunded[line.SaleProductID] += line.Refund
+		}
+	}
+	return returned, refunded
+}
+
+// lineRefund is what returning quantity items of line refunds, at what they
+// were sold for, when earlier returns took returned items of it for a
+// refund of refunded. The return that takes the last items of the line
+// refunds whatever is left of it, so a line returned in parts refunds its
+// total to the cent.
+func lineRefund(line models.SaleProduct, quantity int, returned int, refunded float64) float64 {
+	net := line.TaxableAmount + line.Tax
+	if returned+quantity >= line.Quantity {
+		return roundCents(net - refunded)
+	}
+	return roundCents(net * float64(quantity) / float64(line.Quantity))
+}
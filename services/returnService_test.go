@@ -0,0 +1,116 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"productmanagerapi/models"
+	"testing"
+)
+
+func TestReturnsRefundTheLineTotal(t *testing.T) {
+	forEachStore(t, func(t *testing.T, services *Services) {
+		ctx := asAdmin()
+		product := createProduct(t, ctx, services, 4, 10)
+
+		// Three items for 10.00 once the discount is taken off.
+		sale, err := services.Sales.CreateSale(ctx, body(t, map[string]any{
+			"products": []map[string]any{{"product_id": product.ID, "quantity": 3}},
+			"status":   models.SaleFulfilled,
+			"discount": map[string]any{"type": models.DiscountFixed, "value": 2, "reason": "loyal customer"},
+			"tenders":  []map[string]any{{"method": "cash", "tendered": 10}},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sale.Total != 10 {
+			t.Fatalf("got total %v, want 10", sale.Total)
+		}
+
+		var refunds []float64
+		for range 3 {
+			saleReturn, err := services.Returns.CreateReturn(ctx, fmt.Sprint(sale.ID), body(t, map[string]any{
+				"reason": "unwanted",
+				"lines":  []map[string]any{{"line_id": sale.Products[0].ID, "quantity": 1}},
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			refunds = append(refunds, saleReturn.RefundTotal)
+		}
+		if refunds[0] != 3.33 || refunds[1] != 3.33 || refunds[2] != 3.34 {
+			t.Fatalf("got refunds %v, want 3.33, 3.33 and the 3.34 left", refunds)
+		}
+
+		refunded, err := services.Sales.GetSaleByID(ctx, fmt.Sprint(sale.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if refunded.RefundedTotal != 10 {
+			t.Fatalf("got %v refunded, want the whole 10", refunded.RefundedTotal)
+		}
+		if stock, _ := stockOf(t, ctx, services, product.ID); stock != 10 {
+			t.Fatalf("got stock %d, want the items restocked", stock)
+		}
+	})
+}
+
+func TestReturnsRestockUndamagedItems(t *testing.T) {
+	forEachStore(t, func(t *testing.T, services *Services) {
+		ctx := asAdmin()
+		product := createProduct(t, ctx, services, 10, 10)
+
+		confirmed, err := services.Sales.CreateSale(ctx, body(t, map[string]any{
+			"products": []map[string]any{{"product_id": product.ID, "quantity": 1}},
+			"status":   models.SaleConfirmed,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = services.Returns.CreateReturn(ctx, fmt.Sprint(confirmed.ID), body(t, map[string]any{
+			"reason": "unwanted",
+			"lines":  []map[string]any{{"line_id": confirmed.Products[0].ID, "quantity": 1}},
+		}))
+		var statusErr *SaleStatusError
+		if !errors.As(err, &statusErr) {
+			t.Fatalf("got %v, want only fulfilled sales to take returns", err)
+		}
+
+		sale, err := services.Sales.CreateSale(ctx, body(t, map[string]any{
+			"products": []map[string]any{{"product_id": product.ID, "quantity": 4}},
+			"status":   models.SaleFulfilled,
+			"tenders":  []map[string]any{{"method": "cash", "tendered": 40}},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lineID := sale.Products[0].ID
+		if stock, _ := stockOf(t, ctx, services, product.ID); stock != 6 {
+			t.Fatalf("got stock %d, want 6 after the sale", stock)
+		}
+
+		saleReturn, err := services.Returns.CreateReturn(ctx, fmt.Sprint(sale.ID), body(t, map[string]any{
+			"reason": "two broke in transit",
+			"lines": []map[string]any{
+				{"line_id": lineID, "quantity": 1},
+				{"line_id": lineID, "quantity": 2, "restock": false},
+			},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if saleReturn.RefundTotal != 30 || !saleReturn.Lines[0].Restocked || saleReturn.Lines[1].Restocked {
+			t.Fatalf("got refund %v and lines %+v, want 30 with only the first line restocked", saleReturn.RefundTotal, saleReturn.Lines)
+		}
+		if stock, _ := stockOf(t, ctx, services, product.ID); stock != 7 {
+			t.Fatalf("got stock %d, want only the undamaged item back for 7", stock)
+		}
+
+		_, err = services.Returns.CreateReturn(ctx, fmt.Sprint(sale.ID), body(t, map[string]any{
+			"reason": "unwanted",
+			"lines":  []map[string]any{{"line_id": lineID, "quantity": 2}},
+		}))
+		if err == nil {
+			t.Fatal("only the one item left can still be returned")
+		}
+	})
+}
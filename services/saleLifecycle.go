@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"productmanagerapi/auth"
+	"productmanagerapi/models"
+	"productmanagerapi/repository"
+	"productmanagerapi/types"
+	"slices"
+	"strings"
+	"time"
+)
+
+// saleTransitions lists the statuses each status can move to. Fulfilled and
+// cancelled sales are final.
+var saleTransitions = map[string][]string{
+	models.SaleDraft:     {models.SaleConfirmed, models.SaleCancelled},
+	models.SaleConfirmed: {models.SalePaid, models.SaleCancelled},
+	models.SalePaid:      {models.SaleFulfilled, models.SaleCancelled},
+}
+
+// saleActions names the transition into each status, as used in errors and
+// endpoints.
+var saleActions = map[string]string{
+	models.SaleConfirmed: "confirm",
+	models.SalePaid:      "pay",
+	models.SaleFulfilled: "fulfill",
+	models.SaleCancelled: "cancel",
+}
+
+// SaleStatusError is returned when a sale is asked to do something its
+// status does not allow, such as fulfilling a draft or amending a cancelled
+// sale.
+type SaleStatusError struct {
+	Status string
+	Action string
+}
+
+func (e *SaleStatusError) Error() string {
+	return fmt.Sprintf("cannot %s a %s sale", e.Action, e.Status)
+}
+
+// Allowed lists the statuses the sale can move to from its current one.
+func (e *SaleStatusError) Allowed() []string {
+	allowed := saleTransitions[e.Status]
+	if allowed == nil {
+		return []string{}
+	}
+	return allowed
+}
+
+// BalanceDueError is returned when a sale with something left to pay is
+// asked to be paid. Sales are paid by recording their payments.
+type BalanceDueError struct {
+	Balance float64
+}
+
+func (e *BalanceDueError) Error() string {
+	return fmt.Sprintf("the sale still has %.2f to pay; record payments that cover it", e.Balance)
+}
+
+// ErrCancelPaidNotAllowed is returned when a caller who may not refund sales
+// tries to cancel a paid one, or one with payments.
+var ErrCancelPaidNotAllowed = errors.New("cancelling a paid sale requires the " + string(auth.SalesReturn) + " permission")
+
+// ErrSaleInvoiced is returned when deleting a sale that was invoiced. Its
+// invoice number must stay accounted for, so it is cancelled or its items
+// returned instead.
+var ErrSaleInvoiced = errors.New("an invoiced sale cannot be deleted; cancel it or return its items instead")
+
+// ErrSaleHasPayments is returned when deleting a sale that took payments.
+// Cancelling it refunds them instead.
+var ErrSaleHasPayments = errors.New("a sale with payments cannot be deleted; cancel it to refund them instead")
+
+// ErrInvalidSale matches the errors about what a sale or payment request
+// asks for, which the caller can fix, as opposed to failures of the store.
+var ErrInvalidSale = errors.New("invalid sale request")
+
+// invalidSale formats an error matching ErrInvalidSale, keeping its message
+// as formatted.
+func invalidSale(format string, args ...any) error {
+	return &invalidSaleError{err: fmt.Errorf(format, args...)}
+}
+
+type invalidSaleError struct {
+	err error
+}
+
+func (e *invalidSaleError) Error() string {
+	return e.err.Error()
+}
+
+func (e *invalidSaleError) Unwrap() []error {
+	return []error{ErrInvalidSale, e.err}
+}
+
+// How a sale holds the stock of its lines, depending on its status.
+const (
+	holdNone = iota
+	holdReserved
+	holdTaken
+)
+
+// stockHold tells how a sale in status holds its items: confirmed and paid
+// sales reserve them, fulfilled sales have taken them out of stock, and
+// drafts and cancelled sales hold nothing.
+func stockHold(status string) int {
+	switch status {
+	case models.SaleConfirmed, models.SalePaid:
+		return holdReserved
+	case models.SaleFulfilled:
+		return holdTaken
+	}
+	return holdNone
+}
+
+// holdStock makes a sale in status hold quantity more items of a product,
+// or give them back when quantity is negative.
+func holdStock(ctx context.Context, products repository.ProductRepository, productID uint, quantity int, status string) error {
+	switch stockHold(status) {
+	case holdReserved:
+		return products.AdjustReserved(ctx, productID, quantity)
+	case holdTaken:
+		return products.AdjustStock(ctx, productID, -quantity)
+	}
+	return nil
+}
+
+// availableStock is what is left of a product for new reservations and
+// sales: the stock that no confirmed sale holds yet.
+func availableStock(product models.Product) int {
+	return product.Stock - product.Reserved
+}
+
+// stampStatus moves the sale to status and records when it did.
+func stampStatus(sale *models.Sale, status string, at time.Time) {
+	sale.Status = status
+	switch status {
+	case models.SaleConfirmed:
+		sale.ConfirmedAt = &at
+	case models.SalePaid:
+		sale.PaidAt = &at
+	case models.SaleFulfilled:
+		sale.FulfilledAt = &at
+	case models.SaleCancelled:
+		sale.CancelledAt = &at
+	}
+}
+
+// ConfirmSale reserves the items of a draft sale.
+func (s *SaleService) ConfirmSale(ctx context.Context, saleID string) (models.Sale, error) {
+	return s.transition(ctx, saleID, models.SaleConfirmed, "")
+}
+
+// PaySale marks a confirmed sale with nothing left to pay, such as one
+// discounted to nothing, as paid and gives it its invoice number. Its items
+// stay reserved. Sales with a balance are paid by their payments, see
+// PaymentService.CreatePayment, and fail with a BalanceDueError.
+func (s *SaleService) PaySale(ctx context.Context, saleID string) (models.Sale, error) {
+	return s.transition(ctx, saleID, models.SalePaid, "")
+}
+
+// FulfillSale hands over the items of a paid sale: they leave the stock and
+// their reservation is released.
+func (s *SaleService) FulfillSale(ctx context.Context, saleID string) (models.Sale, error) {
+	return s.transition(ctx, saleID, models.SaleFulfilled, "")
+}
+
+// CancelSale cancels a sale that is not fulfilled yet, releases what it
+// reserved and refunds its payments, see PaymentService.refundPayments. The
+// use of its coupon is given back. The body may give a reason.
+func (s *SaleService) CancelSale(ctx context.Context, saleID string, body io.ReadCloser) (models.Sale, error) {
+	var request types.SaleCancellation
+	if err := json.NewDecoder(body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		return models.Sale{}, invalidSale("invalid request body : %v", err)
+	}
+
+	return s.transition(ctx, saleID, models.SaleCancelled, strings.TrimSpace(request.Reason))
+}
+
+func (s *SaleService) transition(ctx context.Context, saleID string, status string, reason string) (models.Sale, error) {
+	id, err := parseSaleID(saleID)
+	if err != nil {
+		return models.Sale{}, err
+	}
+
+	principal, _ := auth.PrincipalFrom(ctx)
+
+	var sale models.Sale
+	err = s.store.Transaction(ctx, func(tx *repository.Store) error {
+		sale, err = tx.Sales.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if !slices.Contains(saleTransitions[sale.Status], status) {
+			return &SaleStatusError{Status: sale.Status, Action: saleActions[status]}
+		}
+		refund := status == models.SaleCancelled && (sale.Status == models.SalePaid || sale.PaidTotal > 0)
+		if refund && !principal.Role.Can(auth.SalesReturn) {
+			return ErrCancelPaidNotAllowed
+		}
+		if balance := roundCents(sale.Total - sale.PaidTotal); status == models.SalePaid && balance > 0 {
+			return &BalanceDueError{Balance: balance}
+		}
+
+		if err := moveStock(ctx, tx.Products, sale, status); err != nil {
+			return err
+		}
+
+		stampStatus(&sale, status, time.Now())
+		if status == models.SalePaid {
+			if err := issueInvoice(ctx, tx.Sequences, &sale); err != nil {
+				return err
+			}
+		}
+		sale.UpdatedByID = principal.UserID
+		if status == models.SaleCancelled {
+			sale.CancelReason = reason
+			for _, discount := range sale.Discounts {
+				if err := releaseCoupon(ctx, tx.Coupons, discount); err != nil {
+					return err
+				}
+			}
+		}
+		if refund {
+			if err := s.payments.refundPayments(ctx, tx, &sale); err != nil {
+				return err
+			}
+		}
+		if err := tx.Sales.Update(ctx, &sale); err != nil {
+			return err
+		}
+
+		sale, err = tx.Sales.FindByID(ctx, sale.ID)
+		return err
+	})
+	if err != nil {
+		return models.Sale{}, err
+	}
+
+	return sale, nil
+}
+
+// moveStock moves the items of a sale from the way its current status holds
+// them to the way status does. Taking more than it held, by reserving or by
+// taking items out of stock, fails with a StockConflictError when the
+// products cannot cover it. Products deleted since are skipped.
+func moveStock(ctx context.Context, products repository.ProductRepository, sale models.Sale, status string) error {
+	from, to := stockHold(sale.Status), stockHold(status)
+	if from == to {
+		return nil
+	}
+
+	lines := make([]types.ProductSale, 0, len(sale.Products))
+	for _, line := range sale.Products {
+		lines = append(lines, types.ProductSale{ProductID: int(line.ProductID), Quantity: line.Quantity})
+	}
+	locked, err := lockProducts(ctx, products, lines)
+	if err != nil {
+		return err
+	}
+
+	if to != holdNone {
+		available := map[uint]int{}
+		for id, product := range locked {
+			available[id] = availableStock(product)
+		}
+		// What the sale reserved itself is available to it.
+		if from == holdReserved {
+			for _, line := range lines {
+				if _, found := available[uint(line.ProductID)]; found {
+					available[uint(line.ProductID)] += line.Quantity
+				}
+			}
+		}
+
+		var conflicts []types.SaleLineConflict
+		for i, line := range lines {
+			productID := uint(line.ProductID)
+			if _, found := locked[productID]; !found {
+				continue
+			}
+			if available[productID] < line.Quantity {
+				conflicts = append(conflicts, types.SaleLineConflict{Line: i, ProductID: line.ProductID, Requested: line.Quantity, Available: available[productID], Reason: "insufficient_stock"})
+				continue
+			}
+			available[productID] -= line.Quantity
+		}
+		if len(conflicts) > 0 {
+			return &StockConflictError{Lines: conflicts}
+		}
+	}
+
+	for _, line := range lines {
+		productID := uint(line.ProductID)
+		if _, found := locked[productID]; !found {
+			continue
+		}
+		if err := holdStock(ctx, products, productID, -line.Quantity, sale.Status); err != nil {
+			return err
+		}
+		if err := holdStock(ctx, products, productID, line.Quantity, status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
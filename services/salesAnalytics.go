@@ -0,0 +1,544 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"productmanagerapi/models"
+	"productmanagerapi/repository"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The sales analytics count the sales paid in their period, except those
+// cancelled since. Revenue is what the lines come to after every discount,
+// before exclusive taxes, and units are the quantities sold. As in the tax
+// report, the items returned in a period are taken off its units and
+// revenue, whenever their sale was paid, but not off its sale count.
+
+// Report intervals of RevenueByPeriod.
+const (
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+// Rankings of the top reports.
+const (
+	RankByRevenue  = "revenue"
+	RankByQuantity = "quantity"
+)
+
+// Comparisons of SalesSummary: the period of the same length just before,
+// or the same period a year before.
+const (
+	ComparePrevious = "previous"
+	CompareYear     = "year"
+)
+
+const (
+	defaultTopLimit = 10
+	maxTopLimit     = 100
+	// maxReportPeriods bounds RevenueByPeriod to a year of days.
+	maxReportPeriods = 366
+)
+
+// Report is a report that can be downloaded as CSV.
+type Report interface {
+	// Filename names the downloaded report, without extension.
+	Filename() string
+	// Rows lays the report out as a table, header first.
+	Rows() [][]string
+}
+
+// RevenueReport is the revenue of a period split by day, week or month.
+// Periods cover the whole report, the days without sales included; the
+// first and last may start before From or end after To.
+type RevenueReport struct {
+	From      time.Time       `json:"from"`
+	To        time.Time       `json:"to"`
+	Interval  string          `json:"interval"`
+	SaleCount int             `json:"sale_count"`
+	Units     int             `json:"units"`
+	Revenue   float64         `json:"revenue"`
+	Periods   []RevenuePeriod `json:"periods"`
+}
+
+// RevenuePeriod is one period of a RevenueReport. Weeks start on Monday.
+type RevenuePeriod struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	SaleCount int       `json:"sale_count"`
+	Units     int       `json:"units"`
+	Revenue   float64   `json:"revenue"`
+}
+
+// TopProductsReport ranks the products sold in a period. Products keep the
+// name and category they were last sold under.
+type TopProductsReport struct {
+	From     time.Time    `json:"from"`
+	To       time.Time    `json:"to"`
+	By       string       `json:"by"`
+	Products []TopProduct `json:"products"`
+}
+
+type TopProduct struct {
+	ProductID    uint    `json:"product_id"`
+	Name         string  `json:"name"`
+	CategoryID   uint    `json:"category_id"`
+	CategoryName string  `json:"category_name"`
+	SaleCount    int     `json:"sale_count"`
+	Units        int     `json:"units"`
+	Revenue      float64 `json:"revenue"`
+}
+
+// TopCategoriesReport ranks the categories of the products sold in a
+// period.
+type TopCategoriesReport struct {
+	From       time.Time     `json:"from"`
+	To         time.Time     `json:"to"`
+	By         string        `json:"by"`
+	Categories []TopCategory `json:"categories"`
+}
+
+type TopCategory struct {
+	CategoryID uint    `json:"category_id"`
+	Name       string  `json:"name"`
+	SaleCount  int     `json:"sale_count"`
+	Units      int     `json:"units"`
+	Revenue    float64 `json:"revenue"`
+}
+
+// SalesFigures are the totals of a period and its average basket: the
+// units and revenue of a sale.
+type SalesFigures struct {
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+	SaleCount    int       `json:"sale_count"`
+	Units        int       `json:"units"`
+	Revenue      float64   `json:"revenue"`
+	AverageUnits float64   `json:"average_units"`
+	AverageValue float64   `json:"average_value"`
+}
+
+// SalesSummary compares the figures of a period with those of an earlier
+// one.
+type SalesSummary struct {
+	Compare  string       `json:"compare"`
+	Current  SalesFigures `json:"current"`
+	Previous SalesFigures `json:"previous"`
+	Change   SalesChange  `json:"change"`
+}
+
+// SalesChange is the growth of each figure of a SalesSummary in percent, or
+// null when the earlier figure is zero.
+type SalesChange struct {
+	SaleCount    *float64 `json:"sale_count"`
+	Units        *float64 `json:"units"`
+	Revenue      *float64 `json:"revenue"`
+	AverageUnits *float64 `json:"average_units"`
+	AverageValue *float64 `json:"average_value"`
+}
+
+// RevenueByPeriod reports the revenue of the period given by ?from= and
+// ?to=, see parsePeriod, per ?interval=: day, the default, week or month.
+// The report has at most maxReportPeriods periods.
+func (s *ReportService) RevenueByPeriod(ctx context.Context, query url.Values) (RevenueReport, error) {
+	from, to, err := parsePeriod(query, time.Now())
+	if err != nil {
+		return RevenueReport{}, err
+	}
+	interval := strings.TrimSpace(query.Get("interval"))
+	if interval == "" {
+		interval = IntervalDay
+	}
+	if !slices.Contains([]string{IntervalDay, IntervalWeek, IntervalMonth}, interval) {
+		return RevenueReport{}, fmt.Errorf("%w: interval must be day, week or month", ErrInvalidFilter)
+	}
+
+	report := RevenueReport{From: from, To: to, Interval: interval, Periods: []RevenuePeriod{}}
+	var ends []time.Time
+	for start := periodStart(from, interval); start.Before(to); start = nextPeriod(start, interval) {
+		if len(report.Periods) == maxReportPeriods {
+			return RevenueReport{}, fmt.Errorf("%w: the report cannot have more than %d periods, pick a longer interval or a shorter period", ErrInvalidFilter, maxReportPeriods)
+		}
+		end := nextPeriod(start, interval)
+		report.Periods = append(report.Periods, RevenuePeriod{Start: start, End: end})
+		ends = append(ends, end)
+	}
+
+	totals, err := s.netSalesTotals(ctx, repository.SalesTotalsQuery{From: from, To: to, GroupBy: repository.SalesByPeriod, Periods: ends})
+	if err != nil {
+		return RevenueReport{}, err
+	}
+	for _, total := range totals {
+		period := &report.Periods[total.Key]
+		period.SaleCount = total.SaleCount
+		period.Units = total.Units
+		period.Revenue = roundCents(total.Revenue)
+		report.SaleCount += period.SaleCount
+		report.Units += period.Units
+		report.Revenue += period.Revenue
+	}
+	report.Revenue = roundCents(report.Revenue)
+	return report, nil
+}
+
+// TopProducts ranks the products sold in the period given by ?from= and
+// ?to= by ?by=revenue, the default, or quantity, and keeps the first
+// ?limit=, 10 by default.
+func (s *ReportService) TopProducts(ctx context.Context, query url.Values) (TopProductsReport, error) {
+	from, to, by, limit, err := parseTopQuery(query)
+	if err != nil {
+		return TopProductsReport{}, err
+	}
+	totals, err := s.topSalesTotals(ctx, repository.SalesTotalsQuery{From: from, To: to, GroupBy: repository.SalesByProduct}, by, limit)
+	if err != nil {
+		return TopProductsReport{}, err
+	}
+	lines, err := s.lastLines(ctx, totals)
+	if err != nil {
+		return TopProductsReport{}, err
+	}
+
+	products := []TopProduct{}
+	for _, total := range totals {
+		line := lines[total.LastLineID]
+		products = append(products, TopProduct{
+			ProductID:    total.Key,
+			Name:         line.ProductName,
+			CategoryID:   line.CategoryID,
+			CategoryName: line.CategoryName,
+			SaleCount:    total.SaleCount,
+			Units:        total.Units,
+			Revenue:      total.Revenue,
+		})
+	}
+	return TopProductsReport{From: from, To: to, By: by, Products: products}, nil
+}
+
+// TopCategories ranks the categories of the products sold in the period
+// given by ?from= and ?to= like TopProducts. Lines count towards the
+// category their product was in when sold.
+func (s *ReportService) TopCategories(ctx context.Context, query url.Values) (TopCategoriesReport, error) {
+	from, to, by, limit, err := parseTopQuery(query)
+	if err != nil {
+		return TopCategoriesReport{}, err
+	}
+	totals, err := s.topSalesTotals(ctx, repository.SalesTotalsQuery{From: from, To: to, GroupBy: repository.SalesByCategory}, by, limit)
+	if err != nil {
+		return TopCategoriesReport{}, err
+	}
+	lines, err := s.lastLines(ctx, totals)
+	if err != nil {
+		return TopCategoriesReport{}, err
+	}
+
+	categories := []TopCategory{}
+	for _, total := range totals {
+		categories = append(categories, TopCategory{
+			CategoryID: total.Key,
+			Name:       lines[total.LastLineID].CategoryName,
+			SaleCount:  total.SaleCount,
+			Units:      total.Units,
+			Revenue:    total.Revenue,
+		})
+	}
+	return TopCategoriesReport{From: from, To: to, By: by, Categories: categories}, nil
+}
+
+// SalesSummary reports the figures of the period given by ?from= and ?to=
+// against those of the period as long just before it, or of the same period
+// a year before with ?compare=year. A period of whole calendar months is
+// compared with as many months before it.
+func (s *ReportService) SalesSummary(ctx context.Context, query url.Values) (SalesSummary, error) {
+	from, to, err := parsePeriod(query, time.Now())
+	if err != nil {
+		return SalesSummary{}, err
+	}
+	compare := strings.TrimSpace(query.Get("compare"))
+	if compare == "" {
+		compare = ComparePrevious
+	}
+
+	var previousFrom, previousTo time.Time
+	switch compare {
+	case ComparePrevious:
+		previousFrom, previousTo = from.Add(-to.Sub(from)), from
+		if months := wholeMonths(from, to); months > 0 {
+			previousFrom = from.AddDate(0, -months, 0)
+		}
+	case CompareYear:
+		previousFrom, previousTo = from.AddDate(-1, 0, 0), to.AddDate(-1, 0, 0)
+	default:
+		return SalesSummary{}, fmt.Errorf("%w: compare must be previous or year", ErrInvalidFilter)
+	}
+
+	current, err := s.salesFigures(ctx, from, to)
+	if err != nil {
+		return SalesSummary{}, err
+	}
+	previous, err := s.salesFigures(ctx, previousFrom, previousTo)
+	if err != nil {
+		return SalesSummary{}, err
+	}
+
+	return SalesSummary{
+		Compare:  compare,
+		Current:  current,
+		Previous: previous,
+		Change: SalesChange{
+			SaleCount:    growth(float64(current.SaleCount), float64(previous.SaleCount)),
+			Units:        growth(float64(current.Units), float64(previous.Units)),
+			Revenue:      growth(current.Revenue, previous.Revenue),
+			AverageUnits: growth(current.AverageUnits, previous.AverageUnits),
+			AverageValue: growth(current.AverageValue, previous.AverageValue),
+		},
+	}, nil
+}
+
+func (s *ReportService) salesFigures(ctx context.Context, from, to time.Time) (SalesFigures, error) {
+	totals, err := s.netSalesTotals(ctx, repository.SalesTotalsQuery{From: from, To: to})
+	if err != nil {
+		return SalesFigures{}, err
+	}
+
+	figures := SalesFigures{From: from, To: to}
+	for _, total := range totals {
+		figures.SaleCount += total.SaleCount
+		figures.Units += total.Units
+		figures.Revenue += total.Revenue
+	}
+	figures.Revenue = roundCents(figures.Revenue)
+	if figures.SaleCount > 0 {
+		figures.AverageUnits = roundCents(float64(figures.Units) / float64(figures.SaleCount))
+		figures.AverageValue = roundCents(figures.Revenue / float64(figures.SaleCount))
+	}
+	return figures, nil
+}
+
+// netSalesTotals sums the sales of query less the items returned in its
+// range, group by group. The sale counts are those of the sales alone.
+func (s *ReportService) netSalesTotals(ctx context.Context, query repository.SalesTotalsQuery) ([]repository.SalesTotal, error) {
+	sold, err := s.store.Sales.SumSold(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	returned, err := s.store.Returns.SumReturned(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := sold
+	index := map[uint]int{}
+	for i, total := range sold {
+		index[total.Key] = i
+	}
+	for _, total := range returned {
+		i, ok := index[total.Key]
+		if !ok {
+			i = len(totals)
+			index[total.Key] = i
+			totals = append(totals, repository.SalesTotal{Key: total.Key})
+		}
+		totals[i].LastLineID = max(totals[i].LastLineID, total.LastLineID)
+		totals[i].Units -= total.Units
+		totals[i].Revenue -= total.Revenue
+	}
+	return totals, nil
+}
+
+// topSalesTotals ranks the net totals of query by revenue or quantity, best
+// first, and keeps the first limit.
+func (s *ReportService) topSalesTotals(ctx context.Context, query repository.SalesTotalsQuery, by string, limit int) ([]repository.SalesTotal, error) {
+	totals, err := s.netSalesTotals(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	for i := range totals {
+		totals[i].Revenue = roundCents(totals[i].Revenue)
+	}
+	slices.SortFunc(totals, func(a, b repository.SalesTotal) int {
+		return compareRanks(by, a.Revenue, b.Revenue, a.Units, b.Units, a.Key, b.Key)
+	})
+	if len(totals) > limit {
+		totals = totals[:limit]
+	}
+	return totals, nil
+}
+
+// lastLines loads the last sale line of each of totals, by ID, to name them.
+func (s *ReportService) lastLines(ctx context.Context, totals []repository.SalesTotal) (map[uint]models.SaleProduct, error) {
+	ids := make([]uint, 0, len(totals))
+	for _, total := range totals {
+		ids = append(ids, total.LastLineID)
+	}
+	found, err := s.store.Sales.FindLines(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	lines := map[uint]models.SaleProduct{}
+	for _, line := range found {
+		lines[line.ID] = line
+	}
+	return lines, nil
+}
+
+// parseTopQuery reads the period, ?by= and ?limit= of the top reports.
+func parseTopQuery(query url.Values) (time.Time, time.Time, string, int, error) {
+	from, to, err := parsePeriod(query, time.Now())
+	if err != nil {
+		return time.Time{}, time.Time{}, "", 0, err
+	}
+
+	by := strings.TrimSpace(query.Get("by"))
+	if by == "" {
+		by = RankByRevenue
+	}
+	if by != RankByRevenue && by != RankByQuantity {
+		return time.Time{}, time.Time{}, "", 0, fmt.Errorf("%w: by must be revenue or quantity", ErrInvalidFilter)
+	}
+
+	limit := defaultTopLimit
+	if value := strings.TrimSpace(query.Get("limit")); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxTopLimit {
+			return time.Time{}, time.Time{}, "", 0, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidFilter, maxTopLimit)
+		}
+	}
+	return from, to, by, limit, nil
+}
+
+// compareRanks orders the rows of a top report, best first, breaking ties
+// on the other figure and then on the ID.
+func compareRanks(by string, revenueA, revenueB float64, unitsA, unitsB int, idA, idB uint) int {
+	byRevenue, byUnits := cmp.Compare(revenueB, revenueA), cmp.Compare(unitsB, unitsA)
+	if by == RankByQuantity {
+		byRevenue, byUnits = byUnits, byRevenue
+	}
+	return cmp.Or(byRevenue, byUnits, cmp.Compare(idA, idB))
+}
+
+// periodStart is the start of the day, Monday week or month of at, in UTC.
+func periodStart(at time.Time, interval string) time.Time {
+	year, month, day := at.UTC().Date()
+	switch interval {
+	case IntervalWeek:
+		start := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+	case IntervalMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func nextPeriod(start time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	case IntervalMonth:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// wholeMonths is the number of calendar months from from to to, or 0 when
+// they do not both start a month.
+func wholeMonths(from, to time.Time) int {
+	if !from.Equal(periodStart(from, IntervalMonth)) || !to.Equal(periodStart(to, IntervalMonth)) {
+		return 0
+	}
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+}
+
+// growth is the change from previous to current in percent, or nil when
+// previous is zero.
+func growth(current, previous float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	change := roundCents((current - previous) / previous * 100)
+	return &change
+}
+
+func (r RevenueReport) Filename() string {
+	return reportFilename("revenue-by-"+r.Interval, r.From, r.To)
+}
+
+func (r RevenueReport) Rows() [][]string {
+	rows := [][]string{{"start", "end", "sale_count", "units", "revenue"}}
+	for _, period := range r.Periods {
+		rows = append(rows, []string{csvTime(period.Start), csvTime(period.End), strconv.Itoa(period.SaleCount), strconv.Itoa(period.Units), csvAmount(period.Revenue)})
+	}
+	return rows
+}
+
+func (r TopProductsReport) Filename() string {
+	return reportFilename("top-products-by-"+r.By, r.From, r.To)
+}
+
+func (r TopProductsReport) Rows() [][]string {
+	rows := [][]string{{"rank", "product_id", "name", "category_id", "category_name", "sale_count", "units", "revenue"}}
+	for i, product := range r.Products {
+		rows = append(rows, []string{strconv.Itoa(i + 1), csvID(product.ProductID), product.Name, csvID(product.CategoryID), product.CategoryName, strconv.Itoa(product.SaleCount), strconv.Itoa(product.Units), csvAmount(product.Revenue)})
+	}
+	return rows
+}
+
+func (r TopCategoriesReport) Filename() string {
+	return reportFilename("top-categories-by-"+r.By, r.From, r.To)
+}
+
+func (r TopCategoriesReport) Rows() [][]string {
+	rows := [][]string{{"rank", "category_id", "name", "sale_count", "units", "revenue"}}
+	for i, category := range r.Categories {
+		rows = append(rows, []string{strconv.Itoa(i + 1), csvID(category.CategoryID), category.Name, strconv.Itoa(category.SaleCount), strconv.Itoa(category.Units), csvAmount(category.Revenue)})
+	}
+	return rows
+}
+
+func (r SalesSummary) Filename() string {
+	return reportFilename("sales-summary", r.Current.From, r.Current.To)
+}
+
+// Rows puts a figure on each row, with the periods compared on the first.
+func (r SalesSummary) Rows() [][]string {
+	return [][]string{
+		{"figure", "current", "previous", "change_percent"},
+		{"from", csvTime(r.Current.From), csvTime(r.Previous.From), ""},
+		{"to", csvTime(r.Current.To), csvTime(r.Previous.To), ""},
+		{"sale_count", strconv.Itoa(r.Current.SaleCount), strconv.Itoa(r.Previous.SaleCount), csvChange(r.Change.SaleCount)},
+		{"units", strconv.Itoa(r.Current.Units), strconv.Itoa(r.Previous.Units), csvChange(r.Change.Units)},
+		{"revenue", csvAmount(r.Current.Revenue), csvAmount(r.Previous.Revenue), csvChange(r.Change.Revenue)},
+		{"average_units", csvAmount(r.Current.AverageUnits), csvAmount(r.Previous.AverageUnits), csvChange(r.Change.AverageUnits)},
+		{"average_value", csvAmount(r.Current.AverageValue), csvAmount(r.Previous.AverageValue), csvChange(r.Change.AverageValue)},
+	}
+}
+
+// reportFilename names a report after its period, such as
+// revenue-by-day_2024-01-01_2024-02-01.
+func reportFilename(name string, from, to time.Time) string {
+	return fmt.Sprintf("%s_%s_%s", name, from.UTC().Format(time.DateOnly), to.UTC().Format(time.DateOnly))
+}
+
+func csvTime(at time.Time) string {
+	return at.UTC().Format(time.RFC3339)
+}
+
+func csvID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func csvAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func csvChange(change *float64) string {
+	if change == nil {
+		return ""
+	}
+	return csvAmount(*change)
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"productmanagerapi/models"
+	"testing"
+)
+
+func TestSalesAnalyticsTakeOffReturns(t *testing.T) {
+	forEachStore(t, func(t *testing.T, services *Services) {
+		ctx := asAdmin()
+		product := createProduct(t, ctx, services, 10, 10)
+		if _, err := services.TaxRates.CreateTaxRate(ctx, body(t, map[string]any{"Name": "VAT", "Rate": 25, "Inclusive": true, "ProductID": product.ID})); err != nil {
+			t.Fatal(err)
+		}
+
+		sale, err := services.Sales.CreateSale(ctx, body(t, map[string]any{
+			"products": []map[string]any{{"product_id": product.ID, "quantity": 3}},
+			"status":   models.SaleFulfilled,
+			"tenders":  []map[string]any{{"method": "cash", "tendered": 30}},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = services.Returns.CreateReturn(ctx, fmt.Sprint(sale.ID), body(t, map[string]any{
+			"reason": "faulty",
+			"lines":  []map[string]any{{"line_id": sale.Products[0].ID, "quantity": 1}},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		summary, err := services.Reports.SalesSummary(ctx, url.Values{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if current := summary.Current; current.SaleCount != 1 || current.Units != 2 || current.Revenue != 20 {
+			t.Fatalf("got %d sales, %d units and %v revenue, want 1, 2 and 20", current.SaleCount, current.Units, current.Revenue)
+		}
+
+		revenue, err := services.Reports.RevenueByPeriod(ctx, url.Values{"interval": {IntervalMonth}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revenue.Periods) != 1 || revenue.Periods[0].Units != 2 || revenue.Periods[0].Revenue != 20 {
+			t.Fatalf("got periods %+v, want one with 2 units and 20 revenue", revenue.Periods)
+		}
+
+		top, err := services.Reports.TopProducts(ctx, url.Values{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(top.Products) != 1 || top.Products[0].Name != "Hammer" || top.Products[0].Units != 2 || top.Products[0].Revenue != 20 {
+			t.Fatalf("got products %+v, want the hammer with 2 units and 20 revenue", top.Products)
+		}
+
+		if _, err := services.Reports.RevenueByPeriod(ctx, url.Values{"from": {"2020-01-01"}, "to": {"2022-01-01"}}); err == nil {
+			t.Fatal("two years of days exceed the period limit")
+		}
+	})
+}
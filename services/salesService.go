@@ -1,130 +1,842 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
-	"productmanagerapi/config"
+	"net/url"
+	"productmanagerapi/auth"
 	"productmanagerapi/models"
+	"productmanagerapi/repository"
 	"productmanagerapi/types"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
-var CreateSale = func(body io.ReadCloser) (types.SaleRequest, error) {
-	var sale types.SaleRequest
-	if err := json.NewDecoder(body).Decode(&sale); err != nil {
-		return types.SaleRequest{}, errors.New("invalid request body : " + err.Error())
+type SaleService struct {
+	store    *repository.Store
+	sales    repository.SaleRepository
+	products repository.ProductRepository
+	payments *PaymentService
+}
+
+// NewSaleService builds the sale service. payments takes the tenders of
+// sales created paid.
+func NewSaleService(store *repository.Store, payments *PaymentService) *SaleService {
+	return &SaleService{store: store, sales: store.Sales, products: store.Products, payments: payments}
+}
+
+// StockConflictError is returned when some lines of a sale cannot be
+// fulfilled. Nothing is saved in that case.
+type StockConflictError struct {
+	Lines []types.SaleLineConflict
+}
+
+func (e *StockConflictError) Error() string {
+	return fmt.Sprintf("%d sale line(s) cannot be fulfilled", len(e.Lines))
+}
+
+// ErrPriceOverrideNotAllowed is returned when a sale line carries a price
+// that differs from the catalog price and the caller may not override it.
+var ErrPriceOverrideNotAllowed = errors.New("overriding the catalog price requires the " + string(auth.SalesOverridePrice) + " permission")
+
+// CreateSale records a sale in a single transaction. Drafts only need their
+// products to exist; a sale created confirmed or paid reserves its lines and
+// one created fulfilled takes them out of stock. The products are locked
+// first, so concurrent sales of the same product wait for each other instead
+// of overselling. Lines are priced from the catalog, see newSaleLine, then
+// discounted, see applyDiscounts, and taxed at the rates enabled; see
+// taxesFor. Sales created paid or fulfilled get their invoice number.
+func (s *SaleService) CreateSale(ctx context.Context, body io.ReadCloser) (models.Sale, error) {
+	var request types.SaleRequest
+	if err := json.NewDecoder(body).Decode(&request); err != nil {
+		return models.Sale{}, invalidSale("invalid request body : %v", err)
 	}
 
-	if len(sale.Products) == 0 {
-		return types.SaleRequest{}, errors.New("At least one products is required")
+	return s.createSale(ctx, request)
+}
+
+// CreateLegacySale records a sale for POST /create-sale the way it was before
+// sales had statuses and payments: the sale is fulfilled at once, taking its
+// items out of stock, and settled in cash unless the request brings its own
+// tenders. Only fulfilled sales can be created this way.
+func (s *SaleService) CreateLegacySale(ctx context.Context, body io.ReadCloser) (models.Sale, error) {
+	var request types.SaleRequest
+	if err := json.NewDecoder(body).Decode(&request); err != nil {
+		return models.Sale{}, invalidSale("invalid request body : %v", err)
 	}
 
-	var totalAmount float64
-	for _, productSale := range sale.Products {
-		if productSale.Quantity <= 0 {
-			continue
+	if request.Status == "" {
+		request.Status = models.SaleFulfilled
+	}
+	if request.Status != models.SaleFulfilled {
+		return models.Sale{}, invalidSale("/create-sale only records fulfilled sales; create %s sales with POST /api/v1/sales", request.Status)
+	}
+	if len(request.Tenders) == 0 {
+		request.Tenders = []types.Tender{{Method: models.TenderCash}}
+	}
+
+	return s.createSale(ctx, request)
+}
+
+func (s *SaleService) createSale(ctx context.Context, request types.SaleRequest) (models.Sale, error) {
+	if len(request.Products) == 0 {
+		return models.Sale{}, invalidSale("At least one products is required")
+	}
+
+	for i, line := range request.Products {
+		if err := validateSaleLine(line); err != nil {
+			return models.Sale{}, fmt.Errorf("line %d: %w", i, err)
 		}
-		if productSale.Price <= 0 {
-			continue
+	}
+
+	status := request.Status
+	if status == "" {
+		status = models.SaleDraft
+	}
+	forward := []string{models.SaleDraft, models.SaleConfirmed, models.SalePaid, models.SaleFulfilled}
+	reached := slices.Index(forward, status)
+	if reached < 0 {
+		return models.Sale{}, invalidSale("a sale cannot be created %s; use one of %s", status, strings.Join(forward, ", "))
+	}
+	paid := reached >= slices.Index(forward, models.SalePaid)
+	if len(request.Tenders) > 0 && !paid {
+		return models.Sale{}, invalidSale("tenders are only taken by sales created paid or fulfilled")
+	}
+	if err := validateTenders(request.Tenders); err != nil {
+		return models.Sale{}, err
+	}
+
+	var sale models.Sale
+	var charged []string
+	err := s.store.Transaction(ctx, func(tx *repository.Store) error {
+		if request.CustomerID != nil {
+			if _, err := tx.Customers.FindByID(ctx, *request.CustomerID); err != nil {
+				if errors.Is(err, repository.ErrNotFound) {
+					return invalidSale("customer %d does not exist", *request.CustomerID)
+				}
+				return err
+			}
+		}
+
+		products, err := lockProducts(ctx, tx.Products, request.Products)
+		if err != nil {
+			return err
+		}
+
+		stock := map[uint]int{}
+		for id, product := range products {
+			stock[id] = availableStock(product)
+		}
+
+		var conflicts []types.SaleLineConflict
+		for i, line := range request.Products {
+			productID := uint(line.ProductID)
+
+			available, found := stock[productID]
+			switch {
+			case !found:
+				conflicts = append(conflicts, types.SaleLineConflict{Line: i, ProductID: line.ProductID, Requested: line.Quantity, Reason: "product_not_found"})
+			case stockHold(status) == holdNone:
+				// Drafts hold no stock yet.
+			case available < line.Quantity:
+				conflicts = append(conflicts, types.SaleLineConflict{Line: i, ProductID: line.ProductID, Requested: line.Quantity, Available: available, Reason: "insufficient_stock"})
+			default:
+				// Several lines may take from the same product.
+				stock[productID] = available - line.Quantity
+			}
+		}
+		if len(conflicts) > 0 {
+			return &StockConflictError{Lines: conflicts}
+		}
+
+		categories := map[uint]models.Category{}
+		userID := auth.UserID(ctx)
+		sale = models.Sale{Status: models.SaleDraft, CustomerID: request.CustomerID, CreatedByID: userID, UpdatedByID: userID}
+		// Sales created paid stay confirmed until their tenders pay them.
+		now := time.Now()
+		if reached > 0 {
+			stampStatus(&sale, models.SaleConfirmed, now)
 		}
-		totalAmount += float64(productSale.Quantity) * productSale.Price
+		for i, line := range request.Products {
+			product := products[uint(line.ProductID)]
+
+			category, ok := categories[product.CategoryID]
+			if !ok {
+				category, err = tx.Categories.FindByID(ctx, product.CategoryID)
+				if err != nil && !errors.Is(err, repository.ErrNotFound) {
+					return err
+				}
+				categories[product.CategoryID] = category
+			}
+
+			saleLine, err := newSaleLine(ctx, line, product, category)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", i, err)
+			}
+
+			if err := holdStock(ctx, tx.Products, product.ID, line.Quantity, status); err != nil {
+				return err
+			}
+
+			sale.Products = append(sale.Products, saleLine)
+			sale.Subtotal += saleLine.Total
+			sale.Total += saleLine.Total
+		}
+
+		if err := tx.Sales.Create(ctx, &sale); err != nil {
+			return err
+		}
+		if err := applyDiscounts(ctx, tx, &sale, request, now); err != nil {
+			return err
+		}
+
+		rates, err := tx.TaxRates.FindEnabled(ctx)
+		if err != nil {
+			return err
+		}
+		for i := range sale.Products {
+			sale.Products[i].Taxes = taxesFor(sale.Products[i], rates)
+		}
+
+		priceSale(&sale)
+		if err := saveSalePricing(ctx, tx.Sales, &sale); err != nil {
+			return err
+		}
+		if !paid {
+			return nil
+		}
+
+		if err := s.payOnCreation(ctx, tx, &sale, request.Tenders, &charged); err != nil {
+			return err
+		}
+		if status == models.SaleFulfilled {
+			stampStatus(&sale, models.SaleFulfilled, now)
+			if err := tx.Sales.Update(ctx, &sale); err != nil {
+				return err
+			}
+		}
+		sale, err = tx.Sales.FindByID(ctx, sale.ID)
+		return err
+	})
+	if err != nil {
+		s.payments.refundCharges(ctx, charged)
+		return models.Sale{}, err
+	}
+
+	return sale, nil
+}
+
+// payOnCreation pays a sale created paid or fulfilled with the tenders that
+// came with it, which must cover the whole sale: a sale is only paid once
+// nothing is left to pay. A sale that comes to nothing needs no tender.
+func (s *SaleService) payOnCreation(ctx context.Context, tx *repository.Store, sale *models.Sale, tenders []types.Tender, charged *[]string) error {
+	if roundCents(sale.Total) <= 0 {
+		stampStatus(sale, models.SalePaid, time.Now())
+		if err := issueInvoice(ctx, tx.Sequences, sale); err != nil {
+			return err
+		}
+		return tx.Sales.Update(ctx, sale)
+	}
+
+	if len(tenders) == 0 {
+		return &BalanceDueError{Balance: roundCents(sale.Total)}
+	}
+	if _, err := s.payments.pay(ctx, tx, sale, tenders, charged); err != nil {
+		return err
+	}
+	if sale.Status != models.SalePaid {
+		return &BalanceDueError{Balance: roundCents(sale.Total - sale.PaidTotal)}
+	}
+	return nil
+}
+
+// applyDiscounts adds the discounts of a sale just created from request:
+// each line gets the best promotion running for it and its manual discount,
+// then the sale gets its own manual discount and the coupon of the request,
+// which needs the discounted lines to reach its minimum. The caller prices
+// the sale.
+func applyDiscounts(ctx context.Context, tx *repository.Store, sale *models.Sale, request types.SaleRequest, at time.Time) error {
+	promotions, err := tx.Promotions.FindActive(ctx, at)
+	if err != nil {
+		return err
+	}
+
+	for i, line := range sale.Products {
+		if discount, ok := promotionDiscount(line, promotions); ok {
+			sale.Discounts = append(sale.Discounts, discount)
+		}
+		if request.Products[i].Discount != nil {
+			lineID := line.ID
+			discount, err := manualDiscount(ctx, *request.Products[i].Discount, &lineID)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", i, err)
+			}
+			sale.Discounts = append(sale.Discounts, discount)
+		}
+	}
+
+	if request.Discount != nil {
+		discount, err := manualDiscount(ctx, *request.Discount, nil)
+		if err != nil {
+			return err
+		}
+		sale.Discounts = append(sale.Discounts, discount)
+	}
+
+	if request.CouponCode != "" {
+		priceSale(sale)
+		var linesTotal float64
+		for _, line := range sale.Products {
+			linesTotal += line.Total
+		}
+
+		discount, err := redeemCoupon(ctx, tx.Coupons, request.CouponCode, roundCents(linesTotal), at)
+		if err != nil {
+			return err
+		}
+		sale.Discounts = append(sale.Discounts, discount)
+	}
+	return nil
+}
+
+// AmendSale adds, removes or changes the quantity of lines of a draft or
+// confirmed sale. Once paid, the sale is settled and changes go through
+// returns. What a confirmed sale reserves of each product moves by the
+// difference with the previous quantities, the discounts, taxes and totals are worked out again
+// and every change is recorded as an amendment on the sale. Added lines get
+// promotions, manual discounts and taxes as in CreateSale, while the other
+// lines keep the rates they were sold at; the discounts of removed lines go
+// with them, as does a coupon whose minimum subtotal the sale no longer
+// reaches, its use given back. As with CreateSale, nothing is saved unless every
+// change can be applied.
+func (s *SaleService) AmendSale(ctx context.Context, saleID string, body io.ReadCloser) (models.Sale, error) {
+	id, err := parseSaleID(saleID)
+	if err != nil {
+		return models.Sale{}, err
 	}
 
-	saleModel := models.Sale{
-		Total: totalAmount,
+	var request types.SaleAmendmentRequest
+	if err := json.NewDecoder(body).Decode(&request); err != nil {
+		return models.Sale{}, invalidSale("invalid request body : %v", err)
 	}
 
-	var notSavedProduct []types.ProductSale
+	request.Reason = strings.TrimSpace(request.Reason)
+	if request.Reason == "" {
+		return models.Sale{}, invalidSale("a reason is required to amend a sale")
+	}
+	if len(request.Lines) == 0 {
+		return models.Sale{}, invalidSale("at least one line change is required")
+	}
 
-	for _, productSale := range sale.Products {
-		productID := productSale.ProductID
+	changedLines := map[uint]bool{}
+	for i, change := range request.Lines {
+		if change.Quantity < 0 {
+			return models.Sale{}, invalidSale("line %d: quantity cannot be negative", i)
+		}
+		if change.LineID == 0 {
+			if err := validateSaleLine(change.ProductSale()); err != nil {
+				return models.Sale{}, fmt.Errorf("line %d: %w", i, err)
+			}
+			continue
+		}
+		if changedLines[change.LineID] {
+			return models.Sale{}, invalidSale("line %d: line_id %d is changed twice", i, change.LineID)
+		}
+		changedLines[change.LineID] = true
+	}
+
+	principal, _ := auth.PrincipalFrom(ctx)
+
+	var sale models.Sale
+	err = s.store.Transaction(ctx, func(tx *repository.Store) error {
+		sale, err = tx.Sales.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if sale.Status != models.SaleDraft && sale.Status != models.SaleConfirmed {
+			return &SaleStatusError{Status: sale.Status, Action: "amend"}
+		}
+		now := time.Now()
 
-		var product models.Product
-		result := config.Db.First(&product, types.ID{ID: strconv.Itoa(productID)})
+		lines := map[uint]models.SaleProduct{}
+		for _, line := range sale.Products {
+			lines[line.ID] = line
+		}
 
-		if result.Error != nil {
-			if result.Error.Error() == "record not found" {
-				notSavedProduct = append(notSavedProduct, productSale)
+		// Lock every product touched, existing lines and new ones alike.
+		touched := make([]types.ProductSale, 0, len(request.Lines))
+		for i, change := range request.Lines {
+			if change.LineID == 0 {
+				touched = append(touched, change.ProductSale())
 				continue
+			}
+			line, ok := lines[change.LineID]
+			if !ok {
+				return invalidSale("line %d: sale %d has no line %d", i, sale.ID, change.LineID)
+			}
+			touched = append(touched, types.ProductSale{ProductID: int(line.ProductID)})
+		}
+
+		products, err := lockProducts(ctx, tx.Products, touched)
+		if err != nil {
+			return err
+		}
+
+		stock := map[uint]int{}
+		for productID, product := range products {
+			stock[productID] = availableStock(product)
+		}
+
+		var conflicts []types.SaleLineConflict
+		for i, change := range request.Lines {
+			productID := uint(touched[i].ProductID)
+			needed := change.Quantity - lines[change.LineID].Quantity
+
+			available, found := stock[productID]
+			switch {
+			case needed <= 0:
+				// Giving stock back always works, even for a product that
+				// was deleted since.
+				if found {
+					stock[productID] = available - needed
+				}
+			case !found:
+				conflicts = append(conflicts, types.SaleLineConflict{Line: i, ProductID: int(productID), Requested: needed, Reason: "product_not_found"})
+			case stockHold(sale.Status) == holdNone:
+				// Drafts hold no stock yet.
+			case available < needed:
+				conflicts = append(conflicts, types.SaleLineConflict{Line: i, ProductID: int(productID), Requested: needed, Available: available, Reason: "insufficient_stock"})
+			default:
+				stock[productID] = available - needed
+			}
+		}
+		if len(conflicts) > 0 {
+			return &StockConflictError{Lines: conflicts}
+		}
+
+		var amendments []models.SaleAmendment
+		// added maps the index of each added line in the request to its ID.
+		added := map[int]uint{}
+		for i, change := range request.Lines {
+			amendment := models.SaleAmendment{
+				SaleID:      sale.ID,
+				AmendedByID: principal.UserID,
+				Reason:      request.Reason,
+			}
+
+			var stockDelta int
+			if change.LineID == 0 {
+				product := products[uint(change.ProductID)]
+				category, err := tx.Categories.FindByID(ctx, product.CategoryID)
+				if err != nil && !errors.Is(err, repository.ErrNotFound) {
+					return err
+				}
+
+				line, err := newSaleLine(ctx, change.ProductSale(), product, category)
+				if err != nil {
+					return fmt.Errorf("line %d: %w", i, err)
+				}
+				line.SaleID = sale.ID
+				if err := tx.Sales.SaveLine(ctx, &line); err != nil {
+					return err
+				}
+
+				added[i] = line.ID
+				stockDelta = -line.Quantity
+				amendment.Action = models.AmendmentAdded
+				amendment.SaleProductID = line.ID
+				amendment.ProductID = line.ProductID
+				amendment.NewQuantity = line.Quantity
+				amendment.NewTotal = line.Total
 			} else {
-				notSavedProduct = append(notSavedProduct, productSale)
+				line := lines[change.LineID]
+				if change.Quantity == line.Quantity {
+					continue
+				}
+
+				stockDelta = line.Quantity - change.Quantity
+				amendment.SaleProductID = line.ID
+				amendment.ProductID = line.ProductID
+				amendment.OldQuantity = line.Quantity
+				amendment.OldTotal = line.Total
+				amendment.NewQuantity = change.Quantity
+
+				if change.Quantity == 0 {
+					if err := tx.Sales.DeleteLine(ctx, sale.ID, line.ID); err != nil {
+						return err
+					}
+					amendment.Action = models.AmendmentRemoved
+				} else {
+					line.Quantity = change.Quantity
+					line.Total = float64(line.Quantity) * line.UnitPrice
+					if err := tx.Sales.SaveLine(ctx, &line); err != nil {
+						return err
+					}
+					amendment.Action = models.AmendmentChanged
+					amendment.NewTotal = line.Total
+				}
+			}
+
+			if _, found := products[amendment.ProductID]; found {
+				if err := holdStock(ctx, tx.Products, amendment.ProductID, -stockDelta, sale.Status); err != nil {
+					return err
+				}
+			}
+			amendments = append(amendments, amendment)
+		}
+
+		if len(amendments) == 0 {
+			return invalidSale("the changes leave the sale as it is")
+		}
+
+		sale, err = tx.Sales.FindByIDForUpdate(ctx, sale.ID)
+		if err != nil {
+			return err
+		}
+
+		remaining := map[uint]bool{}
+		for _, line := range sale.Products {
+			remaining[line.ID] = true
+		}
+		discounts := sale.Discounts[:0]
+		for _, discount := range sale.Discounts {
+			if discount.SaleProductID != nil && !remaining[*discount.SaleProductID] {
+				if err := tx.Sales.DeleteDiscount(ctx, sale.ID, discount.ID); err != nil {
+					return err
+				}
 				continue
 			}
+			discounts = append(discounts, discount)
 		}
+		sale.Discounts = discounts
 
-		if product.Stock < productSale.Quantity {
-			notSavedProduct = append(notSavedProduct, productSale)
+		if len(added) > 0 {
+			promotions, err := tx.Promotions.FindActive(ctx, now)
+			if err != nil {
+				return err
+			}
+			rates, err := tx.TaxRates.FindEnabled(ctx)
+			if err != nil {
+				return err
+			}
+			for j, line := range sale.Products {
+				for i, lineID := range added {
+					if line.ID != lineID {
+						continue
+					}
+					sale.Products[j].Taxes = taxesFor(line, rates)
+					if discount, ok := promotionDiscount(line, promotions); ok {
+						sale.Discounts = append(sale.Discounts, discount)
+					}
+					if request.Lines[i].Discount != nil {
+						discount, err := manualDiscount(ctx, *request.Lines[i].Discount, &lineID)
+						if err != nil {
+							return fmt.Errorf("line %d: %w", i, err)
+						}
+						sale.Discounts = append(sale.Discounts, discount)
+					}
+				}
+			}
 		}
 
-		// Create SaleProduct model
-		saleProduct := models.SaleProduct{
-			SaleID:    saleModel.ID,
-			ProductID: uint(productID),
-			Quantity:  productSale.Quantity,
-			Total:     float64(productSale.Quantity) * productSale.Price,
+		priceSale(&sale)
+
+		// A coupon stops applying once the lines left no longer reach its
+		// minimum, and its use is given back.
+		var net float64
+		for _, line := range sale.Products {
+			net += line.Total
 		}
-		product.Stock -= productSale.Quantity
-		result = config.Db.Save(&product)
-		if result.Error != nil {
-			return types.SaleRequest{}, result.Error
+		kept := make([]models.SaleDiscount, 0, len(sale.Discounts))
+		for _, discount := range sale.Discounts {
+			if discount.Source == models.DiscountSourceCoupon && discount.CouponID != nil {
+				coupon, err := tx.Coupons.FindByID(ctx, *discount.CouponID)
+				if err != nil && !errors.Is(err, repository.ErrNotFound) {
+					return err
+				}
+				if len(sale.Products) == 0 || (err == nil && roundCents(net) < coupon.MinSubtotal) {
+					if err := tx.Sales.DeleteDiscount(ctx, sale.ID, discount.ID); err != nil {
+						return err
+					}
+					if err := releaseCoupon(ctx, tx.Coupons, discount); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+			kept = append(kept, discount)
+		}
+		if len(kept) < len(sale.Discounts) {
+			sale.Discounts = kept
+			priceSale(&sale)
+		}
+
+		// Amendments record the line totals after discounts.
+		totals := map[uint]float64{}
+		for _, line := range sale.Products {
+			totals[line.ID] = line.Total
+		}
+		for i := range amendments {
+			if amendments[i].Action != models.AmendmentRemoved {
+				amendments[i].NewTotal = totals[amendments[i].SaleProductID]
+			}
+		}
+		if err := tx.Sales.AddAmendments(ctx, amendments); err != nil {
+			return err
 		}
-		saleModel.Products = append(saleModel.Products, saleProduct)
-	}
 
-	result := config.Db.Create(&saleModel)
+		sale.UpdatedByID = principal.UserID
+		if err := saveSalePricing(ctx, tx.Sales, &sale); err != nil {
+			return err
+		}
 
-	if result.Error != nil {
-		return types.SaleRequest{}, result.Error
+		sale, err = tx.Sales.FindByID(ctx, sale.ID)
+		return err
+	})
+	if err != nil {
+		return models.Sale{}, err
 	}
 
 	return sale, nil
+}
 
+func validateSaleLine(line types.ProductSale) error {
+	if line.ProductID <= 0 {
+		return invalidSale("product_id is required")
+	}
+	if line.Quantity <= 0 {
+		return invalidSale("quantity must be positive")
+	}
+	if line.Price != nil && *line.Price < 0 {
+		return invalidSale("price cannot be negative")
+	}
+	return nil
 }
 
-var GetAllSales = func() ([]models.Sale, error) {
-	var sales []models.Sale
-	result := config.Db.Preload("Products").Find(&sales)
-	if result.Error != nil {
-		return []models.Sale{}, errors.New("Error while fetching sales")
+// newSaleLine prices a line from the catalog and snapshots the product onto
+// it. A client price that differs from the catalog price is only accepted
+// from callers allowed to override prices, and only with a reason; the line
+// then records who overrode it.
+func newSaleLine(ctx context.Context, line types.ProductSale, product models.Product, category models.Category) (models.SaleProduct, error) {
+	saleLine := models.SaleProduct{
+		ProductID:    product.ID,
+		ProductName:  product.Name,
+		CategoryID:   product.CategoryID,
+		CategoryName: category.Name,
+		ListPrice:    product.Price,
+		UnitPrice:    product.Price,
+		Quantity:     line.Quantity,
 	}
 
-	if len(sales) == 0 {
-		return []models.Sale{}, nil
+	if line.Price != nil && *line.Price != product.Price {
+		principal, _ := auth.PrincipalFrom(ctx)
+		if !principal.Role.Can(auth.SalesOverridePrice) {
+			return models.SaleProduct{}, ErrPriceOverrideNotAllowed
+		}
+
+		reason := strings.TrimSpace(line.OverrideReason)
+		if reason == "" {
+			return models.SaleProduct{}, invalidSale("override_reason is required when overriding the price")
+		}
+
+		overriddenBy := principal.UserID
+		saleLine.UnitPrice = *line.Price
+		saleLine.OverriddenByID = &overriddenBy
+		saleLine.OverrideReason = reason
 	}
 
-	return sales, nil
+	saleLine.Total = float64(saleLine.Quantity) * saleLine.UnitPrice
+	return saleLine, nil
 }
 
-var GetSaleByID = func(saleID string) (models.Sale, error) {
-	if saleID == "" {
-		return models.Sale{}, errors.New("The sale id is required")
+// lockProducts locks the products referenced by lines, in ID order so that
+// concurrent transactions cannot deadlock. Missing products are left out of
+// the result.
+func lockProducts(ctx context.Context, products repository.ProductRepository, lines []types.ProductSale) (map[uint]models.Product, error) {
+	ids := make([]uint, 0, len(lines))
+	seen := map[uint]bool{}
+	for _, line := range lines {
+		id := uint(line.ProductID)
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
 	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
 
-	var sale models.Sale
-	result := config.Db.Preload("Products").First(&sale, types.ID{ID: saleID})
-	if result.Error != nil {
-		return models.Sale{}, result.Error
+	locked := map[uint]models.Product{}
+	for _, id := range ids {
+		product, err := products.FindByIDForUpdate(ctx, id)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		locked[id] = product
 	}
+	return locked, nil
+}
 
-	return sale, nil
+// saleCursor is where a page of sales ends: the last sale's position in
+// the Sort of the page, as given in ?sort=.
+type saleCursor struct {
+	Sort      string    `json:"sort"`
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Total     float64   `json:"total"`
 }
 
-var DeleteSale = func(saleID string) error {
-	if saleID == "" {
-		return errors.New("The Sale id is required")
+// GetAllSales lists the sales matching the query a page at a time. It
+// filters on ?from= and ?to=, when the sales were created, as
+// parseTimeRange reads them, ?product_id=, ?min_total=, ?max_total=,
+// ?status=, which takes several statuses separated by commas, and
+// ?created_by=. ?sort= orders by created_at, total or id, descending when
+// prefixed with a minus sign; the newest sales come first by default.
+// ?limit= sets the page size and ?cursor= takes the next_cursor of the
+// previous page, which must have the same filters and sort.
+func (s *SaleService) GetAllSales(ctx context.Context, query url.Values) ([]models.Sale, Page, error) {
+	var filter repository.SaleFilter
+	var err error
+
+	if filter.CreatedByID, err = parseUserFilter(query, "created_by"); err != nil {
+		return nil, Page{}, err
+	}
+	if filter.CreatedFrom, filter.CreatedBefore, err = parseTimeRange(query); err != nil {
+		return nil, Page{}, err
+	}
+	if filter.ProductID, err = parseIDFilter(query, "product_id"); err != nil {
+		return nil, Page{}, err
+	}
+	if filter.MinTotal, err = parseAmountFilter(query, "min_total"); err != nil {
+		return nil, Page{}, err
+	}
+	if filter.MaxTotal, err = parseAmountFilter(query, "max_total"); err != nil {
+		return nil, Page{}, err
+	}
+	statuses := []string{models.SaleDraft, models.SaleConfirmed, models.SalePaid, models.SaleFulfilled, models.SaleCancelled}
+	if filter.Statuses, err = parseListFilter(query, "status", statuses); err != nil {
+		return nil, Page{}, err
 	}
 
-	var sale models.Sale
-	result := config.Db.First(&sale, types.ID{ID: saleID})
-	if result.Error != nil {
-		return result.Error
+	field, desc, err := parseSort(query, []string{repository.SaleSortCreatedAt, repository.SaleSortTotal, repository.SaleSortID}, "-"+repository.SaleSortCreatedAt)
+	if err != nil {
+		return nil, Page{}, err
+	}
+	sortKey := field
+	if desc {
+		sortKey = "-" + field
 	}
 
-	result = config.Db.Delete(&sale)
-	if result.Error != nil {
-		return result.Error
+	page := repository.SalePage{Sort: field, Desc: desc}
+	if page.Limit, err = parseLimit(query); err != nil {
+		return nil, Page{}, err
+	}
+	if value := strings.TrimSpace(query.Get("cursor")); value != "" {
+		var cursor saleCursor
+		if err := decodeCursor(value, &cursor); err != nil {
+			return nil, Page{}, err
+		}
+		if cursor.Sort != sortKey {
+			return nil, Page{}, fmt.Errorf("%w: cursor was made for sort=%s", ErrInvalidFilter, cursor.Sort)
+		}
+		page.After = &models.Sale{Total: cursor.Total}
+		page.After.ID = cursor.ID
+		page.After.CreatedAt = cursor.CreatedAt
 	}
 
-	return nil
+	// One sale more than the page tells whether another page follows.
+	limit := page.Limit
+	page.Limit++
+	sales, total, err := s.sales.FindPage(ctx, filter, page)
+	if err != nil {
+		return nil, Page{}, errors.New("Error while fetching sales")
+	}
+
+	result := Page{Limit: limit, Total: total}
+	if len(sales) > limit {
+		sales = sales[:limit]
+		last := sales[limit-1]
+		result.NextCursor = encodeCursor(saleCursor{Sort: sortKey, ID: last.ID, CreatedAt: last.CreatedAt, Total: last.Total})
+	}
+	return sales, result, nil
+}
+
+func (s *SaleService) GetSaleByID(ctx context.Context, saleID string) (models.Sale, error) {
+	id, err := parseSaleID(saleID)
+	if err != nil {
+		return models.Sale{}, err
+	}
+
+	return s.sales.FindByID(ctx, id)
+}
+
+// DeleteSale voids a sale that was never invoiced nor paid in part: the
+// reservations of a confirmed sale are released, the use of its coupon is
+// given back and the sale is deleted with its lines. Paid sales keep their invoice and are cancelled or returned
+// instead, and sales with payments are cancelled, which refunds them.
+func (s *SaleService) DeleteSale(ctx context.Context, saleID string) error {
+	id, err := parseSaleID(saleID)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Transaction(ctx, func(tx *repository.Store) error {
+		sale, err := tx.Sales.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if sale.InvoiceNumber != nil {
+			return ErrSaleInvoiced
+		}
+		if sale.PaidTotal > 0 {
+			return ErrSaleHasPayments
+		}
+
+		lines := make([]types.ProductSale, 0, len(sale.Products))
+		for _, line := range sale.Products {
+			lines = append(lines, types.ProductSale{ProductID: int(line.ProductID)})
+		}
+		products, err := lockProducts(ctx, tx.Products, lines)
+		if err != nil {
+			return err
+		}
+
+		for _, line := range sale.Products {
+			if _, found := products[line.ProductID]; found {
+				if err := holdStock(ctx, tx.Products, line.ProductID, -line.Quantity, sale.Status); err != nil {
+					return err
+				}
+			}
+			if err := tx.Sales.DeleteLine(ctx, sale.ID, line.ID); err != nil {
+				return err
+			}
+		}
+		for _, discount := range sale.Discounts {
+			if err := releaseCoupon(ctx, tx.Coupons, discount); err != nil {
+				return err
+			}
+		}
+
+		return tx.Sales.Delete(ctx, sale.ID)
+	})
+}
+
+func parseSaleID(saleID string) (uint, error) {
+	if saleID == "" {
+		return 0, invalidSale("The sale id is required")
+	}
+
+	id, err := strconv.ParseUint(saleID, 10, 64)
+	if err != nil || id == 0 {
+		return 0, invalidSale("The sale id is invalid")
+	}
+
+	return uint(id), nil
 }
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"productmanagerapi/models"
+	"testing"
+)
+
+func TestCreateSaleReservesStock(t *testing.T) {
+	forEachStore(t, func(t *testing.T, services *Services) {
+		ctx := asAdmin()
+		product := createProduct(t, ctx, services, 10, 5)
+
+		draft, err := services.Sales.CreateSale(ctx, body(t, map[string]any{
+			"products": []map[string]any{{"product_id": product.ID, "quantity": 2}},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stock, reserved := stockOf(t, ctx, services, product.ID); stock != 5 || reserved != 0 {
+			t.Fatalf("a draft holds no stock, got stock %d reserved %d", stock, reserved)
+		}
+
+		if _, err := services.Sales.ConfirmSale(ctx, fmt.Sprint(draft.ID)); err != nil {
+			t.Fatal(err)
+		}
+		if stock, reserved := stockOf(t, ctx, services, product.ID); stock != 5 || reserved != 2 {
+			t.Fatalf("confirming reserves the lines, got stock %d reserved %d", stock, reserved)
+		}
+
+		_, err = services.Sales.CreateSale(ctx, body(t, map[string]any{
+			"products": []map[string]any{{"product_id": product.ID, "quantity": 4}},
+			"status":   models.SaleConfirmed,
+		}))
+		var conflict *StockConflictError
+		if !errors.As(err, &conflict) || len(conflict.Lines) != 1 || conflict.Lines[0].Available != 3 {
+			t.Fatalf("reserved items cannot be sold again, got %v", err)
+		}
+
+		if _, err := services.Sales.CancelSale(ctx, fmt.Sprint(draft.ID), body(t, map[string]any{"reason": "changed mind"})); err != nil {
+			t.Fatal(err)
+		}
+		if stock, reserved := stockOf(t, ctx, services, product.ID); stock != 5 || reserved != 0 {
+			t.Fatalf("cancelling releases the reservations, got stock %d reserved %d", stock, reserved)
+		}
+	})
+}
+
+func TestFulfillSaleTakesStock(t *testing.T) {
+	forEachStore(t, func(t *testing.T, services *Services) {
+		ctx := asAdmin()
+		product := createProduct(t, ctx, services, 10, 5)
+
+		sale, err := services.Sales.CreateSale(ctx, body(t, map[string]any{
+			"products": []map[string]any{{"product_id": product.ID, "quantity": 3}},
+			"status":   models.SaleFulfilled,
+			"tenders":  []map[string]any{{"method": "cash", "tendered": 50}},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sale.Status != models.SaleFulfilled || sale.PaidTotal != 30 {
+			t.Fatalf("got status %s paid %v, want fulfilled and 30 paid", sale.Status, sale.PaidTotal)
+		}
+		if stock, reserved := stockOf(t, ctx, services, product.ID); stock != 2 || reserved != 0 {
+			t.Fatalf("fulfilling takes the items out of stock, got stock %d reserved %d", stock, reserved)
+		}
+
+		_, err = services.Sales.CreateSale(ctx, body(t, map[string]any{
+			"products": []map[string]any{{"product_id": product.ID, "quantity": 1}},
+			"status":   models.SaleFulfilled,
+		}))
+		var balance *BalanceDueError
+		if !errors.As(err, &balance) || balance.Balance != 10 {
+			t.Fatalf("a sale is only fulfilled once paid, got %v", err)
+		}
+		if stock, reserved := stockOf(t, ctx, services, product.ID); stock != 2 || reserved != 0 {
+			t.Fatalf("a refused sale leaves the stock alone, got stock %d reserved %d", stock, reserved)
+		}
+	})
+}
+
+func TestCancelSaleRefundsPayments(t *testing.T) {
+	forEachStore(t, func(t *testing.T, services *Services) {
+		ctx := asAdmin()
+		product := createProduct(t, ctx, services, 10, 5)
+		customer, err := services.Customers.CreateCustomer(ctx, body(t, map[string]any{"Name": "Ada"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := services.Customers.AdjustStoreCredit(ctx, fmt.Sprint(customer.ID), body(t, map[string]any{"amount": 15})); err != nil {
+			t.Fatal(err)
+		}
+
+		sale, err := services.Sales.CreateSale(ctx, body(t, map[string]any{
+			"products":    []map[string]any{{"product_id": product.ID, "quantity": 3}},
+			"customer_id": customer.ID,
+			"status":      models.SaleConfirmed,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := fmt.Sprint(sale.ID)
+		_, err = services.Payments.CreatePayment(ctx, id, body(t, map[string]any{
+			"tenders": []map[string]any{{"method": "store_credit", "amount": 10}, {"method": "card", "amount": 5, "token": "tok_visa"}},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := services.Sales.DeleteSale(ctx, id); !errors.Is(err, ErrSaleHasPayments) {
+			t.Fatalf("got %v, want a sale with payments kept", err)
+		}
+
+		cancelled, err := services.Sales.CancelSale(ctx, id, body(t, map[string]any{"reason": "changed mind"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cancelled.Status != models.SaleCancelled || cancelled.RefundedTotal != 15 {
+			t.Fatalf("got status %s refunded %v, want cancelled with 15 refunded", cancelled.Status, cancelled.RefundedTotal)
+		}
+		summary, err := services.Payments.GetSalePayments(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, payment := range summary.Payments {
+			if payment.RefundedAt == nil {
+				t.Fatalf("payment %d by %s is not marked refunded", payment.ID, payment.Method)
+			}
+		}
+		refreshed, err := services.Customers.GetCustomerByID(ctx, fmt.Sprint(customer.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if refreshed.StoreCredit != 15 {
+			t.Fatalf("got store credit %v, want the 10 spent given back to make 15", refreshed.StoreCredit)
+		}
+		if stock, reserved := stockOf(t, ctx, services, product.ID); stock != 5 || reserved != 0 {
+			t.Fatalf("cancelling releases the reservations, got stock %d reserved %d", stock, reserved)
+		}
+	})
+}
+
+func TestCreateLegacySaleFulfillsAndSettlesInCash(t *testing.T) {
+	forEachStore(t, func(t *testing.T, services *Services) {
+		ctx := asAdmin()
+		product := createProduct(t, ctx, services, 10, 5)
+
+		sale, err := services.Sales.CreateLegacySale(ctx, body(t, map[string]any{
+			"products": []map[string]any{{"product_id": product.ID, "quantity": 2}},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sale.Status != models.SaleFulfilled || sale.PaidTotal != 20 || sale.InvoiceNumber == nil {
+			t.Fatalf("got status %s paid %v invoice %v, want an invoiced fulfilled sale paid 20", sale.Status, sale.PaidTotal, sale.InvoiceNumber)
+		}
+		if len(sale.Payments) != 1 || sale.Payments[0].Method != models.TenderCash {
+			t.Fatalf("got payments %+v, want one in cash", sale.Payments)
+		}
+		if stock, reserved := stockOf(t, ctx, services, product.ID); stock != 3 || reserved != 0 {
+			t.Fatalf("a legacy sale takes its items out of stock, got stock %d reserved %d", stock, reserved)
+		}
+
+		_, err = services.Sales.CreateLegacySale(ctx, body(t, map[string]any{
+			"products": []map[string]any{{"product_id": product.ID, "quantity": 1}},
+			"status":   models.SaleDraft,
+		}))
+		if err == nil {
+			t.Fatal("the legacy route only records fulfilled sales")
+		}
+	})
+}
+
+func TestAmendSaleMovesReservations(t *testing.T) {
+	forEachStore(t, func(t *testing.T, services *Services) {
+		ctx := asAdmin()
+		hammer := createProduct(t, ctx, services, 10, 5)
+		saw := createProduct(t, ctx, services, 25, 1)
+
+		sale, err := services.Sales.CreateSale(ctx, body(t, map[string]any{
+			"products": []map[string]any{{"product_id": hammer.ID, "quantity": 2}},
+			"status":   models.SaleConfirmed,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := fmt.Sprint(sale.ID)
+		lineID := sale.Products[0].ID
+
+		if _, err := services.Sales.AmendSale(ctx, id, body(t, map[string]any{
+			"lines": []map[string]any{{"line_id": lineID, "quantity": 3}},
+		})); err == nil {
+			t.Fatal("an amendment needs a reason")
+		}
+
+		_, err = services.Sales.AmendSale(ctx, id, body(t, map[string]any{
+			"reason": "wants more",
+			"lines":  []map[string]any{{"line_id": lineID, "quantity": 3}, {"product_id": saw.ID, "quantity": 2}},
+		}))
+		var conflict *StockConflictError
+		if !errors.As(err, &conflict) || len(conflict.Lines) != 1 || conflict.Lines[0].Line != 1 {
+			t.Fatalf("got %v, want the saw line short of stock", err)
+		}
+		if _, reserved := stockOf(t, ctx, services, hammer.ID); reserved != 2 {
+			t.Fatalf("a refused amendment changes nothing, got %d hammers reserved", reserved)
+		}
+
+		amended, err := services.Sales.AmendSale(ctx, id, body(t, map[string]any{
+			"reason": "wants more",
+			"lines":  []map[string]any{{"line_id": lineID, "quantity": 4}, {"product_id": saw.ID, "quantity": 1}},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if amended.Subtotal != 65 || amended.Total != 65 || len(amended.Products) != 2 {
+			t.Fatalf("got subtotal %v total %v with %d lines, want 65 over 2 lines", amended.Subtotal, amended.Total, len(amended.Products))
+		}
+		if len(amended.Amendments) != 2 || amended.Amendments[0].OldQuantity != 2 || amended.Amendments[0].NewQuantity != 4 || amended.Amendments[1].Action != models.AmendmentAdded {
+			t.Fatalf("got amendments %+v, want the change and the addition recorded", amended.Amendments)
+		}
+		if _, reserved := stockOf(t, ctx, services, hammer.ID); reserved != 4 {
+			t.Fatalf("got %d hammers reserved, want 4", reserved)
+		}
+		if _, reserved := stockOf(t, ctx, services, saw.ID); reserved != 1 {
+			t.Fatalf("got %d saws reserved, want 1", reserved)
+		}
+
+		amended, err = services.Sales.AmendSale(ctx, id, body(t, map[string]any{
+			"reason": "changed mind",
+			"lines":  []map[string]any{{"line_id": lineID, "quantity": 0}},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(amended.Products) != 1 || amended.Total != 25 {
+			t.Fatalf("got %d lines and total %v, want the saw alone for 25", len(amended.Products), amended.Total)
+		}
+		if _, reserved := stockOf(t, ctx, services, hammer.ID); reserved != 0 {
+			t.Fatalf("removing the line releases its reservation, got %d reserved", reserved)
+		}
+
+		paid, err := services.Payments.CreatePayment(ctx, id, body(t, map[string]any{
+			"tenders": []map[string]any{{"method": "cash", "tendered": 25}},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = services.Sales.AmendSale(ctx, id, body(t, map[string]any{
+			"reason": "too late",
+			"lines":  []map[string]any{{"line_id": paid.Sale.Products[0].ID, "quantity": 0}},
+		}))
+		var statusErr *SaleStatusError
+		if !errors.As(err, &statusErr) || statusErr.Status != models.SalePaid {
+			t.Fatalf("got %v, want a paid sale refused", err)
+		}
+	})
+}
+
+func TestGetAllSalesPagesAndFilters(t *testing.T) {
+	forEachStore(t, func(t *testing.T, services *Services) {
+		ctx := asAdmin()
+		product := createProduct(t, ctx, services, 10, 50)
+		for quantity := 1; quantity <= 5; quantity++ {
+			if _, err := services.Sales.CreateSale(ctx, body(t, map[string]any{
+				"products": []map[string]any{{"product_id": product.ID, "quantity": quantity}},
+			})); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var totals []float64
+		query := url.Values{"sort": {"total"}, "limit": {"2"}}
+		for pages := 0; ; pages++ {
+			if pages == 3 {
+				t.Fatal("five sales take three pages of two")
+			}
+			sales, page, err := services.Sales.GetAllSales(ctx, query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Total != 5 || page.Limit != 2 {
+				t.Fatalf("got total %d limit %d, want 5 and 2", page.Total, page.Limit)
+			}
+			for _, sale := range sales {
+				totals = append(totals, sale.Total)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			query.Set("cursor", page.NextCursor)
+		}
+		if fmt.Sprint(totals) != "[10 20 30 40 50]" {
+			t.Fatalf("got totals %v, want every sale once in ascending order", totals)
+		}
+
+		if _, _, err := services.Sales.GetAllSales(ctx, url.Values{"sort": {"-total"}, "cursor": query["cursor"]}); !errors.Is(err, ErrInvalidFilter) {
+			t.Fatalf("got %v, want a cursor refused for another sort", err)
+		}
+		if _, _, err := services.Sales.GetAllSales(ctx, url.Values{"status": {"lost"}}); !errors.Is(err, ErrInvalidFilter) {
+			t.Fatalf("got %v, want an unknown status refused", err)
+		}
+
+		sales, page, err := services.Sales.GetAllSales(ctx, url.Values{"min_total": {"25"}, "max_total": {"45"}, "status": {"draft,confirmed"}, "product_id": {fmt.Sprint(product.ID)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != 2 || len(sales) != 2 || sales[0].Total != 40 {
+			t.Fatalf("got %d sales of %d, first at %v, want the drafts of 30 and 40, newest first", len(sales), page.Total, sales[0].Total)
+		}
+	})
+}
+
+func TestCouponUsesAreGivenBack(t *testing.T) {
+	forEachStore(t, func(t *testing.T, services *Services) {
+		ctx := asAdmin()
+		product := createProduct(t, ctx, services, 10, 10)
+		coupon, err := services.Coupons.CreateCoupon(ctx, body(t, map[string]any{
+			"Code": "spring10", "Kind": models.DiscountPercentage, "Value": 10, "MinSubtotal": 15, "MaxUses": 1,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		couponID := fmt.Sprint(coupon.ID)
+		usedCount := func() int {
+			t.Helper()
+			coupon, err := services.Coupons.GetCouponByID(ctx, couponID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return coupon.UsedCount
+		}
+		redeem := func() models.Sale {
+			t.Helper()
+			sale, err := services.Sales.CreateSale(ctx, body(t, map[string]any{
+				"products":    []map[string]any{{"product_id": product.ID, "quantity": 2}},
+				"status":      models.SaleConfirmed,
+				"coupon_code": "SPRING10",
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sale.Total != 18 || usedCount() != 1 {
+				t.Fatalf("got total %v and %d uses, want 18 and the coupon used once", sale.Total, usedCount())
+			}
+			return sale
+		}
+
+		sale := redeem()
+		if _, err := services.Sales.CancelSale(ctx, fmt.Sprint(sale.ID), body(t, map[string]any{"reason": "changed their mind"})); err != nil {
+			t.Fatal(err)
+		}
+		if usedCount() != 0 {
+			t.Fatalf("got %d uses, want the use given back when the sale is cancelled", usedCount())
+		}
+
+		sale = redeem()
+		if err := services.Sales.DeleteSale(ctx, fmt.Sprint(sale.ID)); err != nil {
+			t.Fatal(err)
+		}
+		if usedCount() != 0 {
+			t.Fatalf("got %d uses, want the use given back when the sale is deleted", usedCount())
+		}
+
+		sale = redeem()
+		amended, err := services.Sales.AmendSale(ctx, fmt.Sprint(sale.ID), body(t, map[string]any{
+			"reason": "one hammer only",
+			"lines":  []map[string]any{{"line_id": sale.Products[0].ID, "quantity": 1}},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if amended.Total != 10 || len(amended.Discounts) != 0 || usedCount() != 0 {
+			t.Fatalf("got total %v, %d discounts and %d uses, want the coupon dropped below its minimum", amended.Total, len(amended.Discounts), usedCount())
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"productmanagerapi/payments"
+	"productmanagerapi/repository"
+)
+
+// Services groups every service built on top of the same store.
+type Services struct {
+	Auth        *AuthService
+	Categories  *CategoryService
+	Coupons     *CouponService
+	Customers   *CustomerService
+	Idempotency *IdempotencyService
+	Payments    *PaymentService
+	Products    *ProductService
+	Promotions  *PromotionService
+	Receipts    *ReceiptService
+	Reports     *ReportService
+	Returns     *ReturnService
+	Sales       *SaleService
+	TaxRates    *TaxRateService
+	Users       *UserService
+}
+
+// New builds the services. provider settles card and mobile money
+// payments.
+func New(store *repository.Store, provider payments.Provider) *Services {
+	payments := NewPaymentService(store, provider)
+	return &Services{
+		Auth:        NewAuthService(store.Users, store.Tokens),
+		Categories:  NewCategoryService(store.Categories),
+		Coupons:     NewCouponService(store),
+		Customers:   NewCustomerService(store),
+		Idempotency: NewIdempotencyService(store),
+		Payments:    payments,
+		Products:    NewProductService(store),
+		Promotions:  NewPromotionService(store),
+		Receipts:    NewReceiptService(store),
+		Reports:     NewReportService(store),
+		Returns:     NewReturnService(store),
+		Sales:       NewSaleService(store, payments),
+		TaxRates:    NewTaxRateService(store),
+		Users:       NewUserService(store.Users),
+	}
+}
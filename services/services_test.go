@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"productmanagerapi/auth"
+	"productmanagerapi/config"
+	"productmanagerapi/database"
+	"productmanagerapi/migrations"
+	"productmanagerapi/models"
+	"productmanagerapi/payments"
+	"productmanagerapi/repository"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	config.App = config.Default()
+	config.App.Auth.SecretKey = strings.Repeat("k", 32)
+	os.Exit(m.Run())
+}
+
+// forEachStore runs test against the memory store and against SQLite in
+// memory, migrated like a real database, so both backends behave the same.
+func forEachStore(t *testing.T, test func(t *testing.T, services *Services)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, New(repository.NewMemoryStore(), payments.NewFakeProvider()))
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		cfg := config.Default().Database
+		cfg.Driver = config.DriverSQLite
+		cfg.DSN = ":memory:"
+		db, err := database.Open(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		migrator, err := migrations.New(db.DB, db.Driver())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := migrator.Up(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		test(t, New(repository.NewGormStore(db.DB), payments.NewFakeProvider()))
+	})
+}
+
+// asAdmin returns a context acting as an admin, allowed everything.
+func asAdmin() context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{UserID: 1, Username: "admin", Role: auth.RoleAdmin})
+}
+
+// body encodes value as a JSON request body.
+func body(t *testing.T, value any) io.ReadCloser {
+	t.Helper()
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return io.NopCloser(strings.NewReader(string(encoded)))
+}
+
+// createProduct adds a product with price and stock to a new category.
+func createProduct(t *testing.T, ctx context.Context, services *Services, price float64, stock int) models.Product {
+	t.Helper()
+	category, err := services.Categories.CreateCategory(ctx, body(t, map[string]any{"Name": "Tools", "Description": "Hand tools"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	product, err := services.Products.CreateProduct(ctx, body(t, map[string]any{
+		"Name": "Hammer", "Description": "Claw hammer", "Price": price, "Stock": stock, "CategoryID": category.ID,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return product
+}
+
+// stockOf returns the stock and reservations of a product.
+func stockOf(t *testing.T, ctx context.Context, services *Services, productID uint) (int, int) {
+	t.Helper()
+	product, err := services.Products.GetProductByID(ctx, fmt.Sprint(productID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return product.Stock, product.Reserved
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"productmanagerapi/auth"
+	"productmanagerapi/models"
+	"productmanagerapi/repository"
+	"strconv"
+	"strings"
+)
+
+type TaxRateService struct {
+	store *repository.Store
+	rates repository.TaxRateRepository
+}
+
+func NewTaxRateService(store *repository.Store) *TaxRateService {
+	return &TaxRateService{store: store, rates: store.TaxRates}
+}
+
+func (s *TaxRateService) GetAllTaxRates(ctx context.Context) ([]models.TaxRate, error) {
+	return s.rates.FindAll(ctx)
+}
+
+func (s *TaxRateService) GetTaxRateByID(ctx context.Context, rateID string) (models.TaxRate, error) {
+	id, err := parseTaxRateID(rateID)
+	if err != nil {
+		return models.TaxRate{}, err
+	}
+
+	return s.rates.FindByID(ctx, id)
+}
+
+func (s *TaxRateService) CreateTaxRate(ctx context.Context, body io.ReadCloser) (models.TaxRate, error) {
+	var rate models.TaxRate
+	if err := json.NewDecoder(body).Decode(&rate); err != nil {
+		return models.TaxRate{}, errors.New("invalid request body: " + err.Error())
+	}
+
+	rate.ID = 0
+	rate.CreatedByID = auth.UserID(ctx)
+	rate.UpdatedByID = rate.CreatedByID
+	if err := s.validateTaxRate(ctx, &rate); err != nil {
+		return models.TaxRate{}, err
+	}
+
+	if err := s.rates.Create(ctx, &rate); err != nil {
+		return models.TaxRate{}, err
+	}
+
+	return rate, nil
+}
+
+// UpdateTaxRate replaces every editable field of the rate. Sales already
+// taxed at it keep the rate they were sold at.
+func (s *TaxRateService) UpdateTaxRate(ctx context.Context, rateID string, body io.ReadCloser) (models.TaxRate, error) {
+	id, err := parseTaxRateID(rateID)
+	if err != nil {
+		return models.TaxRate{}, err
+	}
+
+	var rate models.TaxRate
+	if err := json.NewDecoder(body).Decode(&rate); err != nil {
+		return models.TaxRate{}, errors.New("invalid request body: " + err.Error())
+	}
+
+	var updated models.TaxRate
+	err = s.store.Transaction(ctx, func(tx *repository.Store) error {
+		updated, err = tx.TaxRates.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		updated.Name = rate.Name
+		updated.Rate = rate.Rate
+		updated.Inclusive = rate.Inclusive
+		updated.Compound = rate.Compound
+		updated.ProductID = rate.ProductID
+		updated.CategoryID = rate.CategoryID
+		updated.Disabled = rate.Disabled
+		updated.UpdatedByID = auth.UserID(ctx)
+		if err := s.validateTaxRate(ctx, &updated); err != nil {
+			return err
+		}
+
+		return tx.TaxRates.Update(ctx, &updated)
+	})
+	if err != nil {
+		return models.TaxRate{}, err
+	}
+
+	return updated, nil
+}
+
+func (s *TaxRateService) DeleteTaxRate(ctx context.Context, rateID string) error {
+	id, err := parseTaxRateID(rateID)
+	if err != nil {
+		return err
+	}
+
+	return s.rates.Delete(ctx, id)
+}
+
+// validateTaxRate checks the rate is a sensible percentage and applies to
+// at most one existing product or category.
+func (s *TaxRateService) validateTaxRate(ctx context.Context, rate *models.TaxRate) error {
+	rate.Name = strings.TrimSpace(rate.Name)
+	if rate.Name == "" {
+		return errors.New("name is required")
+	}
+	if rate.Rate < 0 || rate.Rate > 100 {
+		return errors.New("rate must be a percentage between 0 and 100")
+	}
+
+	switch {
+	case rate.ProductID != nil && rate.CategoryID != nil:
+		return errors.New("a tax rate applies to a ProductID, a CategoryID or, with neither, every product")
+	case rate.ProductID != nil:
+		if _, err := s.store.Products.FindByID(ctx, *rate.ProductID); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return fmt.Errorf("product %d does not exist", *rate.ProductID)
+			}
+			return err
+		}
+	case rate.CategoryID != nil:
+		if _, err := s.store.Categories.FindByID(ctx, *rate.CategoryID); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return fmt.Errorf("category %d does not exist", *rate.CategoryID)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func parseTaxRateID(rateID string) (uint, error) {
+	if rateID == "" {
+		return 0, errors.New("The tax rate id is required")
+	}
+
+	id, err := strconv.ParseUint(rateID, 10, 64)
+	if err != nil || id == 0 {
+		return 0, errors.New("The tax rate id is invalid")
+	}
+
+	return uint(id), nil
+}
@@ -0,0 +1,121 @@
+package services
+
+import "productmanagerapi/models"
+
+// taxesFor picks the rates line is taxed at among the enabled rates: those
+// of its product if there are any, else those of its category, else the
+// store-wide ones. The rates are copied onto the line, to be worked out by
+// priceSale.
+func taxesFor(line models.SaleProduct, rates []models.TaxRate) []models.SaleLineTax {
+	var product, category, store []models.SaleLineTax
+	for _, rate := range rates {
+		tax := models.SaleLineTax{
+			TaxRateID: rate.ID,
+			Name:      rate.Name,
+			Rate:      rate.Rate,
+			Inclusive: rate.Inclusive,
+			Compound:  rate.Compound,
+		}
+		switch {
+		case rate.ProductID != nil:
+			if *rate.ProductID == line.ProductID {
+				product = append(product, tax)
+			}
+		case rate.CategoryID != nil:
+			if *rate.CategoryID == line.CategoryID {
+				category = append(category, tax)
+			}
+		default:
+			store = append(store, tax)
+		}
+	}
+
+	switch {
+	case len(product) > 0:
+		return product
+	case len(category) > 0:
+		return category
+	}
+	return store
+}
+
+// taxLine works out the taxes of line on amount, what the line comes to
+// after every discount, and returns the exclusive part, which is added to
+// what the customer pays. Inclusive taxes are taken out of amount: the line
+// is taxed on what is left once they are. Compound rates are charged on the
+// taxable amount plus the taxes of the rates before them.
+func taxLine(line *models.SaleProduct, amount float64) float64 {
+	// The tax of each rate per unit of taxable amount.
+	factors := make([]float64, len(line.Taxes))
+	bases := make([]float64, len(line.Taxes))
+	var stacked, inclusive float64
+	for i, tax := range line.Taxes {
+		bases[i] = 1
+		if tax.Compound {
+			bases[i] += stacked
+		}
+		factors[i] = bases[i] * tax.Rate / 100
+		stacked += factors[i]
+		if tax.Inclusive {
+			inclusive += factors[i]
+		}
+	}
+
+	taxable := amount / (1 + inclusive)
+	var included, exclusive float64
+	for i := range line.Taxes {
+		tax := &line.Taxes[i]
+		tax.Amount = roundCents(taxable * factors[i])
+		if tax.Inclusive {
+			included += tax.Amount
+		} else {
+			exclusive += tax.Amount
+		}
+	}
+	line.TaxableAmount = roundCents(amount - included)
+	line.Tax = roundCents(included + exclusive)
+
+	// What each rate applied to, from the rounded amounts so that they add
+	// up.
+	stacked = 0
+	for i := range line.Taxes {
+		tax := &line.Taxes[i]
+		tax.Taxable = line.TaxableAmount
+		if tax.Compound {
+			tax.Taxable = roundCents(tax.Taxable + stacked)
+		}
+		stacked += tax.Amount
+	}
+	return exclusive
+}
+
+// saleTaxes totals the taxes of lines per rate, in the order the rates first
+// appear.
+func saleTaxes(lines []models.SaleProduct) []models.SaleTax {
+	type key struct {
+		id   uint
+		rate float64
+	}
+
+	var taxes []models.SaleTax
+	index := map[key]int{}
+	for _, line := range lines {
+		for _, tax := range line.Taxes {
+			k := key{tax.TaxRateID, tax.Rate}
+			i, ok := index[k]
+			if !ok {
+				i = len(taxes)
+				index[k] = i
+				taxes = append(taxes, models.SaleTax{
+					TaxRateID: tax.TaxRateID,
+					Name:      tax.Name,
+					Rate:      tax.Rate,
+					Inclusive: tax.Inclusive,
+				})
+			}
+			taxes[i].Taxable = roundCents(taxes[i].Taxable + tax.Taxable)
+			taxes[i].Amount = roundCents(taxes[i].Amount + tax.Amount)
+		}
+	}
+	return taxes
+}
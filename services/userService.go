@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"productmanagerapi/auth"
+	"productmanagerapi/models"
+	"productmanagerapi/repository"
+	"productmanagerapi/types"
+	"strconv"
+)
+
+type UserService struct {
+	users repository.UserRepository
+}
+
+func NewUserService(users repository.UserRepository) *UserService {
+	return &UserService{users: users}
+}
+
+func (s *UserService) GetAllUsers(ctx context.Context) ([]models.User, error) {
+	return s.users.FindAll(ctx)
+}
+
+// AssignRole changes a user's role. The last admin cannot be demoted, so the
+// system always keeps someone able to manage roles.
+func (s *UserService) AssignRole(ctx context.Context, userID string, body io.ReadCloser) (models.User, error) {
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil || id == 0 {
+		return models.User{}, errors.New("invalid user ID")
+	}
+
+	var assignment types.RoleAssignment
+	if err := json.NewDecoder(body).Decode(&assignment); err != nil {
+		return models.User{}, errors.New("invalid request body: " + err.Error())
+	}
+
+	role := auth.Role(assignment.Role)
+	if !role.Valid() {
+		return models.User{}, errors.New("unknown role: " + assignment.Role)
+	}
+
+	user, err := s.users.FindByID(ctx, uint(id))
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if auth.Role(user.Role) == auth.RoleAdmin && role != auth.RoleAdmin {
+		users, err := s.users.FindAll(ctx)
+		if err != nil {
+			return models.User{}, err
+		}
+
+		admins := 0
+		for _, other := range users {
+			if auth.Role(other.Role) == auth.RoleAdmin {
+				admins++
+			}
+		}
+		if admins <= 1 {
+			return models.User{}, errors.New("cannot remove the role of the last admin")
+		}
+	}
+
+	user.Role = string(role)
+	if err := s.users.Update(ctx, &user); err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
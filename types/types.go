@@ -1,7 +1,7 @@
 package types
 
 type Response struct {
-	Status  int    `json:"status`
+	Status  int    `json:"status"`
 	Data    any    `json:"data"`
 	Message string `json:"message"`
 }
@@ -10,12 +10,159 @@ type ID struct {
 	ID string `json:"id"`
 }
 
+// ProductSale is a line of a sale request. Lines are priced from the
+// catalog: Price is only needed to override the catalog price, which takes
+// the sales:override_price permission and an OverrideReason. Discount takes
+// a manual discount off the line.
 type ProductSale struct {
-	ProductID int     `json:"product_id"`
-	Quantity  int     `json:"quantity"`
-	Price     float64 `json:"price"`
+	ProductID      int       `json:"product_id"`
+	Quantity       int       `json:"quantity"`
+	Price          *float64  `json:"price,omitempty"`
+	OverrideReason string    `json:"override_reason,omitempty"`
+	Discount       *Discount `json:"discount,omitempty"`
 }
 
+// SaleRequest is the body of POST /sales. Sales start as drafts unless
+// Status asks for confirmed, paid or fulfilled, in which case the sale goes
+// through every status up to it at once. A sale created paid or fulfilled
+// needs Tenders that pay all of it, as in PaymentRequest. CustomerID
+// optionally names the buyer. Discount takes a manual discount off the
+// whole sale and CouponCode redeems a coupon.
 type SaleRequest struct {
-	Products []ProductSale `json:"products"`
+	Products   []ProductSale `json:"products"`
+	Status     string        `json:"status,omitempty"`
+	Tenders    []Tender      `json:"tenders,omitempty"`
+	CustomerID *uint         `json:"customer_id,omitempty"`
+	Discount   *Discount     `json:"discount,omitempty"`
+	CouponCode string        `json:"coupon_code,omitempty"`
+}
+
+// Discount is a manual discount: Type is percentage, with Value the percent
+// off, or fixed, with Value the amount off. It takes the sales:discount
+// permission and a Reason.
+type Discount struct {
+	Type   string  `json:"type"`
+	Value  float64 `json:"value"`
+	Reason string  `json:"reason"`
+}
+
+// SaleCancellation is the optional body of POST /sales/{id}/cancel.
+type SaleCancellation struct {
+	Reason string `json:"reason"`
+}
+
+// SaleAmendmentRequest is the body of PATCH /sales/{id}.
+type SaleAmendmentRequest struct {
+	Reason string           `json:"reason"`
+	Lines  []SaleLineChange `json:"lines"`
+}
+
+// SaleLineChange sets the quantity of the existing line LineID, where 0
+// removes the line, or adds a line for ProductID when LineID is not set.
+// Price, OverrideReason and Discount work as in ProductSale for added lines.
+type SaleLineChange struct {
+	LineID         uint      `json:"line_id,omitempty"`
+	ProductID      int       `json:"product_id,omitempty"`
+	Quantity       int       `json:"quantity"`
+	Price          *float64  `json:"price,omitempty"`
+	OverrideReason string    `json:"override_reason,omitempty"`
+	Discount       *Discount `json:"discount,omitempty"`
+}
+
+// ProductSale returns the change as a new sale line.
+func (c SaleLineChange) ProductSale() ProductSale {
+	return ProductSale{ProductID: c.ProductID, Quantity: c.Quantity, Price: c.Price, OverrideReason: c.OverrideReason, Discount: c.Discount}
+}
+
+// PaymentRequest is the body of POST /sales/{id}/payments. Several tenders
+// split the payment; together they may pay part of the balance.
+type PaymentRequest struct {
+	Tenders []Tender `json:"tenders"`
+}
+
+// Tender pays Amount with Method, or the whole balance left when Amount is
+// not set. For cash, Tendered is what the customer hands over, and Amount
+// defaults to as much of it as the balance needs. Token identifies the card
+// or mobile money account for the payment provider.
+type Tender struct {
+	Method   string  `json:"method"`
+	Amount   float64 `json:"amount,omitempty"`
+	Tendered float64 `json:"tendered,omitempty"`
+	Token    string  `json:"token,omitempty"`
+}
+
+// StoreCreditAdjustment is the body of POST /customers/{id}/store-credit.
+// A negative Amount takes credit away.
+type StoreCreditAdjustment struct {
+	Amount float64 `json:"amount"`
+}
+
+// ReturnRequest is the body of POST /sales/{id}/returns.
+type ReturnRequest struct {
+	Reason string       `json:"reason"`
+	Lines  []ReturnLine `json:"lines"`
+}
+
+// ReturnLine returns Quantity items of the sale line LineID. Restock
+// defaults to true; false marks the items as damaged.
+type ReturnLine struct {
+	LineID   uint  `json:"line_id"`
+	Quantity int   `json:"quantity"`
+	Restock  *bool `json:"restock,omitempty"`
+}
+
+// SaleLineConflict describes a sale line that could not be fulfilled. Line
+// is the index of the line in the request, or in the sale when a status
+// change hits the conflict.
+type SaleLineConflict struct {
+	Line      int    `json:"line"`
+	ProductID int    `json:"product_id"`
+	Requested int    `json:"requested"`
+	Available int    `json:"available"`
+	Reason    string `json:"reason"`
+}
+
+// ProductPatch holds the fields of a PATCH request; nil fields are left
+// unchanged.
+type ProductPatch struct {
+	Name        *string
+	Description *string
+	Price       *float64
+	Stock       *int
+	CategoryID  *uint
+}
+
+// CategoryPatch holds the fields of a PATCH request; nil fields are left
+// unchanged.
+type CategoryPatch struct {
+	Name        *string
+	Description *string
+}
+
+type RoleAssignment struct {
+	Role string `json:"role"`
+}
+
+// Registration is the body of /auth/register. Roles are not part of it: new
+// accounts always start with the default role.
+type Registration struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Email    string `json:"email"`
+}
+
+// TokenPair is returned by login and refresh. ExpiresIn and
+// RefreshExpiresIn are in seconds.
+type TokenPair struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int    `json:"expires_in"`
+	RefreshExpiresIn int    `json:"refresh_expires_in"`
+	// SessionID is the refresh token family, used to derive the CSRF token.
+	SessionID string `json:"-"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
@@ -1,27 +1,23 @@
 package utils
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"productmanagerapi/auth"
 	"productmanagerapi/config"
 	responseFormatter "productmanagerapi/responseFormatter"
+	"strings"
+	"time"
 
 	jwt "github.com/golang-jwt/jwt/v5"
 )
 
-var RequestMethodValidator = func(w http.ResponseWriter, r http.Request, method string) bool {
-	if r.Method != method {
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(responseFormatter.FormatResponse(http.StatusMethodNotAllowed, "This method is not allowed", nil))
-		return false
-	}
-
-	return true
-}
-
 var ResponseWritter = func(w http.ResponseWriter, statusCode int, response map[string]interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -34,18 +30,63 @@ var Log = func(r *http.Request, resource string) {
 
 }
 
-var IsValidToken = func(token string) bool {
+// ParseToken validates a signed access token and returns its claims. Tokens
+// without an expiry, a token ID or issued by someone else are rejected.
+var ParseToken = func(token string) (jwt.MapClaims, error) {
 	jwtToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, jwt.ErrSignatureInvalid
-		}
-		return []byte(config.SECRET_KEY), nil // Replace with your actual secret key
-	})
+		return []byte(config.App.Auth.SecretKey), nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(config.App.Auth.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
 
 	if err != nil || !jwtToken.Valid {
-		return false
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+
+	claims, ok := jwtToken.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, jwt.ErrTokenInvalidClaims
 	}
-	return true
+	if tokenID, _ := claims["jti"].(string); tokenID == "" {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
+}
+
+// IsTokenRevoked reports whether the access token with the given jti is on
+// the revocation list. main points it at the auth service; it must not be
+// left unset in a server that issues tokens.
+var IsTokenRevoked = func(ctx context.Context, tokenID string) (bool, error) {
+	return false, nil
+}
+
+var IsValidToken = func(token string) bool {
+	_, err := ParseToken(token)
+	return err == nil
+}
+
+// principalFromClaims builds the request principal from the claims issued
+// at login.
+func principalFromClaims(claims jwt.MapClaims) auth.Principal {
+	principal := auth.Principal{}
+	if userID, ok := claims["user_id"].(float64); ok {
+		principal.UserID = uint(userID)
+	}
+	if username, ok := claims["username"].(string); ok {
+		principal.Username = username
+	}
+	if role, ok := claims["role"].(string); ok {
+		principal.Role = auth.Role(role)
+	}
+	principal.TokenID, _ = claims["jti"].(string)
+	principal.SessionID, _ = claims["sid"].(string)
+	if expiresAt, err := claims.GetExpirationTime(); err == nil && expiresAt != nil {
+		principal.ExpiresAt = expiresAt.Time
+	}
+	return principal
 }
 
 func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
@@ -56,32 +97,118 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		cookie, err := r.Cookie("token")
+		token, fromCookie, err := requestToken(r)
 		if err != nil {
-			http.Error(w, "Unauthorized - no token", http.StatusUnauthorized)
+			http.Error(w, "Unauthorized - "+err.Error(), http.StatusUnauthorized)
 			return
 		}
 
-		token := cookie.Value
-
-		if token == "" || !IsValidToken(token) {
+		claims, err := ParseToken(token)
+		if token == "" || err != nil {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusUnauthorized)
 			json.NewEncoder(w).Encode(responseFormatter.FormatResponse(http.StatusUnauthorized, "Unauthorized", nil))
 			return
 		}
 
-		next(w, r)
+		principal := principalFromClaims(claims)
+
+		revoked, err := IsTokenRevoked(r.Context(), principal.TokenID)
+		if err != nil {
+			ResponseWritter(w, http.StatusInternalServerError, responseFormatter.FormatResponse(http.StatusInternalServerError, "Error checking token", nil))
+			fmt.Println("Error checking token revocation:", err)
+			return
+		}
+		if revoked {
+			ResponseWritter(w, http.StatusUnauthorized, responseFormatter.FormatResponse(http.StatusUnauthorized, "Token has been revoked", nil))
+			return
+		}
+
+		// Browsers attach cookies to cross-site requests on their own, so
+		// cookie-authenticated writes must echo the CSRF token in a header.
+		// Bearer tokens are only ever sent deliberately.
+		if fromCookie && !isSafeMethod(r.Method) && !ValidCSRFToken(principal.SessionID, r.Header.Get(CSRFHeader)) {
+			ResponseWritter(w, http.StatusForbidden, responseFormatter.FormatResponse(http.StatusForbidden, "Invalid or missing CSRF token", nil))
+			fmt.Printf("Rejected %s %s: invalid CSRF token\n", r.Method, r.URL.Path)
+			return
+		}
+
+		next(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
 	})
 }
 
+// requestToken returns the access token from the Authorization header,
+// falling back to the token cookie, and whether it came from the cookie.
+func requestToken(r *http.Request) (string, bool, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			return "", false, errors.New("malformed Authorization header")
+		}
+		return strings.TrimSpace(token), false, nil
+	}
+
+	cookie, err := r.Cookie("token")
+	if err != nil || cookie.Value == "" {
+		return "", false, errors.New("no token")
+	}
+	return cookie.Value, true, nil
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// CSRFHeader carries the CSRF token on cookie-authenticated requests.
+const CSRFHeader = "X-CSRF-Token"
+
+// CSRFToken derives the CSRF token of a session. It is bound to the session
+// and signed with the server secret, so it cannot be guessed or planted by
+// another site and needs no storage.
+func CSRFToken(sessionID string) string {
+	mac := hmac.New(sha256.New, []byte(config.App.Auth.SecretKey))
+	mac.Write([]byte("csrf:" + sessionID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ValidCSRFToken reports whether token is the CSRF token of the session.
+func ValidCSRFToken(sessionID, token string) bool {
+	if sessionID == "" || token == "" {
+		return false
+	}
+	return hmac.Equal([]byte(token), []byte(CSRFToken(sessionID)))
+}
+
+// RequirePermission rejects requests whose principal's role does not grant
+// permission. It must run inside AuthMiddleware.
+func RequirePermission(permission auth.Permission, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFrom(r.Context())
+		if !ok || !principal.Role.Can(permission) {
+			ResponseWritter(w, http.StatusForbidden, responseFormatter.FormatResponse(http.StatusForbidden, "Forbidden", map[string]interface{}{
+				"required_permission": permission,
+				"role":                principal.Role,
+			}))
+			fmt.Printf("Denied %s %s to role %q: missing %s\n", r.Method, r.URL.Path, principal.Role, permission)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
 func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Allow requests from your frontend (e.g., Next.js running on localhost:3000)
-		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		// Only echo back origins listed in the configuration; credentials
+		// cannot be combined with a wildcard origin.
+		if origin := r.Header.Get("Origin"); origin != "" && isAllowedOrigin(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key, "+CSRFHeader)
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Expose-Headers", "Deprecation, Sunset, Link, Idempotent-Replayed")
 
 		// Handle preflight request (OPTIONS)
 		if r.Method == http.MethodOptions {
@@ -92,3 +219,33 @@ func CORSMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+func isAllowedOrigin(origin string) bool {
+	for _, allowed := range config.App.CORS.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// Deprecated marks responses from a deprecated route, points clients at the
+// route that replaces it and, when known, announces its removal date.
+func Deprecated(successor string, sunset time.Time, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		if successor != "" {
+			link := successor
+			if id := r.PathValue("id"); id != "" {
+				link = strings.ReplaceAll(link, "{id}", id)
+			} else if id := r.URL.Query().Get("id"); id != "" {
+				link = strings.ReplaceAll(link, "{id}", id)
+			}
+			w.Header().Set("Link", "<"+link+">; rel=\"successor-version\"")
+		}
+		if !sunset.IsZero() {
+			w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		next(w, r)
+	}
+}